@@ -71,6 +71,9 @@ func TestWithLocalhost(t *testing.T) {
 		}, {
 			Name: "network block dns",
 			Test: testNetworkBlockDns,
+		}, {
+			Name: "network dns error",
+			Test: testNetworkDnsError,
 		}, {
 			Name: "network limit bandwidth",
 			Test: testNetworkLimitBandwidth,
@@ -81,8 +84,20 @@ func TestWithLocalhost(t *testing.T) {
 			Name: "network package corruption",
 			Test: testNetworkPackageCorruption,
 		}, {
-			Name: "two simultaneous network attacks should error",
+			Name: "network package duplication",
+			Test: testNetworkPackageDuplication,
+		}, {
+			Name: "network package reordering",
+			Test: testNetworkPackageReordering,
+		}, {
+			Name: "network tcp reset",
+			Test: testNetworkTcpReset,
+		}, {
+			Name: "two simultaneous overlapping network attacks should error",
 			Test: testTwoNetworkAttacks,
+		}, {
+			Name: "two simultaneous non-overlapping network attacks",
+			Test: testTwoNonOverlappingNetworkAttacks,
 		},
 	})
 }
@@ -413,6 +428,38 @@ func testNetworkBlockDns(t *testing.T, l Environment, e Extension) {
 	}
 }
 
+func testNetworkDnsError(t *testing.T, l Environment, e Extension) {
+	port, err := FindAvailablePorts(8080, 8800, 2)
+	require.NoError(t, err)
+	netperf := NewHttpNetperf(port)
+	err = netperf.Deploy(t.Context(), l)
+	require.NoError(t, err)
+	defer func() { _ = netperf.Delete() }()
+
+	config := struct {
+		Duration  int      `json:"duration"`
+		Hostnames []string `json:"hostnames"`
+		ErrorType string   `json:"errorType"`
+	}{
+		Duration:  10000,
+		Hostnames: []string{"chaosmesh.com"},
+		ErrorType: "nxdomain",
+	}
+
+	require.True(t, netperf.CanReach("steadybit.com"))
+
+	action, err := e.RunAction(exthostwindows.BaseActionID+".network_dns_error", l.BuildTarget(t.Context()), config, defaultExecutionContext)
+	defer func() { _ = action.Cancel() }()
+	require.NoError(t, err)
+
+	assert.False(t, netperf.CanReach("chaosmesh.com"))
+	// lookups of other names are still answered
+	assert.True(t, netperf.CanReach("google.com"))
+
+	require.NoError(t, action.Cancel())
+	require.True(t, netperf.CanReach("chaosmesh.com"))
+}
+
 func testNetworkLimitBandwidth(t *testing.T, l Environment, e Extension) {
 	t.Skip("Limit bandwidth tests use Windows QoS, which does not apply to local loopback interfaces. Test manually.")
 
@@ -699,6 +746,103 @@ func testNetworkPackageCorruption(t *testing.T, l Environment, e Extension) {
 	}
 }
 
+func testNetworkPackageDuplication(t *testing.T, l Environment, e Extension) {
+	testWdnaMode(t, l, e, "network_package_duplication", map[string]any{"percentage": 50, "correlation": 25})
+}
+
+func testNetworkPackageReordering(t *testing.T, l Environment, e Extension) {
+	testWdnaMode(t, l, e, "network_package_reordering", map[string]any{"percentage": 50, "correlation": 25})
+}
+
+// testWdnaMode runs an attack whose wdna mode doesn't prevent traffic on the port of a test server. wdna exits right
+// away if the pinned WinDivert release doesn't know the mode.
+func testWdnaMode(t *testing.T, l Environment, e Extension, action string, config map[string]any) {
+	port, err := FindAvailablePorts(8080, 8800, 2)
+	require.NoError(t, err)
+	netperf := NewHttpNetperf(port)
+	err = netperf.Deploy(t.Context(), l)
+	require.NoError(t, err)
+	defer func() { _ = netperf.Delete() }()
+
+	config["duration"] = 20000
+	config["port"] = []string{strconv.Itoa(netperf.Port)}
+
+	execution, err := e.RunAction(exthostwindows.BaseActionID+"."+action, l.BuildTarget(t.Context()), config, defaultExecutionContext)
+	defer func() { _ = execution.Cancel() }()
+	require.NoError(t, err)
+
+	assertWdnaRunning(t, l)
+	assert.True(t, netperf.IsReachable())
+
+	require.NoError(t, execution.Cancel())
+	assert.True(t, netperf.IsReachable())
+}
+
+// assertWdnaRunning asserts that wdna keeps running after the attack started.
+func assertWdnaRunning(t *testing.T, l Environment) {
+	t.Helper()
+	require.NotEmpty(t, l.FindProcessIds(t.Context(), "wdna.exe"), "wdna is not running")
+	assert.Never(t, func() bool {
+		return len(l.FindProcessIds(t.Context(), "wdna.exe")) == 0
+	}, 3*time.Second, 500*time.Millisecond, "wdna exited, the mode may not be supported by the pinned WinDivert release")
+}
+
+func testNetworkTcpReset(t *testing.T, l Environment, e Extension) {
+	port, err := FindAvailablePorts(8080, 8800, 2)
+	require.NoError(t, err)
+	netperf := NewHttpNetperf(port)
+	err = netperf.Deploy(t.Context(), l)
+	require.NoError(t, err)
+	defer func() { _ = netperf.Delete() }()
+
+	tests := []struct {
+		name        string
+		interval    int
+		wantedReset bool
+	}{
+		{
+			name:        "should reset connections once",
+			interval:    0,
+			wantedReset: false,
+		},
+		{
+			name:        "should reset connections at a rate",
+			interval:    500,
+			wantedReset: true,
+		},
+	}
+
+	for _, tt := range tests {
+		config := struct {
+			Duration int      `json:"duration"`
+			Interval int      `json:"interval"`
+			Port     []string `json:"port"`
+		}{
+			Duration: 20000,
+			Interval: tt.interval,
+			Port:     []string{strconv.Itoa(netperf.Port)},
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			action, err := e.RunAction(exthostwindows.BaseActionID+".network_tcp_reset", l.BuildTarget(t.Context()), config, defaultExecutionContext)
+			defer func() { _ = action.Cancel() }()
+			require.NoError(t, err)
+
+			assertWdnaRunning(t, l)
+			if tt.wantedReset {
+				// the kept alive connection of the client is reset
+				assert.Eventually(t, func() bool { return !netperf.IsReachable() }, 5*time.Second, 100*time.Millisecond)
+			} else {
+				// connections opened after the start aren't reset
+				assert.True(t, netperf.IsReachable())
+			}
+
+			require.NoError(t, action.Cancel())
+			assert.True(t, netperf.IsReachable())
+		})
+	}
+}
+
 func testTwoNetworkAttacks(t *testing.T, l Environment, e Extension) {
 	configDelay := struct {
 		Duration int `json:"duration"`
@@ -722,7 +866,38 @@ func testTwoNetworkAttacks(t *testing.T, l Environment, e Extension) {
 	}
 	actionLimit, err2 := e.RunAction(exthostwindows.BaseActionID+".network_bandwidth", l.BuildTarget(t.Context()), configLimit, defaultExecutionContext)
 	defer func() { _ = actionLimit.Cancel() }()
-	require.ErrorContains(t, err2, "running multiple network attacks at the same time is not supported")
+	require.ErrorContains(t, err2, "overlaps with an already running attack")
+}
+
+func testTwoNonOverlappingNetworkAttacks(t *testing.T, l Environment, e Extension) {
+	configDelay := struct {
+		Duration int      `json:"duration"`
+		Delay    int      `json:"networkDelay"`
+		Port     []string `json:"port"`
+	}{
+		Duration: 10000,
+		Delay:    200,
+		Port:     []string{"8080"},
+	}
+	actionDelay, err := e.RunAction(exthostwindows.BaseActionID+".network_delay", l.BuildTarget(t.Context()), configDelay, defaultExecutionContext)
+	defer func() { _ = actionDelay.Cancel() }()
+	require.NoError(t, err)
+
+	configLoss := struct {
+		Duration   int      `json:"duration"`
+		Percentage int      `json:"percentage"`
+		Port       []string `json:"port"`
+	}{
+		Duration:   10000,
+		Percentage: 50,
+		Port:       []string{"9090"},
+	}
+	actionLoss, err := e.RunAction(exthostwindows.BaseActionID+".network_package_loss", l.BuildTarget(t.Context()), configLoss, defaultExecutionContext)
+	defer func() { _ = actionLoss.Cancel() }()
+	require.NoError(t, err)
+
+	require.NoError(t, actionLoss.Cancel())
+	require.NoError(t, actionDelay.Cancel())
 }
 
 func generateRestrictedEndpoints(count int) []action_kit_api.RestrictedEndpoint {
@@ -6,6 +6,7 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/steadybit/action-kit/go/action_kit_api/v2"
 	stopprocess "github.com/steadybit/extension-host-windows/exthostwindows/process"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
 	"os"
 	"os/exec"
 	"path"
@@ -81,5 +82,5 @@ func (l *LocalEnvironment) StopProcess(_ context.Context, commandOrPid string) e
 	if len(pids) != 1 {
 		return fmt.Errorf("cannot stop process %q, found candidates %v", commandOrPid, pids)
 	}
-	return stopprocess.StopProcesses(pids, true)
+	return stopprocess.StopProcesses(utils.NewCommandRunner(), pids, true)
 }
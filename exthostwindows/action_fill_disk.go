@@ -4,17 +4,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 	"github.com/steadybit/action-kit/go/action_kit_api/v2"
 	"github.com/steadybit/action-kit/go/action_kit_sdk"
+	"github.com/steadybit/extension-host-windows/exthostwindows/diskfill"
 	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
 	"github.com/steadybit/extension-kit/extbuild"
 	"github.com/steadybit/extension-kit/extutil"
@@ -29,6 +29,14 @@ const (
 	MBLeft     FillMode   = "MB_LEFT"
 	AtOnce     FillMethod = "AT_ONCE"
 	OverTime   FillMethod = "OVER_TIME"
+	Continuous FillMethod = "CONTINUOUS"
+)
+
+const (
+	// keepFreeInterval is the time between two checks of the free space when filling continuously.
+	keepFreeInterval = 5 * time.Second
+	// keepFreeTolerance is the deviation from the free space which isn't compensated when filling continuously.
+	keepFreeTolerance = 10 * diskfill.Megabyte
 )
 
 var FillDiskModes = struct {
@@ -42,11 +50,13 @@ var FillDiskModes = struct {
 }
 
 var FillDiskMethods = struct {
-	AtOnce   FillMethod
-	OverTime FillMethod
+	AtOnce     FillMethod
+	OverTime   FillMethod
+	Continuous FillMethod
 }{
-	AtOnce:   AtOnce,
-	OverTime: OverTime,
+	AtOnce:     AtOnce,
+	OverTime:   OverTime,
+	Continuous: Continuous,
 }
 
 func (fm FillMode) IsValid() bool {
@@ -60,7 +70,7 @@ func (fm FillMode) IsValid() bool {
 
 func (fm FillMethod) IsValid() bool {
 	switch fm {
-	case FillDiskMethods.AtOnce, FillDiskMethods.OverTime:
+	case FillDiskMethods.AtOnce, FillDiskMethods.OverTime, FillDiskMethods.Continuous:
 		return true
 	default:
 		return false
@@ -70,6 +80,10 @@ func (fm FillMethod) IsValid() bool {
 type fillDiskAction struct {
 	description  action_kit_api.ActionDescription
 	optsProvider fillDiskOptsProvider
+	runner       utils.CommandRunner
+	disk         diskSampler
+	volume       func(path string) (utils.VolumeSpace, error)
+	fills        sync.Map
 }
 
 type FillDiskOpts struct {
@@ -80,35 +94,46 @@ type FillDiskOpts struct {
 	ByteSize  uint64
 	BlockSize uint
 	FilePath  string
+	// Sparse allocates a sparse file when filling at once.
+	Sparse bool
+	// Rate is the MB written per second when filling over time, 0 writes as fast as possible.
+	Rate uint
+	// Size is the fill value, a percentage or MB depending on the fill mode.
+	Size uint64
 }
 
 func BytesToMegabytes(bytes uint64) uint64 {
-	return bytes / 1000 / 1000
+	return bytes / diskfill.Megabyte
 }
 
-func (o *FillDiskOpts) Args() []string {
-	args := []string{}
+// driveLetter returns the drive letter of the path, which is validated to start with it.
+func (o *FillDiskOpts) driveLetter() string {
+	return strings.ToUpper(o.Path[:1])
+}
 
-	if o.Method == AtOnce {
-		args = []string{"file", "createNew", o.FilePath}
-		args = append(args, fmt.Sprintf("%d", o.ByteSize))
+func (o *FillDiskOpts) filler() *diskfill.Filler {
+	return &diskfill.Filler{
+		Path:      o.FilePath,
+		Size:      o.ByteSize,
+		Sparse:    o.Sparse,
+		Rate:      uint64(o.Rate) * diskfill.Megabyte,
+		BlockSize: uint64(o.BlockSize) * diskfill.Megabyte,
 	}
+}
 
-	if o.Method == OverTime {
-		args = []string{"dd", fmt.Sprintf("of=%s", o.FilePath)}
-
-		allocationInMB := BytesToMegabytes(o.ByteSize)
-
-		if uint64(o.BlockSize) > allocationInMB {
-			o.BlockSize = uint(allocationInMB)
-		}
-
-		numberOfBlocks := o.ByteSize / uint64(o.BlockSize)
-
-		args = append(args, "iflag=fullblock", fmt.Sprintf("bs=%dM", o.BlockSize), fmt.Sprintf("count=%d", BytesToMegabytes(numberOfBlocks)))
+// keeper returns a keeper holding the free space of the fill mode, using the given space of the volume.
+func (o *FillDiskOpts) keeper(volume func(path string) (utils.VolumeSpace, error)) *diskfill.Keeper {
+	free := func(utils.VolumeSpace) uint64 { return o.Size * diskfill.Megabyte }
+	if o.FillMode == Percentage {
+		free = func(space utils.VolumeSpace) uint64 { return diskfill.FreeForPercentage(space.Total, o.Size) }
+	}
+	return &diskfill.Keeper{
+		Path:      o.FilePath,
+		Space:     func() (utils.VolumeSpace, error) { return volume(o.Path) },
+		Free:      free,
+		Interval:  keepFreeInterval,
+		Tolerance: keepFreeTolerance,
 	}
-
-	return args
 }
 
 type FillDiskActionState struct {
@@ -117,16 +142,21 @@ type FillDiskActionState struct {
 }
 
 var (
-	_ action_kit_sdk.Action[FillDiskActionState]         = (*fillDiskAction)(nil)
-	_ action_kit_sdk.ActionWithStop[FillDiskActionState] = (*fillDiskAction)(nil) // Optional, needed when the action needs a stop method
+	_ action_kit_sdk.Action[FillDiskActionState]           = (*fillDiskAction)(nil)
+	_ action_kit_sdk.ActionWithStatus[FillDiskActionState] = (*fillDiskAction)(nil)
+	_ action_kit_sdk.ActionWithStop[FillDiskActionState]   = (*fillDiskAction)(nil) // Optional, needed when the action needs a stop method
 )
 
 type fillDiskOptsProvider func(request action_kit_api.PrepareActionRequestBody) (*FillDiskOpts, error)
 
 func NewFillDiskAction() action_kit_sdk.Action[FillDiskActionState] {
+	runner := utils.NewCommandRunner()
 	return &fillDiskAction{
 		description:  getFillDiskDescription(),
-		optsProvider: fillDisk(),
+		optsProvider: fillDisk(runner),
+		runner:       runner,
+		disk:         driveSpaceSampler{},
+		volume:       utils.GetVolumeSpace,
 	}
 }
 
@@ -134,7 +164,7 @@ func (a *fillDiskAction) NewEmptyState() FillDiskActionState {
 	return FillDiskActionState{}
 }
 
-func fillDisk() fillDiskOptsProvider {
+func fillDisk(runner utils.CommandRunner) fillDiskOptsProvider {
 	return func(request action_kit_api.PrepareActionRequestBody) (*FillDiskOpts, error) {
 		duration := time.Duration(extutil.ToInt64(request.Config["duration"])) * time.Millisecond
 
@@ -151,7 +181,11 @@ func fillDisk() fillDiskOptsProvider {
 		method := FillMethod(extutil.ToString(request.Config["method"]))
 
 		if !method.IsValid() {
-			return nil, fmt.Errorf("unit must be one of the following: %s, %s", FillDiskMethods.AtOnce, FillDiskMethods.OverTime)
+			return nil, fmt.Errorf("unit must be one of the following: %s, %s, %s", FillDiskMethods.AtOnce, FillDiskMethods.OverTime, FillDiskMethods.Continuous)
+		}
+
+		if method == Continuous && mode == MBToFill {
+			return nil, fmt.Errorf("method %s requires mode %s or %s", FillDiskMethods.Continuous, FillDiskModes.MBLeft, FillDiskModes.Percentage)
 		}
 
 		path := extutil.ToString(request.Config["path"])
@@ -180,10 +214,14 @@ func fillDisk() fillDiskOptsProvider {
 
 		size := extutil.ToUInt(request.Config["size"])
 
-		amountToAllocate, err := calculateAllocation(mode, driveLetter, uint64(size))
+		// when filling continuously, the amount is adjusted to the free space during the attack
+		var amountToAllocate uint64
+		if method != Continuous {
+			amountToAllocate, err = calculateAllocation(runner, mode, driveLetter, uint64(size))
 
-		if err != nil {
-			return nil, err
+			if err != nil {
+				return nil, err
+			}
 		}
 
 		blockSize := extutil.ToUInt(request.Config["blocksize"])
@@ -198,55 +236,35 @@ func fillDisk() fillDiskOptsProvider {
 			Method:    method,
 			FillMode:  mode,
 			Path:      path,
+			Size:      uint64(size),
 			BlockSize: blockSize,
 			FilePath:  filepath.Join(path, fmt.Sprintf("steadybit-disk-fill-%s", uuid.NewString())),
+			Sparse:    extutil.ToBool(request.Config["sparse"]),
+			Rate:      extutil.ToUInt(request.Config["rate"]),
 		}, nil
 	}
 }
 
-func calculateAllocation(fillMode FillMode, driveLetter string, percentageOrMegabytes uint64) (uint64, error) {
-	availableSpace, err := utils.GetDriveSpace(driveLetter, utils.Available)
+func calculateAllocation(runner utils.CommandRunner, fillMode FillMode, driveLetter string, percentageOrMegabytes uint64) (uint64, error) {
+	availableSpace, err := utils.GetDriveSpace(runner, driveLetter, utils.Available)
 
 	if err != nil {
 		return 0, err
 	}
 
-	if fillMode == MBLeft {
-		wantToAllocate := percentageOrMegabytes * 1000 * 1000
-		if availableSpace < wantToAllocate {
-			return 0, fmt.Errorf("not enough space on the drive")
-		}
-
-		return availableSpace - wantToAllocate, nil
-	}
-
-	if fillMode == MBToFill {
-		wantToAllocate := percentageOrMegabytes * 1000 * 1000
-		if availableSpace < wantToAllocate {
-			return 0, fmt.Errorf("not enough space on the drive")
-		}
-
-		return wantToAllocate, nil
-	}
-
-	if fillMode == Percentage {
-		totalSpace, err := utils.GetDriveSpace(driveLetter, utils.Total)
+	switch fillMode {
+	case MBLeft:
+		return diskfill.ToLeaveFree(availableSpace, percentageOrMegabytes*diskfill.Megabyte)
+	case MBToFill:
+		return diskfill.ToFill(availableSpace, percentageOrMegabytes*diskfill.Megabyte)
+	case Percentage:
+		totalSpace, err := utils.GetDriveSpace(runner, driveLetter, utils.Total)
 
 		if err != nil {
 			return 0, err
 		}
 
-		currentlyAllocated := ((float64(totalSpace) - float64(availableSpace)) / float64(totalSpace)) * 100
-
-		if currentlyAllocated > float64(percentageOrMegabytes) {
-			return 0, nil
-		}
-
-		totalModifier := (float64(percentageOrMegabytes) - currentlyAllocated) / 100
-
-		wantToAllocate := float64(totalSpace) * totalModifier
-
-		return uint64(wantToAllocate), nil
+		return diskfill.ToReachPercentage(availableSpace, totalSpace, percentageOrMegabytes), nil
 	}
 
 	return 0, fmt.Errorf("unknown fill mode")
@@ -324,7 +342,7 @@ func getFillDiskDescription() action_kit_api.ActionDescription {
 			{
 				Name:         "method",
 				Label:        "Method used to fill disk",
-				Description:  new("Should the disk filled at once or over time?"),
+				Description:  new("Should the disk filled at once or over time? Keeping the free space continuously grows and shrinks the file every few seconds to compensate other processes writing or deleting files, it requires the mode percentage or Megabytes to leave free."),
 				Required:     new(true),
 				Order:        new(5),
 				DefaultValue: new("AT_ONCE"),
@@ -332,19 +350,23 @@ func getFillDiskDescription() action_kit_api.ActionDescription {
 				Advanced:     new(true),
 				Options: new([]action_kit_api.ParameterOption{
 					action_kit_api.ExplicitParameterOption{
-						Label: "At once (fsutil)",
+						Label: "At once",
 						Value: string(AtOnce),
 					},
 					action_kit_api.ExplicitParameterOption{
-						Label: "Over time (dd)",
+						Label: "Over time",
 						Value: string(OverTime),
 					},
+					action_kit_api.ExplicitParameterOption{
+						Label: "Keep free space continuously",
+						Value: string(Continuous),
+					},
 				}),
 			},
 			{
 				Name:         "blocksize",
 				Label:        "Block Size (in MBytes) of the File to Write for method `OverTime`",
-				Description:  new("Define the block size for writing the file with the dd command. If the block size is larger than the fill value, the fill value will be used as block size."),
+				Description:  new("Define the size of each write. If the block size is larger than the fill value, the fill value will be used as block size."),
 				Type:         action_kit_api.Integer,
 				DefaultValue: new("5"),
 				Required:     new(true),
@@ -353,7 +375,33 @@ func getFillDiskDescription() action_kit_api.ActionDescription {
 				MaxValue:     new(1024),
 				Advanced:     new(true),
 			},
+			{
+				Name:         "rate",
+				Label:        "Write Rate (in MBytes per second) for method `OverTime`",
+				Description:  new("How fast the file is written. Use 0 to write as fast as the disk allows."),
+				Type:         action_kit_api.Integer,
+				DefaultValue: new("0"),
+				Order:        new(7),
+				MinValue:     new(0),
+				Advanced:     new(true),
+			},
+			{
+				Name:         "sparse",
+				Label:        "Sparse File for method `AtOnce`",
+				Description:  new("Creates a sparse file, which has the size but takes no space on the disk until it is written. Useful to test applications checking file sizes instead of the free space."),
+				Type:         action_kit_api.Boolean,
+				DefaultValue: new("false"),
+				Order:        new(8),
+				Advanced:     new(true),
+			},
 		},
+		Status: new(action_kit_api.MutatingEndpointReferenceWithCallInterval{
+			CallInterval: new("1s"),
+		}),
+		Widgets: new([]action_kit_api.Widget{
+			metricsWidget("Free Disk Space", diskFreeMetric, "drive"),
+			metricsWidget("Fill File Size", diskFillFileMetric, "drive"),
+		}),
 		Stop: new(action_kit_api.MutatingEndpointReference{}),
 	}
 }
@@ -378,112 +426,164 @@ func (a *fillDiskAction) Prepare(ctx context.Context, state *FillDiskActionState
 }
 
 func (a *fillDiskAction) Start(ctx context.Context, state *FillDiskActionState) (*action_kit_api.StartResult, error) {
-	if state.StressOpts.Method == AtOnce {
-		err := utils.IsExecutableOperational("fsutil")
+	opts := state.StressOpts
+	filler := opts.filler()
+	var message string
 
-		if err != nil {
+	switch opts.Method {
+	case AtOnce:
+		if err := filler.Allocate(); err != nil {
 			return nil, err
 		}
-		command := exec.CommandContext(context.Background(), "fsutil", state.StressOpts.Args()...)
-		go func() {
-			log.Info().Msgf("Running command: %s, %s.", command.Path, command.Args)
-			output, err := command.CombinedOutput()
-
-			if err != nil {
-				log.Error().Msgf("Failed to start disk fill attack: %s.", err)
-			}
-
-			log.Info().Msgf("%s", output)
-		}()
-	} else {
-		executable := resolveExecutable("coreutils", "STEADYBIT_COREUTILS")
-
-		err := utils.IsExecutableOperational(executable, "dd", "--help")
-
-		if err != nil {
-			return nil, err
+		a.fills.Store(state.ExecutionId, newDiskFill(opts.FilePath, filler.Filled, nil))
+		message = fmt.Sprintf("Allocated %d MB in %s.", BytesToMegabytes(opts.ByteSize), opts.FilePath)
+	case Continuous:
+		keeper := opts.keeper(a.volume)
+		fill := newDiskFill(opts.FilePath, keeper.Size, keeper.Run)
+		fill.keeper = keeper
+		a.fills.Store(state.ExecutionId, fill)
+		if opts.FillMode == Percentage {
+			message = fmt.Sprintf("Keeping %d%% of the drive used with %s.", opts.Size, opts.FilePath)
+		} else {
+			message = fmt.Sprintf("Keeping %d MB free on the drive with %s.", opts.Size, opts.FilePath)
 		}
-
-		err = utils.IsExecutableOperational("devzero", "--help")
-
-		if err != nil {
-			return nil, err
+	default:
+		a.fills.Store(state.ExecutionId, newDiskFill(opts.FilePath, filler.Filled, filler.Write))
+		if opts.Rate > 0 {
+			message = fmt.Sprintf("Writing %d MB to %s at %d MB/s.", BytesToMegabytes(opts.ByteSize), opts.FilePath, opts.Rate)
+		} else {
+			message = fmt.Sprintf("Writing %d MB to %s.", BytesToMegabytes(opts.ByteSize), opts.FilePath)
 		}
+	}
 
-		bgCtx := context.Background()
-		devzeroCmd := exec.CommandContext(bgCtx, "devzero")
-		ddCmd := exec.CommandContext(bgCtx, executable, state.StressOpts.Args()...)
-
-		log.Info().Msgf("Running command: %s, %s.", ddCmd.Path, ddCmd.Args)
-
-		go func() {
-			pipeReader, pipeWriter := io.Pipe()
-			defer pipeReader.Close()
-			defer pipeWriter.Close()
-
-			devzeroCmd.Stdout = pipeWriter
-			ddCmd.Stdin = pipeReader
+	return &action_kit_api.StartResult{
+		Messages: new([]action_kit_api.Message{
+			{
+				Level:   extutil.Ptr(action_kit_api.Info),
+				Message: message,
+			},
+		}),
+	}, nil
+}
 
-			ddCmd.Stdout = os.Stdout
-			ddCmd.Stderr = os.Stderr
+// Status reports the free space of the filled drive and, when filling continuously, each adjustment of the file. The
+// attack runs until it is stopped, unless writing the file fails.
+func (a *fillDiskAction) Status(_ context.Context, state *FillDiskActionState) (*action_kit_api.StatusResult, error) {
+	driveLetter := state.StressOpts.driveLetter()
+	labels := map[string]string{"drive": driveLetter}
+	result := &action_kit_api.StatusResult{
+		Completed: false,
+		Metrics: sampledMetrics(diskFreeMetric, state.ExecutionId, labels, func() (uint64, error) {
+			return a.disk.freeSpace(driveLetter)
+		}),
+	}
 
-			if err := devzeroCmd.Start(); err != nil {
-				log.Err(err).Msgf("failed to start devzero")
+	if value, ok := a.fills.Load(state.ExecutionId); ok {
+		fill := value.(*diskFill)
+		if fill.keeper != nil {
+			metrics := adjustmentMetrics(fill.keeper.TakeAdjustments(), state.ExecutionId, labels)
+			if result.Metrics != nil {
+				metrics = append(*result.Metrics, metrics...)
 			}
-
-			if err := ddCmd.Start(); err != nil {
-				log.Err(err).Msgf("failed to start dd")
+			result.Metrics = &metrics
+		}
+		if err := fill.failed(); err != nil {
+			result.Completed = true
+			result.Error = &action_kit_api.ActionKitError{
+				Title:  fmt.Sprintf("Failed to fill disk: %s", err),
+				Status: extutil.Ptr(action_kit_api.Errored),
 			}
+		}
+	}
+	return result, nil
+}
 
-			if err := ddCmd.Wait(); err != nil {
-				log.Err(err).Msg("dd failed executing: might have been stopped forcefully")
-			}
+func (a *fillDiskAction) Stop(ctx context.Context, state *FillDiskActionState) (*action_kit_api.StopResult, error) {
+	value, ok := a.fills.LoadAndDelete(state.ExecutionId)
 
-			if err := devzeroCmd.Process.Kill(); err != nil {
-				log.Err(err).Msg("failed to stop devzero")
-			}
+	if !ok {
+		log.Debug().Msg("Execution run data not found, stop was already called")
+		// the extension may have been restarted during the attack
+		if err := diskfill.Remove(state.StressOpts.FilePath); err != nil {
+			log.Warn().Err(err).Msg("failed to delete leftover fill file")
+		}
+		return nil, nil
+	}
 
-			devzeroCmd.Wait()
-		}()
+	fill := value.(*diskFill)
+	if err := fill.stop(ctx); err != nil {
+		return nil, err
 	}
 
-	return &action_kit_api.StartResult{
-		Messages: new([]action_kit_api.Message{
+	return &action_kit_api.StopResult{
+		Messages: &[]action_kit_api.Message{
 			{
 				Level:   extutil.Ptr(action_kit_api.Info),
-				Message: fmt.Sprintf("Starting disk fill with args: %s.", fmt.Sprintf("\"%s\"", strings.Join(state.StressOpts.Args(), " "))),
+				Message: fmt.Sprintf("Deleted %s after filling %d MB.", fill.path, BytesToMegabytes(fill.filled())),
 			},
-		}),
+		},
 	}, nil
 }
 
-func (a *fillDiskAction) Stop(_ context.Context, state *FillDiskActionState) (*action_kit_api.StopResult, error) {
-	messages := make([]action_kit_api.Message, 0)
-
-	err := os.Remove(state.StressOpts.FilePath)
+// diskFill is a file filling a disk, written in the background when filling over time or continuously.
+type diskFill struct {
+	path   string
+	filled func() uint64
+	// keeper is set when filling continuously.
+	keeper *diskfill.Keeper
+	cancel context.CancelFunc
+	// done is closed once writing finished, err is set before.
+	done chan struct{}
+	err  error
+}
 
-	if err != nil {
-		return nil, err
+func newDiskFill(path string, filled func() uint64, write func(ctx context.Context) error) *diskFill {
+	ctx, cancel := context.WithCancel(context.Background())
+	fill := &diskFill{path: path, filled: filled, cancel: cancel, done: make(chan struct{})}
+	if write == nil {
+		close(fill.done)
+		return fill
 	}
 
-	err = utils.StopProcess("coreutils")
+	go func() {
+		defer close(fill.done)
+		if err := write(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			log.Error().Err(err).Str("file", path).Msg("failed to fill disk")
+			fill.err = err
+		}
+	}()
+	return fill
+}
 
-	if err != nil {
-		return nil, err
+// failed returns the error if writing the file failed.
+func (f *diskFill) failed() error {
+	select {
+	case <-f.done:
+		return f.err
+	default:
+		return nil
 	}
+}
 
-	err = utils.StopProcess("devzero")
-
-	if err != nil {
-		return nil, err
+// stop stops writing and deletes the file.
+func (f *diskFill) stop(ctx context.Context) error {
+	f.cancel()
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		return fmt.Errorf("writing %s did not stop: %w", f.path, ctx.Err())
 	}
+	return diskfill.Remove(f.path)
+}
 
-	messages = append(messages, action_kit_api.Message{
-		Level:   extutil.Ptr(action_kit_api.Info),
-		Message: "Canceled fsutil",
-	})
-
-	return &action_kit_api.StopResult{
-		Messages: &messages,
-	}, nil
+// adjustmentMetrics returns the size of the file and the change of the size for each adjustment.
+func adjustmentMetrics(adjustments []diskfill.Adjustment, executionId uuid.UUID, labels map[string]string) action_kit_api.Metrics {
+	metrics := make(action_kit_api.Metrics, 0, 2*len(adjustments))
+	for _, adjustment := range adjustments {
+		metrics = append(metrics,
+			newMetric(diskFillFileMetric, executionId, labels, adjustment.Time, float64(adjustment.Size)),
+			newMetric(diskFillAdjustmentMetric, executionId, labels, adjustment.Time, float64(adjustment.Delta)),
+		)
+	}
+	return metrics
 }
@@ -3,12 +3,15 @@ package exthostwindows
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestActionFillDisk_Prepare(t *testing.T) {
@@ -51,6 +54,7 @@ func TestActionFillDisk_Prepare(t *testing.T) {
 					Method:    FillDiskMethods.AtOnce,
 					Path:      "C:\\",
 					BlockSize: 5,
+					Size:      80,
 				},
 			},
 		},
@@ -118,7 +122,29 @@ func TestActionFillDisk_Prepare(t *testing.T) {
 				}),
 			},
 
-			wantedError: fmt.Sprintf("unit must be one of the following: %s, %s", FillDiskMethods.AtOnce, FillDiskMethods.OverTime),
+			wantedError: fmt.Sprintf("unit must be one of the following: %s, %s, %s", FillDiskMethods.AtOnce, FillDiskMethods.OverTime, FillDiskMethods.Continuous),
+		},
+		{
+			name: "Should return error continuous method without free space",
+			requestBody: action_kit_api.PrepareActionRequestBody{
+				Config: map[string]any{
+					"action":    "prepare",
+					"duration":  "1000",
+					"mode":      "MB_TO_FILL",
+					"size":      "80",
+					"path":      "C:\\",
+					"method":    "CONTINUOUS",
+					"blocksize": "5",
+				},
+				ExecutionId: uuid.New(),
+				Target: new(action_kit_api.Target{
+					Attributes: map[string][]string{
+						"host.hostname": {hostname},
+					},
+				}),
+			},
+
+			wantedError: fmt.Sprintf("method %s requires mode %s or %s", FillDiskMethods.Continuous, FillDiskModes.MBLeft, FillDiskModes.Percentage),
 		},
 		{
 			name: "Should return error path must not be empty",
@@ -234,7 +260,112 @@ func TestActionFillDisk_Prepare(t *testing.T) {
 				assert.Equal(t, tt.wantedState.StressOpts.FillMode, state.StressOpts.FillMode)
 				assert.Equal(t, tt.wantedState.StressOpts.Method, state.StressOpts.Method)
 				assert.Equal(t, tt.wantedState.StressOpts.Path, state.StressOpts.Path)
+				assert.Equal(t, tt.wantedState.StressOpts.Size, state.StressOpts.Size)
 			}
 		})
 	}
 }
+
+func TestActionFillDisk_StartAndStop(t *testing.T) {
+	for _, method := range []FillMethod{AtOnce, OverTime} {
+		t.Run(string(method), func(t *testing.T) {
+			dir := t.TempDir()
+			action := &fillDiskAction{disk: fakeDiskSampler{"C": 1_000_000_000}}
+			state := &FillDiskActionState{
+				ExecutionId: uuid.New(),
+				StressOpts: FillDiskOpts{
+					Method:    method,
+					Path:      `C:\`,
+					ByteSize:  10 * 1000 * 1000,
+					BlockSize: 1,
+					Rate:      1,
+					FilePath:  filepath.Join(dir, "steadybit-disk-fill"),
+				},
+			}
+
+			_, err := action.Start(t.Context(), state)
+			require.NoError(t, err)
+			// written in the background when filling over time
+			assert.EventuallyWithT(t, func(c *assert.CollectT) {
+				assert.FileExists(c, state.StressOpts.FilePath)
+			}, time.Second, 10*time.Millisecond)
+
+			status, err := action.Status(t.Context(), state)
+			require.NoError(t, err)
+			assert.False(t, status.Completed)
+			assert.Nil(t, status.Error)
+
+			result, err := action.Stop(t.Context(), state)
+			require.NoError(t, err)
+			require.NotNil(t, result.Messages)
+			assert.Contains(t, (*result.Messages)[0].Message, "Deleted")
+			assert.NoFileExists(t, state.StressOpts.FilePath)
+		})
+	}
+}
+
+func TestActionFillDisk_StatusReportsWriteFailure(t *testing.T) {
+	action := &fillDiskAction{disk: fakeDiskSampler{"C": 1_000_000_000}}
+	state := &FillDiskActionState{
+		ExecutionId: uuid.New(),
+		StressOpts: FillDiskOpts{
+			Method:    OverTime,
+			Path:      `C:\`,
+			ByteSize:  1000,
+			BlockSize: 1,
+			FilePath:  filepath.Join(t.TempDir(), "missing", "steadybit-disk-fill"),
+		},
+	}
+
+	_, err := action.Start(t.Context(), state)
+	require.NoError(t, err)
+
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		status, err := action.Status(t.Context(), state)
+		require.NoError(c, err)
+		assert.True(c, status.Completed)
+		require.NotNil(c, status.Error)
+		assert.Contains(c, status.Error.Title, "Failed to fill disk: failed to create fill file")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestActionFillDisk_ContinuousReportsAdjustments(t *testing.T) {
+	action := &fillDiskAction{
+		disk: fakeDiskSampler{"C": 20_000_000},
+		volume: func(string) (utils.VolumeSpace, error) {
+			return utils.VolumeSpace{Available: 50_000_000, Total: 100_000_000}, nil
+		},
+	}
+	state := &FillDiskActionState{
+		ExecutionId: uuid.New(),
+		StressOpts: FillDiskOpts{
+			FillMode: MBLeft,
+			Method:   Continuous,
+			Path:     `C:\`,
+			Size:     20,
+			FilePath: filepath.Join(t.TempDir(), "steadybit-disk-fill"),
+		},
+	}
+
+	_, err := action.Start(t.Context(), state)
+	require.NoError(t, err)
+
+	value, ok := action.fills.Load(state.ExecutionId)
+	require.True(t, ok)
+	assert.Eventually(t, func() bool {
+		return value.(*diskFill).keeper.Size() == 30_000_000
+	}, time.Second, 10*time.Millisecond)
+
+	status, err := action.Status(t.Context(), state)
+	require.NoError(t, err)
+	assert.False(t, status.Completed)
+	require.NotNil(t, status.Metrics)
+	assert.Equal(t, []string{diskFreeMetric, diskFillFileMetric, diskFillAdjustmentMetric}, metricNames(*status.Metrics))
+	assert.Equal(t, float64(30_000_000), (*status.Metrics)[1].Value)
+	assert.Equal(t, float64(30_000_000), (*status.Metrics)[2].Value)
+
+	result, err := action.Stop(t.Context(), state)
+	require.NoError(t, err)
+	assert.Contains(t, (*result.Messages)[0].Message, "after filling 30 MB")
+	assert.NoFileExists(t, state.StressOpts.FilePath)
+}
@@ -4,8 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os/exec"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -64,6 +64,9 @@ func (u Unit) IsValid() bool {
 type fillMemAction struct {
 	description  action_kit_api.ActionDescription
 	optsProvider fillMemOptsProvider
+	runner       utils.CommandRunner
+	memory       memorySampler
+	memfills     sync.Map // execution id -> *memfillExecution
 }
 
 type FillMemOpts struct {
@@ -98,6 +101,8 @@ func NewFillMemAction() action_kit_sdk.Action[FillMemActionState] {
 	return &fillMemAction{
 		description:  getFillMemDescription(),
 		optsProvider: fillMem(),
+		runner:       utils.NewCommandRunner(),
+		memory:       systemMemorySampler{},
 	}
 }
 
@@ -216,6 +221,12 @@ func getFillMemDescription() action_kit_api.ActionDescription {
 				}),
 			},
 		},
+		Status: new(action_kit_api.MutatingEndpointReferenceWithCallInterval{
+			CallInterval: new("1s"),
+		}),
+		Widgets: new([]action_kit_api.Widget{
+			metricsWidget("Committed Memory", committedMemoryMetric, "execution_id"),
+		}),
 		Stop: new(action_kit_api.MutatingEndpointReference{}),
 	}
 }
@@ -240,25 +251,20 @@ func (a *fillMemAction) Prepare(ctx context.Context, state *FillMemActionState,
 }
 
 func (a *fillMemAction) Start(ctx context.Context, state *FillMemActionState) (*action_kit_api.StartResult, error) {
-	err := utils.IsExecutableOperational("memfill", "--help")
+	err := utils.IsExecutableOperational(a.runner, "memfill", "--help")
 
 	if err != nil {
 		return nil, err
 	}
 
-	command := exec.CommandContext(context.Background(), "memfill", state.StressOpts.Args()...)
-
-	log.Info().Msgf("Running command: %s, %s.", command.Path, command.Args)
-
-	go func() {
-		output, err := command.CombinedOutput()
+	reports := &stressReports[memfillReport]{}
+	process, err := startStressProcess(a.runner, utils.Command{Name: "memfill", Args: state.StressOpts.Args(), Stdout: reports})
 
-		if err != nil {
-			log.Error().Msgf("Failed to start memfill attack: %s.", err)
-		}
+	if err != nil {
+		return nil, err
+	}
 
-		log.Info().Msgf("%s", output)
-	}()
+	a.memfills.Store(state.ExecutionId, &memfillExecution{process: process, reports: reports})
 
 	return &action_kit_api.StartResult{
 		Messages: new([]action_kit_api.Message{
@@ -271,43 +277,54 @@ func (a *fillMemAction) Start(ctx context.Context, state *FillMemActionState) (*
 }
 
 func (a *fillMemAction) Status(_ context.Context, state *FillMemActionState) (*action_kit_api.StatusResult, error) {
-	isRunning, err := utils.IsProcessRunning("memfill")
+	value, ok := a.memfills.Load(state.ExecutionId)
 
-	if err != nil {
-		return nil, err
+	if !ok {
+		return &action_kit_api.StatusResult{Completed: true}, nil
 	}
 
-	if isRunning {
-		return &action_kit_api.StatusResult{Completed: false}, nil
+	memfill := value.(*memfillExecution)
+	result := memfill.process.status("Memfill stopped")
+	result.Metrics = sampledMetrics(committedMemoryMetric, state.ExecutionId, nil, a.memory.committedMemory)
+
+	if report := memfill.reports.last(); report != nil {
+		messages := []action_kit_api.Message{{
+			Level:   extutil.Ptr(action_kit_api.Info),
+			Message: report.String(),
+		}}
+		if result.Messages != nil {
+			messages = append(messages, *result.Messages...)
+		}
+		result.Messages = &messages
 	}
 
-	return &action_kit_api.StatusResult{
-		Completed: true,
-		Messages: &[]action_kit_api.Message{
-			{
-				Level:   extutil.Ptr(action_kit_api.Info),
-				Message: "Memfill stopped",
-			},
-		},
-	}, nil
-
+	return result, nil
 }
 
-func (a *fillMemAction) Stop(_ context.Context, state *FillMemActionState) (*action_kit_api.StopResult, error) {
-	messages := make([]action_kit_api.Message, 0)
+func (a *fillMemAction) Stop(ctx context.Context, state *FillMemActionState) (*action_kit_api.StopResult, error) {
+	value, ok := a.memfills.LoadAndDelete(state.ExecutionId)
 
-	err := utils.StopProcess("memfill")
-
-	if err != nil {
-		return nil, err
+	if !ok {
+		log.Debug().Msg("Execution run data not found, stop was already called")
+		return nil, nil
 	}
 
-	messages = append(messages, action_kit_api.Message{
-		Level:   extutil.Ptr(action_kit_api.Info),
-		Message: "Canceled memfill",
-	})
+	return value.(*memfillExecution).process.stop(ctx, "Canceled memfill")
+}
 
-	return &action_kit_api.StopResult{
-		Messages: &messages,
-	}, nil
+// memfillExecution is a running memfill together with its reports.
+type memfillExecution struct {
+	process *stressProcess
+	reports *stressReports[memfillReport]
+}
+
+// memfillReport is written to stdout by memfill once per second.
+type memfillReport struct {
+	Allocated uint64 `json:"allocated"`
+	Used      uint64 `json:"used"`
+	Total     uint64 `json:"total"`
+}
+
+func (r *memfillReport) String() string {
+	return fmt.Sprintf("Memfill allocated %d MiB, host memory usage is %d of %d MiB.", r.Allocated/1024/1024, r.Used/1024/1024, r.Total/1024/1024)
 }
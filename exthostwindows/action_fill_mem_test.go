@@ -8,7 +8,9 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils/utilstest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestActionFillMem_Prepare(t *testing.T) {
@@ -179,3 +181,31 @@ func TestActionFillMem_Prepare(t *testing.T) {
 		})
 	}
 }
+
+func TestActionFillMem_StartReportsAllocation(t *testing.T) {
+	report := `{"allocated":1073741824,"used":6442450944,"total":8589934592}`
+	runner := utilstest.NewRecordingRunner().Respond("memfill 75% usage 30s", "starting\n"+report+"\n", nil)
+	action := &fillMemAction{runner: runner, memory: fakeMemorySampler{committed: 6 << 30}}
+	state := &FillMemActionState{
+		ExecutionId: uuid.New(),
+		StressOpts:  FillMemOpts{Duration: 30 * time.Second, Mode: ModeUsage, Unit: UnitPercent, Size: 75},
+	}
+
+	_, err := action.Start(t.Context(), state)
+	require.NoError(t, err)
+
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		result, err := action.Status(t.Context(), state)
+		require.NoError(c, err)
+		assert.True(c, result.Completed)
+		require.NotNil(c, result.Messages)
+		require.Len(c, *result.Messages, 3)
+		assert.Equal(c, "Memfill allocated 1024 MiB, host memory usage is 6144 of 8192 MiB.", (*result.Messages)[0].Message)
+		require.NotNil(c, result.Metrics)
+		assert.Equal(c, []string{committedMemoryMetric}, metricNames(*result.Metrics))
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = action.Stop(t.Context(), state)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"memfill --help", "memfill 75% usage 30s"}, runner.Commands())
+}
@@ -6,12 +6,16 @@ package exthostwindows
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
+	"slices"
 	"strings"
 
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 	"github.com/steadybit/extension-host-windows/exthostwindows/network"
+	stopprocess "github.com/steadybit/extension-host-windows/exthostwindows/process"
 	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
 
 	"github.com/steadybit/action-kit/go/action_kit_api/v2"
@@ -30,9 +34,11 @@ type networkAction struct {
 	description  action_kit_api.ActionDescription
 	optsProvider networkOptsProvider
 	optsDecoder  networkOptsDecoder
+	runner       utils.CommandRunner
 }
 
 type NetworkActionState struct {
+	ExecutionId uuid.UUID
 	NetworkOpts json.RawMessage
 }
 
@@ -88,6 +94,43 @@ var commonNetworkParameters = []action_kit_api.ActionParameter{
 		Advanced:     new(true),
 		Order:        new(103),
 	},
+	{
+		Name:        "process",
+		Label:       "Process",
+		Description: new("Restrict to the traffic of a process, given by PID or image name, e.g. sqlservr.exe. The traffic is matched by the local ports of the process, which are refreshed while the attack is running. ICMP traffic can't be attributed to a process."),
+		Type:        action_kit_api.ActionParameterTypeString,
+		Advanced:    new(true),
+		Order:       new(106),
+	},
+	{
+		Name:        "protocol",
+		Label:       "Protocols",
+		Description: new("Restrict which protocols are affected. TCP and UDP if none specified. ICMP traffic is affected regardless of the ports."),
+		Type:        action_kit_api.ActionParameterTypeStringArray,
+		Advanced:    new(true),
+		Order:       new(105),
+		Options: new([]action_kit_api.ParameterOption{
+			action_kit_api.ExplicitParameterOption{
+				Label: "TCP",
+				Value: string(network.ProtocolTCP),
+			},
+			action_kit_api.ExplicitParameterOption{
+				Label: "UDP",
+				Value: string(network.ProtocolUDP),
+			},
+			action_kit_api.ExplicitParameterOption{
+				Label: "ICMP",
+				Value: string(network.ProtocolICMP),
+			},
+		}),
+	},
+}
+
+// withoutParameter returns a copy of the parameters without the parameter with the given name.
+func withoutParameter(parameters []action_kit_api.ActionParameter, name string) []action_kit_api.ActionParameter {
+	return slices.DeleteFunc(slices.Clone(parameters), func(parameter action_kit_api.ActionParameter) bool {
+		return parameter.Name == name
+	})
 }
 
 func (a *networkAction) NewEmptyState() NetworkActionState {
@@ -118,6 +161,7 @@ func (a *networkAction) Prepare(ctx context.Context, state *NetworkActionState,
 		return nil, extensionKit.ToError("Failed to serialize network settings.", err)
 	}
 
+	state.ExecutionId = request.ExecutionId
 	state.NetworkOpts = rawOpts
 
 	return &action_kit_api.PrepareResult{Messages: &messages}, nil
@@ -125,7 +169,7 @@ func (a *networkAction) Prepare(ctx context.Context, state *NetworkActionState,
 
 func (a *networkAction) Start(ctx context.Context, state *NetworkActionState) (*action_kit_api.StartResult, error) {
 
-	if isTestSigningEnabled, err := utils.IsTestSigningEnabled(); err != nil {
+	if isTestSigningEnabled, err := utils.IsTestSigningEnabled(a.runner); err != nil {
 		log.Warn().Err(err).Msg("failed retrieving testsigning flag from the bcdedit.")
 	} else if isTestSigningEnabled {
 		log.Debug().Msg("testsigning is enabled on the machine")
@@ -143,7 +187,16 @@ func (a *networkAction) Start(ctx context.Context, state *NetworkActionState) (*
 		},
 	}}
 
-	err = network.Apply(ctx, opts)
+	err = network.Apply(ctx, a.runner, state.ExecutionId, opts)
+	if qosErrs := network.QosErrors(err); len(qosErrs) > 0 {
+		for _, qosErr := range qosErrs {
+			*result.Messages = append(*result.Messages, action_kit_api.Message{
+				Level:   extutil.Ptr(action_kit_api.Error),
+				Message: qosErr.Error(),
+			})
+		}
+		return &result, extensionKit.ToError("Failed to apply QoS policies.", err)
+	}
 	if err != nil {
 		return &result, extensionKit.ToError("Failed to apply network settings.", err)
 	}
@@ -157,7 +210,7 @@ func (a *networkAction) Stop(ctx context.Context, state *NetworkActionState) (*a
 		return nil, extensionKit.ToError("Failed to deserialize network settings.", err)
 	}
 
-	if err := network.Revert(ctx, opts); err != nil {
+	if err := network.Revert(ctx, a.runner, state.ExecutionId, opts); err != nil {
 		return nil, extensionKit.ToError("Failed to revert network settings.", err)
 	}
 
@@ -179,7 +232,7 @@ func mapToNetworkFilter(ctx context.Context, actionConfig map[string]any, restri
 		includeCidrs = akn.NetAny
 	}
 
-	portRanges, err := utils.ParsePortRanges(extutil.ToStringArray(actionConfig["port"]))
+	portRanges, err := utils.ParsePortRanges(toStringList(actionConfig["port"]))
 	if err != nil {
 		return network.Filter{}, nil, err
 	}
@@ -188,6 +241,23 @@ func mapToNetworkFilter(ctx context.Context, actionConfig map[string]any, restri
 		portRanges = []akn.PortRange{akn.PortRangeAny}
 	}
 
+	protocols, err := network.ParseProtocols(extutil.ToStringArray(actionConfig["protocol"]))
+	if err != nil {
+		return network.Filter{}, nil, err
+	}
+
+	process := strings.TrimSpace(extutil.ToString(actionConfig["process"]))
+	if process != "" {
+		if len(stopprocess.FindProcessIdsByImageName(process)) == 0 {
+			return network.Filter{}, nil, fmt.Errorf("no process found matching %q", process)
+		}
+		if len(protocols) > 0 && !slices.ContainsFunc(protocols, func(protocol network.Protocol) bool {
+			return protocol != network.ProtocolICMP
+		}) {
+			return network.Filter{}, nil, errors.New("ICMP traffic can't be restricted to a process")
+		}
+	}
+
 	includes := akn.NewNetWithPortRanges(includeCidrs, portRanges...)
 	for _, i := range includes {
 		i.Comment = "parameters"
@@ -211,7 +281,7 @@ func mapToNetworkFilter(ctx context.Context, actionConfig map[string]any, restri
 		})
 	}
 
-	interfaces := extutil.ToStringArray(actionConfig["networkInterface"])
+	interfaces := toStringList(actionConfig["networkInterface"])
 	var interfaceIndexes []int
 	if len(interfaces) != 0 {
 		interfaceIndexes = akn.GetNetworkInterfaceIndexesByName(interfaces)
@@ -221,9 +291,30 @@ func mapToNetworkFilter(ctx context.Context, actionConfig map[string]any, restri
 		Include:          includes,
 		Exclude:          excludes,
 		InterfaceIndexes: interfaceIndexes,
+		Protocols:        protocols,
+		Process:          process,
 	}, messages, nil
 }
 
+// toStringList converts a string array parameter to a list without blank values. A single string is split at commas,
+// so parameters which used to be strings keep working with existing configurations.
+func toStringList(value any) []string {
+	var raw []string
+	if s, ok := value.(string); ok {
+		raw = strings.Split(s, ",")
+	} else {
+		raw = extutil.ToStringArray(value)
+	}
+
+	var values []string
+	for _, v := range raw {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
 func condenseExcludes(excludes []akn.NetWithPortRange) ([]akn.NetWithPortRange, bool) {
 	l := len(excludes)
 	excludes = utils.CondenseNetWithPortRange(excludes, 500)
@@ -6,10 +6,12 @@ package exthostwindows
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"strconv"
 	"strings"
+	"time"
 
 	akn "github.com/steadybit/action-kit/go/action_kit_commons/network"
 	"github.com/steadybit/extension-host-windows/exthostwindows/network"
@@ -26,14 +28,15 @@ func NewNetworkLimitBandwidthContainerAction() action_kit_sdk.Action[NetworkActi
 		optsProvider: limitBandwidth(),
 		optsDecoder:  limitBandwidthDecode,
 		description:  getNetworkLimitBandwidthDescription(),
+		runner:       utils.NewNetworkCommandRunner(),
 	}
 }
 
 func getNetworkLimitBandwidthDescription() action_kit_api.ActionDescription {
 	return action_kit_api.ActionDescription{
 		Id:          fmt.Sprintf("%s.network_bandwidth", BaseActionID),
-		Label:       "Limit Outgoing Bandwidth",
-		Description: "Limit available egress network bandwidth.",
+		Label:       "Limit Bandwidth",
+		Description: "Limit available network bandwidth.",
 		Version:     extbuild.GetSemverVersionStringOrUnknown(),
 		Icon:        new(bandwidthIcon),
 		TargetSelection: &action_kit_api.TargetSelection{
@@ -55,13 +58,57 @@ func getNetworkLimitBandwidthDescription() action_kit_api.ActionDescription {
 				Required:     new(true),
 				Order:        new(1),
 			},
+			{
+				Name:         "backend",
+				Label:        "Backend",
+				Description:  new("How the bandwidth is limited. Windows QoS policies only limit outgoing traffic to the given hosts and a single port range. WinDivert limits incoming and outgoing traffic with a token bucket and supports multiple port ranges and network interfaces."),
+				Type:         action_kit_api.ActionParameterTypeString,
+				DefaultValue: new(bandwidthBackendQoS),
+				Required:     new(true),
+				Advanced:     new(true),
+				Order:        new(2),
+				Options: new([]action_kit_api.ParameterOption{
+					action_kit_api.ExplicitParameterOption{
+						Label: "Windows QoS Policy",
+						Value: bandwidthBackendQoS,
+					},
+					action_kit_api.ExplicitParameterOption{
+						Label: "WinDivert",
+						Value: bandwidthBackendWinDivert,
+					},
+				}),
+			},
+			{
+				Name:         "direction",
+				Label:        "Direction",
+				Description:  new("Which traffic is limited. Incoming traffic is only supported by the WinDivert backend."),
+				Type:         action_kit_api.ActionParameterTypeString,
+				DefaultValue: new(string(network.DirectionOutgoing)),
+				Required:     new(true),
+				Advanced:     new(true),
+				Order:        new(3),
+				Options: new([]action_kit_api.ParameterOption{
+					action_kit_api.ExplicitParameterOption{
+						Label: "Outgoing",
+						Value: string(network.DirectionOutgoing),
+					},
+					action_kit_api.ExplicitParameterOption{
+						Label: "Incoming",
+						Value: string(network.DirectionIncoming),
+					},
+					action_kit_api.ExplicitParameterOption{
+						Label: "Both",
+						Value: string(network.DirectionAll),
+					},
+				}),
+			},
 			{
 				Name:  "filter",
 				Label: "Traffic Filter",
 				Type:  action_kit_api.ActionParameterTypeHeader,
 				Order: new(100),
 				Hint: &action_kit_api.ActionHint{
-					Content: "Either the hostname or IP parameter is required.",
+					Content: "Either the hostname or IP parameter is required for the Windows QoS Policy backend.",
 					Type:    action_kit_api.HintInfo,
 				},
 			},
@@ -84,16 +131,22 @@ func getNetworkLimitBandwidthDescription() action_kit_api.ActionDescription {
 			{
 				Name:         "port",
 				Label:        "Ports",
-				Description:  new("Restrict to/from which port the traffic is affected."),
-				Type:         action_kit_api.ActionParameterTypeString,
+				Description:  new("Restrict to/from which ports the traffic is affected. The Windows QoS Policy backend supports a single port range only."),
+				Type:         action_kit_api.ActionParameterTypeStringArray,
 				DefaultValue: new(""),
 				Advanced:     new(true),
 				Order:        new(103),
 			},
+			networkInterfaceParameter,
 		},
 	}
 }
 
+const (
+	bandwidthBackendQoS       = "qos"
+	bandwidthBackendWinDivert = "windivert"
+)
+
 func limitBandwidth() networkOptsProvider {
 	return func(ctx context.Context, request action_kit_api.PrepareActionRequestBody) (network.WinOpts, action_kit_api.Messages, error) {
 		_, err := CheckTargetHostname(request.Target.Attributes)
@@ -112,38 +165,91 @@ func limitBandwidth() networkOptsProvider {
 			return nil, nil, err
 		}
 
-		ipsAndHosts := append(
-			extutil.ToStringArray(request.Config["ip"]),
-			extutil.ToStringArray(request.Config["hostname"])...,
-		)
-		if len(ipsAndHosts) == 0 {
-			return nil, nil, fmt.Errorf("hostname or IP required")
+		switch backend := extutil.ToString(request.Config["backend"]); backend {
+		case "", bandwidthBackendQoS:
+			return limitBandwidthQoS(ctx, request, bandwidth)
+		case bandwidthBackendWinDivert:
+			return limitBandwidthWinDivert(ctx, request, bandwidth, time.Duration(parsedDuration)*time.Millisecond)
+		default:
+			return nil, nil, fmt.Errorf("unsupported bandwidth backend %q", backend)
 		}
+	}
+}
 
-		includeCidrs, err := utils.MapToNetworks(ctx, ipsAndHosts...)
-		if err != nil {
-			return nil, nil, err
-		}
+func limitBandwidthQoS(ctx context.Context, request action_kit_api.PrepareActionRequestBody, bandwidth string) (network.WinOpts, action_kit_api.Messages, error) {
+	if direction := extutil.ToString(request.Config["direction"]); direction != "" && direction != string(network.DirectionOutgoing) {
+		return nil, nil, fmt.Errorf("the Windows QoS Policy backend only limits outgoing traffic")
+	}
+	if len(toStringList(request.Config["networkInterface"])) > 0 {
+		return nil, nil, fmt.Errorf("the Windows QoS Policy backend doesn't support network interfaces")
+	}
 
-		portRange := akn.PortRangeAny
-		if portRangeParameter := extutil.ToString(request.Config["port"]); portRangeParameter != "" {
-			portRange, err = akn.ParsePortRange(portRangeParameter)
-			if err != nil {
-				return nil, nil, err
-			}
-		}
+	ipsAndHosts := append(
+		extutil.ToStringArray(request.Config["ip"]),
+		extutil.ToStringArray(request.Config["hostname"])...,
+	)
+	if len(ipsAndHosts) == 0 {
+		return nil, nil, fmt.Errorf("hostname or IP required")
+	}
+
+	includeCidrs, err := utils.MapToNetworks(ctx, ipsAndHosts...)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		err = validateRestrictedEndpoints(request, includeCidrs, portRange)
+	portRange := akn.PortRangeAny
+	switch ports := toStringList(request.Config["port"]); len(ports) {
+	case 0:
+	case 1:
+		portRange, err = akn.ParsePortRange(ports[0])
 		if err != nil {
 			return nil, nil, err
 		}
+	default:
+		return nil, nil, fmt.Errorf("the Windows QoS Policy backend supports a single port range only")
+	}
 
-		return &network.LimitBandwidthOpts{
-			Bandwidth:    bandwidth,
-			IncludeCidrs: includeCidrs,
-			PortRange:    portRange,
-		}, nil, nil
+	err = validateRestrictedEndpoints(request, includeCidrs, portRange)
+	if err != nil {
+		return nil, nil, err
 	}
+
+	return &network.LimitBandwidthOpts{
+		Bandwidth:    bandwidth,
+		IncludeCidrs: includeCidrs,
+		PortRange:    portRange,
+	}, nil, nil
+}
+
+func limitBandwidthWinDivert(ctx context.Context, request action_kit_api.PrepareActionRequestBody, bandwidth string, duration time.Duration) (network.WinOpts, action_kit_api.Messages, error) {
+	if duration < time.Second {
+		return nil, nil, errors.New("duration must be greater / equal than 1s")
+	}
+
+	bitsPerSecond, err := network.ParseBandwidth(bandwidth)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filter, messages, err := mapToNetworkFilter(ctx, request.Config, getRestrictedEndpoints(request))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch direction := network.Direction(extutil.ToString(request.Config["direction"])); direction {
+	case "":
+		filter.Direction = network.DirectionOutgoing
+	case network.DirectionOutgoing, network.DirectionIncoming, network.DirectionAll:
+		filter.Direction = direction
+	default:
+		return nil, nil, fmt.Errorf("unsupported direction %q", direction)
+	}
+
+	return &network.LimitBandwidthWinDivertOpts{
+		Filter:        filter,
+		BitsPerSecond: bitsPerSecond,
+		Duration:      duration,
+	}, messages, nil
 }
 
 func validateRestrictedEndpoints(request action_kit_api.PrepareActionRequestBody, includeCidrs []net.IPNet, portRange akn.PortRange) error {
@@ -192,6 +298,19 @@ func sanitizeBandwidthAttribute(bandwidth string) (string, error) {
 }
 
 func limitBandwidthDecode(data json.RawMessage) (network.WinOpts, error) {
+	// only the WinDivert backend stores the bandwidth in bits per second
+	var backend struct {
+		BitsPerSecond uint64
+	}
+	if err := json.Unmarshal(data, &backend); err != nil {
+		return nil, err
+	}
+	if backend.BitsPerSecond > 0 {
+		var opts network.LimitBandwidthWinDivertOpts
+		err := json.Unmarshal(data, &opts)
+		return &opts, err
+	}
+
 	var opts network.LimitBandwidthOpts
 	err := json.Unmarshal(data, &opts)
 	return &opts, err
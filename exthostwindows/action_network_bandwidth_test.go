@@ -6,6 +6,7 @@ package exthostwindows
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net"
 	"testing"
 
@@ -14,6 +15,7 @@ import (
 	akn "github.com/steadybit/action-kit/go/action_kit_commons/network"
 	"github.com/steadybit/extension-host-windows/exthostwindows/network"
 	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils/utilstest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -93,6 +95,42 @@ func TestActionNetworkBandwidth_Prepare(t *testing.T) {
 				},
 			},
 			wantedError: "hostname or IP required",
+		}, {
+			name: "Should return error on multiple port ranges",
+			requestBody: action_kit_api.PrepareActionRequestBody{
+				Config: map[string]any{
+					"action":    "prepare",
+					"duration":  "10000",
+					"bandwidth": "1000mbit",
+					"ip":        []any{"1.1.1.1"},
+					"port":      []any{"80", "443"},
+				},
+				ExecutionId: uuid.New(),
+				Target: &action_kit_api.Target{
+					Attributes: map[string][]string{
+						hostNameAttribute: {"myhostname"},
+					},
+				},
+			},
+			wantedError: "the Windows QoS Policy backend supports a single port range only",
+		}, {
+			name: "Should return error on incoming traffic",
+			requestBody: action_kit_api.PrepareActionRequestBody{
+				Config: map[string]any{
+					"action":    "prepare",
+					"duration":  "10000",
+					"bandwidth": "1000mbit",
+					"ip":        []any{"1.1.1.1"},
+					"direction": "Incoming",
+				},
+				ExecutionId: uuid.New(),
+				Target: &action_kit_api.Target{
+					Attributes: map[string][]string{
+						hostNameAttribute: {"myhostname"},
+					},
+				},
+			},
+			wantedError: "the Windows QoS Policy backend only limits outgoing traffic",
 		}, {
 			name: "Should return error on too low duration",
 			requestBody: action_kit_api.PrepareActionRequestBody{
@@ -292,6 +330,97 @@ func TestActionNetworkBandwidth_Prepare(t *testing.T) {
 	}
 }
 
+func TestActionNetworkBandwidth_PrepareWinDivert(t *testing.T) {
+	osHostname = func() (string, error) {
+		return "myhostname", nil
+	}
+
+	action := NewNetworkLimitBandwidthContainerAction()
+	state := NetworkActionState{}
+	_, err := action.Prepare(t.Context(), &state, action_kit_api.PrepareActionRequestBody{
+		Config: map[string]any{
+			"action":    "prepare",
+			"duration":  "10000",
+			"bandwidth": "2mbit",
+			"backend":   "windivert",
+			"direction": "Incoming",
+			"port":      []any{"80", "8000-8080"},
+		},
+		ExecutionId: uuid.New(),
+		Target: &action_kit_api.Target{
+			Attributes: map[string][]string{
+				hostNameAttribute: {"myhostname"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	opts, err := limitBandwidthDecode(state.NetworkOpts)
+	require.NoError(t, err)
+	require.IsType(t, &network.LimitBandwidthWinDivertOpts{}, opts)
+	winDivertOpts := opts.(*network.LimitBandwidthWinDivertOpts)
+	assert.Equal(t, uint64(2*1024*1024), winDivertOpts.BitsPerSecond)
+	assert.Equal(t, network.DirectionIncoming, winDivertOpts.Direction)
+	assert.Equal(t, akn.NewNetWithPortRanges(akn.NetAny, akn.PortRange{From: 80, To: 80}, akn.PortRange{From: 8000, To: 8080}), winDivertOpts.Include)
+}
+
+func TestActionNetworkBandwidth_StartReportsQosErrors(t *testing.T) {
+	opts := network.LimitBandwidthOpts{Bandwidth: "1MB", IncludeCidrs: []net.IPNet{{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(24, 32)}}}
+	rawOpts, err := json.Marshal(opts)
+	require.NoError(t, err)
+	action := &networkAction{
+		runner:      utilstest.NewRecordingRunner().Respond("New-NetQosPolicy", "", errors.New("Access denied")),
+		optsDecoder: limitBandwidthDecode,
+	}
+	state := &NetworkActionState{ExecutionId: uuid.New(), NetworkOpts: rawOpts}
+	defer func() { _ = network.Revert(t.Context(), utilstest.NewRecordingRunner(), state.ExecutionId, &opts) }()
+
+	result, err := action.Start(t.Context(), state)
+	require.ErrorContains(t, err, "Failed to apply QoS policies.")
+	require.Len(t, *result.Messages, 2)
+	assert.Equal(t, action_kit_api.Error, *(*result.Messages)[1].Level)
+	assert.Contains(t, (*result.Messages)[1].Message, "New-NetQosPolicy STEADYBIT_QOS_1MB_")
+	assert.Contains(t, (*result.Messages)[1].Message, "error: Access denied")
+}
+
+func TestActionNetworkBandwidth_BackendsLimitToSameRate(t *testing.T) {
+	osHostname = func() (string, error) {
+		return "myhostname", nil
+	}
+
+	prepare := func(backend string) network.WinOpts {
+		action := NewNetworkLimitBandwidthContainerAction()
+		state := NetworkActionState{}
+		_, err := action.Prepare(t.Context(), &state, action_kit_api.PrepareActionRequestBody{
+			Config: map[string]any{
+				"action":    "prepare",
+				"duration":  "10000",
+				"bandwidth": "100mbit",
+				"backend":   backend,
+				"ip":        []any{"1.1.1.1"},
+			},
+			ExecutionId: uuid.New(),
+			Target: &action_kit_api.Target{
+				Attributes: map[string][]string{
+					hostNameAttribute: {"myhostname"},
+				},
+			},
+		})
+		require.NoError(t, err)
+		opts, err := limitBandwidthDecode(state.NetworkOpts)
+		require.NoError(t, err)
+		return opts
+	}
+
+	invocations, err := prepare("qos").QoSCommands(network.ModeAdd)
+	require.NoError(t, err)
+	require.Len(t, invocations, 1)
+	winDivertOpts := prepare("windivert").(*network.LimitBandwidthWinDivertOpts)
+
+	assert.Equal(t, uint64(100*1024*1024), winDivertOpts.BitsPerSecond)
+	assert.Equal(t, invocations[0].Params["ThrottleRateActionBitsPerSecond"], winDivertOpts.BitsPerSecond)
+}
+
 func assertContainsCidrs(wantedCidrs []net.IPNet, actualCidrs []net.IPNet) bool {
 	for _, wantedCidr := range wantedCidrs {
 		if !assertContainsCidr(wantedCidr, actualCidrs) {
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/steadybit/extension-host-windows/exthostwindows/network"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
 	"time"
 
 	"github.com/steadybit/action-kit/go/action_kit_api/v2"
@@ -22,6 +23,7 @@ func NewNetworkBlackholeContainerAction() action_kit_sdk.Action[NetworkActionSta
 		optsProvider: blackhole(),
 		optsDecoder:  blackholeDecode,
 		description:  getNetworkBlackholeDescription(),
+		runner:       utils.NewNetworkCommandRunner(),
 	}
 }
 
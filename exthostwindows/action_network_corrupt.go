@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/steadybit/extension-host-windows/exthostwindows/network"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
 
 	"github.com/steadybit/action-kit/go/action_kit_api/v2"
 	"github.com/steadybit/action-kit/go/action_kit_sdk"
@@ -23,6 +24,7 @@ func NewNetworkCorruptPackagesContainerAction() action_kit_sdk.Action[NetworkAct
 		optsProvider: corruptPackages(),
 		optsDecoder:  corruptPackagesDecode,
 		description:  getNetworkCorruptPackagesDescription(),
+		runner:       utils.NewNetworkCommandRunner(),
 	}
 }
 
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/steadybit/extension-host-windows/exthostwindows/network"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
 
 	"github.com/steadybit/action-kit/go/action_kit_api/v2"
 	"github.com/steadybit/action-kit/go/action_kit_sdk"
@@ -23,6 +24,7 @@ func NewNetworkDelayContainerAction() action_kit_sdk.Action[NetworkActionState]
 		optsProvider: delay(),
 		optsDecoder:  delayDecode,
 		description:  getNetworkDelayDescription(),
+		runner:       utils.NewNetworkCommandRunner(),
 	}
 }
 
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/steadybit/extension-host-windows/exthostwindows/network"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
 
 	"github.com/steadybit/action-kit/go/action_kit_api/v2"
 	akn "github.com/steadybit/action-kit/go/action_kit_commons/network"
@@ -23,6 +24,7 @@ func NewNetworkBlockDnsContainerAction() action_kit_sdk.Action[NetworkActionStat
 		optsProvider: blockDns(),
 		optsDecoder:  blackholeDecode,
 		description:  getNetworkBlockDnsDescription(),
+		runner:       utils.NewNetworkCommandRunner(),
 	}
 }
 
@@ -0,0 +1,221 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package exthostwindows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/action-kit/go/action_kit_sdk"
+	"github.com/steadybit/extension-host-windows/exthostwindows/dns"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+	extension_kit "github.com/steadybit/extension-kit"
+	"github.com/steadybit/extension-kit/extbuild"
+	"github.com/steadybit/extension-kit/extutil"
+)
+
+// dnsResponderAddress is the address of the local responder. The Windows DNS client sends the lookups of the attacked
+// host names to it, as configured by NRPT rules. The responder serves udp and tcp, lookups are retried on tcp if the
+// answer on udp was truncated.
+const dnsResponderAddress = "127.0.0.1:53"
+
+type dnsErrorAction struct {
+	responders sync.Map
+	runner     utils.CommandRunner
+}
+
+type DnsErrorActionState struct {
+	ExecutionId uuid.UUID
+	Mode        dns.Mode
+	Hostnames   []string
+	Delay       time.Duration
+}
+
+var (
+	_ action_kit_sdk.Action[DnsErrorActionState]         = (*dnsErrorAction)(nil)
+	_ action_kit_sdk.ActionWithStop[DnsErrorActionState] = (*dnsErrorAction)(nil)
+)
+
+func NewNetworkDnsErrorAction() action_kit_sdk.Action[DnsErrorActionState] {
+	return &dnsErrorAction{runner: utils.NewNetworkCommandRunner()}
+}
+
+func (a *dnsErrorAction) NewEmptyState() DnsErrorActionState {
+	return DnsErrorActionState{}
+}
+
+func (a *dnsErrorAction) Describe() action_kit_api.ActionDescription {
+	return action_kit_api.ActionDescription{
+		Id:          fmt.Sprintf("%s.network_dns_error", BaseActionID),
+		Label:       "DNS Errors",
+		Description: "Answer lookups of the given host names with NXDOMAIN, SERVFAIL or delayed answers. All other lookups are resolved as usual.",
+		Version:     extbuild.GetSemverVersionStringOrUnknown(),
+		Icon:        new(dnsIcon),
+		TargetSelection: new(action_kit_api.TargetSelection{
+			TargetType:         targetID,
+			SelectionTemplates: &targetSelectionTemplates,
+		}),
+		Technology:  new(WindowsHostTechnology),
+		Category:    new("Network"),
+		Kind:        action_kit_api.Attack,
+		TimeControl: action_kit_api.TimeControlExternal,
+		Parameters: []action_kit_api.ActionParameter{
+			durationParamter,
+			{
+				Name:        "hostnames",
+				Label:       "Host Names",
+				Description: new("Host names whose lookups are affected. A leading \"*.\" matches all subdomains, \"*\" matches all host names."),
+				Type:        action_kit_api.ActionParameterTypeStringArray,
+				Required:    new(true),
+				Order:       new(1),
+			},
+			{
+				Name:         "errorType",
+				Label:        "Error Type",
+				Description:  new("How the lookups are answered."),
+				Type:         action_kit_api.ActionParameterTypeString,
+				DefaultValue: new(string(dns.ModeNXDomain)),
+				Required:     new(true),
+				Order:        new(2),
+				Options: new([]action_kit_api.ParameterOption{
+					action_kit_api.ExplicitParameterOption{
+						Label: "NXDOMAIN (name does not exist)",
+						Value: string(dns.ModeNXDomain),
+					},
+					action_kit_api.ExplicitParameterOption{
+						Label: "SERVFAIL (server failure)",
+						Value: string(dns.ModeServFail),
+					},
+					action_kit_api.ExplicitParameterOption{
+						Label: "Delayed answer",
+						Value: string(dns.ModeDelay),
+					},
+				}),
+			},
+			{
+				Name:         "delay",
+				Label:        "Delay",
+				Description:  new("How long answers are delayed, if the error type is delayed answer."),
+				Type:         action_kit_api.ActionParameterTypeDuration,
+				DefaultValue: new("2s"),
+				Order:        new(3),
+			},
+		},
+		Stop: new(action_kit_api.MutatingEndpointReference{}),
+	}
+}
+
+func (a *dnsErrorAction) Prepare(_ context.Context, state *DnsErrorActionState, request action_kit_api.PrepareActionRequestBody) (*action_kit_api.PrepareResult, error) {
+	_, err := CheckTargetHostname(request.Target.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	duration := time.Duration(extutil.ToInt64(request.Config["duration"])) * time.Millisecond
+	if duration < time.Second {
+		return nil, extension_kit.ToError("Duration must be greater / equal than 1s", nil)
+	}
+
+	var hostnames []string
+	for _, hostname := range extutil.ToStringArray(request.Config["hostnames"]) {
+		if hostname = strings.TrimSpace(hostname); hostname != "" {
+			hostnames = append(hostnames, hostname)
+		}
+	}
+
+	config := dns.Config{
+		Mode:     dns.Mode(extutil.ToString(request.Config["errorType"])),
+		Patterns: hostnames,
+		Delay:    time.Duration(extutil.ToInt64(request.Config["delay"])) * time.Millisecond,
+	}
+	if err := config.Validate(); err != nil {
+		return nil, extension_kit.ToError(err.Error(), nil)
+	}
+
+	state.ExecutionId = request.ExecutionId
+	state.Mode = config.Mode
+	state.Hostnames = config.Patterns
+	state.Delay = config.Delay
+	return nil, nil
+}
+
+func (a *dnsErrorAction) Start(ctx context.Context, state *DnsErrorActionState) (*action_kit_api.StartResult, error) {
+	upstream, err := dns.SystemNameServers(ctx, a.runner)
+	if err != nil {
+		return nil, extension_kit.ToError("Failed to find the name servers of the host.", err)
+	}
+	if len(upstream) == 0 {
+		log.Warn().Msg("no name servers found, lookups which are not attacked will fail")
+	}
+
+	// the responder listens on the dns port, so only one attack can run at a time
+	conn, err := net.ListenPacket("udp", dnsResponderAddress)
+	if err != nil {
+		return nil, extension_kit.ToError("Failed to start the DNS responder. Is another DNS attack running?", err)
+	}
+	listener, err := net.Listen("tcp", dnsResponderAddress)
+	if err != nil {
+		_ = conn.Close()
+		return nil, extension_kit.ToError("Failed to start the DNS responder. Is another DNS attack running?", err)
+	}
+
+	responder := dns.NewResponder(dns.Config{
+		Mode:     state.Mode,
+		Patterns: state.Hostnames,
+		Delay:    state.Delay,
+		Upstream: upstream,
+	})
+	serveCtx, cancel := context.WithCancel(context.Background())
+	go func() {
+		if err := responder.Serve(serveCtx, conn); err != nil {
+			log.Error().Err(err).Msg("dns responder failed")
+		}
+	}()
+	go func() {
+		if err := responder.ServeTCP(serveCtx, listener); err != nil {
+			log.Error().Err(err).Msg("dns responder failed on tcp")
+		}
+	}()
+	a.responders.Store(state.ExecutionId, cancel)
+
+	host, _, _ := net.SplitHostPort(dnsResponderAddress)
+	if err := dns.AddNrptRules(ctx, a.runner, state.ExecutionId.String(), state.Hostnames, host); err != nil {
+		cancel()
+		a.responders.Delete(state.ExecutionId)
+		return nil, extension_kit.ToError("Failed to route lookups to the DNS responder.", errors.Join(err, dns.RemoveNrptRules(context.Background(), a.runner, state.ExecutionId.String())))
+	}
+
+	return &action_kit_api.StartResult{
+		Messages: new([]action_kit_api.Message{
+			{
+				Level:   extutil.Ptr(action_kit_api.Info),
+				Message: fmt.Sprintf("Answering lookups of %s with %s", strings.Join(state.Hostnames, ", "), state.Mode),
+			},
+		}),
+	}, nil
+}
+
+func (a *dnsErrorAction) Stop(ctx context.Context, state *DnsErrorActionState) (*action_kit_api.StopResult, error) {
+	cancel, ok := a.responders.LoadAndDelete(state.ExecutionId)
+	if !ok {
+		log.Debug().Msg("Execution run data not found, stop was already called")
+		return nil, nil
+	}
+
+	// remove the rules first, so no lookups are sent to the stopped responder
+	err := dns.RemoveNrptRules(ctx, a.runner, state.ExecutionId.String())
+	cancel.(context.CancelFunc)()
+	if err != nil {
+		return nil, extension_kit.ToError("Failed to remove the DNS rules.", err)
+	}
+	return nil, nil
+}
@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package exthostwindows
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/steadybit/extension-host-windows/exthostwindows/network"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/action-kit/go/action_kit_sdk"
+	"github.com/steadybit/extension-kit/extbuild"
+	"github.com/steadybit/extension-kit/extutil"
+)
+
+func NewNetworkPackageReorderingContainerAction() action_kit_sdk.Action[NetworkActionState] {
+	return &networkAction{
+		optsProvider: reorderPackages(),
+		optsDecoder:  reorderPackagesDecode,
+		description:  getNetworkPackageReorderingDescription(),
+		runner:       utils.NewNetworkCommandRunner(),
+	}
+}
+
+func getNetworkPackageReorderingDescription() action_kit_api.ActionDescription {
+	return action_kit_api.ActionDescription{
+		Id:          fmt.Sprintf("%s.network_package_reordering", BaseActionID),
+		Label:       "Reorder Outgoing Packages",
+		Description: "Hold back packets of the egress network traffic, so they are sent after the following packets.",
+		Version:     extbuild.GetSemverVersionStringOrUnknown(),
+		Icon:        new(delayIcon),
+		TargetSelection: &action_kit_api.TargetSelection{
+			TargetType:         targetID,
+			SelectionTemplates: &targetSelectionTemplates,
+		},
+		Technology:  new(WindowsHostTechnology),
+		Category:    new("Network"),
+		Kind:        action_kit_api.Attack,
+		TimeControl: action_kit_api.TimeControlExternal,
+		Parameters: append(
+			commonNetworkParameters,
+			action_kit_api.ActionParameter{
+				Name:         "percentage",
+				Label:        "Package Reordering",
+				Description:  new("How much of the traffic should be reordered?"),
+				Type:         action_kit_api.ActionParameterTypePercentage,
+				DefaultValue: new("25"),
+				Required:     new(true),
+				Order:        new(1),
+			},
+			correlationParameter,
+			networkInterfaceParameter,
+		),
+	}
+}
+
+func reorderPackages() networkOptsProvider {
+	return func(ctx context.Context, request action_kit_api.PrepareActionRequestBody) (network.WinOpts, action_kit_api.Messages, error) {
+		_, err := CheckTargetHostname(request.Target.Attributes)
+		if err != nil {
+			return nil, nil, err
+		}
+		reorder := extutil.ToUInt(request.Config["percentage"])
+		correlation := extutil.ToUInt(request.Config["correlation"])
+
+		duration := time.Duration(extutil.ToInt64(request.Config["duration"])) * time.Millisecond
+		if duration < time.Second {
+			return nil, nil, errors.New("duration must be greater / equal than 1s")
+		}
+
+		filter, messages, err := mapToNetworkFilter(ctx, request.Config, getRestrictedEndpoints(request))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		filter.Direction = network.DirectionOutgoing
+
+		return &network.ReorderOpts{
+			Filter:      filter,
+			Reorder:     reorder,
+			Correlation: correlation,
+			Duration:    duration,
+		}, messages, nil
+	}
+}
+
+func reorderPackagesDecode(data json.RawMessage) (network.WinOpts, error) {
+	var opts network.ReorderOpts
+	err := json.Unmarshal(data, &opts)
+	return &opts, err
+}
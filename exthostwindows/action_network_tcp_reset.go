@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package exthostwindows
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/steadybit/extension-host-windows/exthostwindows/network"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/action-kit/go/action_kit_sdk"
+	"github.com/steadybit/extension-kit/extbuild"
+	"github.com/steadybit/extension-kit/extutil"
+)
+
+func NewNetworkTcpResetContainerAction() action_kit_sdk.Action[NetworkActionState] {
+	return &networkAction{
+		optsProvider: tcpReset(),
+		optsDecoder:  tcpResetDecode,
+		description:  getNetworkTcpResetDescription(),
+		runner:       utils.NewNetworkCommandRunner(),
+	}
+}
+
+func getNetworkTcpResetDescription() action_kit_api.ActionDescription {
+	return action_kit_api.ActionDescription{
+		Id:          fmt.Sprintf("%s.network_tcp_reset", BaseActionID),
+		Label:       "Reset TCP Connections",
+		Description: "Tear down TCP connections by injecting RST packets, like a middlebox or load balancer dropping connections.",
+		Version:     extbuild.GetSemverVersionStringOrUnknown(),
+		Icon:        new(blackHoleIcon),
+		TargetSelection: &action_kit_api.TargetSelection{
+			TargetType:         targetID,
+			SelectionTemplates: &targetSelectionTemplates,
+		},
+		Technology:  new(WindowsHostTechnology),
+		Category:    new("Network"),
+		Kind:        action_kit_api.Attack,
+		TimeControl: action_kit_api.TimeControlExternal,
+		Parameters: append(
+			// only tcp connections can be reset
+			withoutParameter(commonNetworkParameters, "protocol"),
+			action_kit_api.ActionParameter{
+				Name:         "interval",
+				Label:        "Interval",
+				Description:  new("How often the connections are reset. If 0, the connections are reset once at start."),
+				Type:         action_kit_api.ActionParameterTypeDuration,
+				DefaultValue: new("0s"),
+				Required:     new(true),
+				Order:        new(1),
+			},
+			networkInterfaceParameter,
+		),
+	}
+}
+
+func tcpReset() networkOptsProvider {
+	return func(ctx context.Context, request action_kit_api.PrepareActionRequestBody) (network.WinOpts, action_kit_api.Messages, error) {
+		_, err := CheckTargetHostname(request.Target.Attributes)
+		if err != nil {
+			return nil, nil, err
+		}
+		interval := time.Duration(extutil.ToInt64(request.Config["interval"])) * time.Millisecond
+		if interval < 0 {
+			return nil, nil, errors.New("interval must not be negative")
+		}
+
+		duration := time.Duration(extutil.ToInt64(request.Config["duration"])) * time.Millisecond
+		if duration < time.Second {
+			return nil, nil, errors.New("duration must be greater / equal than 1s")
+		}
+
+		filter, messages, err := mapToNetworkFilter(ctx, request.Config, getRestrictedEndpoints(request))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		filter.Direction = network.DirectionOutgoing
+		filter.Protocols = []network.Protocol{network.ProtocolTCP}
+
+		return &network.TcpResetOpts{
+			Filter:   filter,
+			Interval: interval,
+			Duration: duration,
+		}, messages, nil
+	}
+}
+
+func tcpResetDecode(data json.RawMessage) (network.WinOpts, error) {
+	var opts network.TcpResetOpts
+	err := json.Unmarshal(data, &opts)
+	return &opts, err
+}
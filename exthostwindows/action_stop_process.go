@@ -15,12 +15,14 @@ import (
 	"github.com/steadybit/action-kit/go/action_kit_api/v2"
 	"github.com/steadybit/action-kit/go/action_kit_sdk"
 	stopprocess "github.com/steadybit/extension-host-windows/exthostwindows/process"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
 	"github.com/steadybit/extension-kit/extbuild"
 	"github.com/steadybit/extension-kit/extutil"
 )
 
 type stopProcessAction struct {
 	processStoppers sync.Map
+	runner          utils.CommandRunner
 }
 
 type StopProcessActionState struct {
@@ -39,7 +41,7 @@ var (
 )
 
 func NewStopProcessAction() action_kit_sdk.Action[StopProcessActionState] {
-	return &stopProcessAction{}
+	return &stopProcessAction{runner: utils.NewCommandRunner()}
 }
 
 func (a *stopProcessAction) NewEmptyState() StopProcessActionState {
@@ -141,7 +143,7 @@ func (a *stopProcessAction) Prepare(_ context.Context, state *StopProcessActionS
 }
 
 func (a *stopProcessAction) Start(_ context.Context, state *StopProcessActionState) (*action_kit_api.StartResult, error) {
-	stopper := newProcessStopper(state.ProcessFilter, state.Graceful, state.Delay, state.Duration)
+	stopper := newProcessStopper(a.runner, state.ProcessFilter, state.Graceful, state.Delay, state.Duration)
 
 	a.processStoppers.Store(state.ExecutionId, stopper)
 
@@ -205,7 +207,7 @@ type processStopper struct {
 	err    atomic.Pointer[error]
 }
 
-func newProcessStopper(processFilter string, graceful bool, delay, duration time.Duration) *processStopper {
+func newProcessStopper(runner utils.CommandRunner, processFilter string, graceful bool, delay, duration time.Duration) *processStopper {
 	ctx, cancel := context.WithTimeout(context.Background(), duration)
 	s := &processStopper{
 		cancel: cancel,
@@ -219,7 +221,7 @@ func newProcessStopper(processFilter string, graceful bool, delay, duration time
 				case <-time.After(delay):
 					pids := stopprocess.FindProcessIds(processFilter)
 					log.Debug().Msgf("Found %d processes to stop", len(pids))
-					err := stopprocess.StopProcesses(pids, !graceful)
+					err := stopprocess.StopProcesses(runner, pids, !graceful)
 					if err != nil {
 						log.Error().Err(err).Msg("Failed to stop processes")
 						s.err.Store(&err)
@@ -4,10 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os/exec"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -55,13 +55,18 @@ const steadybitStressCpuExecutableName = "steadybit-stress-cpu"
 type cpuStressAction struct {
 	description  action_kit_api.ActionDescription
 	optsProvider stressOptsProvider
+	runner       utils.CommandRunner
+	processes    sync.Map
 }
 
 type CpuStressOpts struct {
-	Cores    int
+	Cores int
+	// CpuLoad is the total CPU load of the host in percent, including the load of other processes.
 	CpuLoad  int
 	Priority ProcessPriority
 	Duration time.Duration
+	// Affinity pins each worker to its own core.
+	Affinity bool
 }
 
 func (o *CpuStressOpts) Args() []string {
@@ -69,6 +74,9 @@ func (o *CpuStressOpts) Args() []string {
 	args = append(args, "--cores", strconv.Itoa(int(o.Cores)))
 	args = append(args, "--priority", string(o.Priority))
 	args = append(args, "--percentage", strconv.Itoa(o.CpuLoad))
+	if o.Affinity {
+		args = append(args, "--affinity")
+	}
 
 	return args
 }
@@ -90,6 +98,7 @@ func NewStressCpuAction() action_kit_sdk.Action[CPUStressActionState] {
 	return &cpuStressAction{
 		description:  getStressCpuDescription(),
 		optsProvider: stressCpu(),
+		runner:       utils.NewCommandRunner(),
 	}
 }
 
@@ -133,6 +142,7 @@ func stressCpu() stressOptsProvider {
 			CpuLoad:  cpuLoad,
 			Duration: duration,
 			Priority: priority,
+			Affinity: extutil.ToBool(request.Config["affinity"]),
 		}, nil
 	}
 }
@@ -141,7 +151,7 @@ func getStressCpuDescription() action_kit_api.ActionDescription {
 	return action_kit_api.ActionDescription{
 		Id:          fmt.Sprintf("%s.stress-cpu", BaseActionID),
 		Label:       "Stress CPU",
-		Description: "Generates CPU load for one or more cores.",
+		Description: "Holds the total CPU load of the host at the given percentage. All workers run the same duty cycle, adjusted to the total load of the host.",
 		Version:     extbuild.GetSemverVersionStringOrUnknown(),
 		Icon:        new(stressCPUIcon),
 		TargetSelection: new(action_kit_api.TargetSelection{
@@ -173,7 +183,7 @@ func getStressCpuDescription() action_kit_api.ActionDescription {
 			{
 				Name:         "cpuLoad",
 				Label:        "Host CPU Load",
-				Description:  new("How much CPU should be consumed?"),
+				Description:  new("Which total CPU load should the host have? The load of other processes is taken into account."),
 				Type:         action_kit_api.ActionParameterTypePercentage,
 				DefaultValue: new("100"),
 				Required:     new(true),
@@ -184,7 +194,7 @@ func getStressCpuDescription() action_kit_api.ActionDescription {
 			{
 				Name:         "cores",
 				Label:        "Host Cores",
-				Description:  new("How many CPU cores should be targeted during the stress attack?"),
+				Description:  new("How many workers should stress the CPU? Each worker keeps up to one core busy."),
 				Type:         action_kit_api.ActionParameterTypeStressngWorkers,
 				DefaultValue: new("0"),
 				Required:     new(true),
@@ -226,7 +236,23 @@ func getStressCpuDescription() action_kit_api.ActionDescription {
 				Required:     new(true),
 				Order:        new(4),
 			},
+			{
+				Name:         "affinity",
+				Label:        "Pin Workers to Cores",
+				Description:  new("Should each worker run on its own core?"),
+				Type:         action_kit_api.ActionParameterTypeBoolean,
+				DefaultValue: new("false"),
+				Required:     new(true),
+				Advanced:     new(true),
+				Order:        new(5),
+			},
 		},
+		Status: new(action_kit_api.MutatingEndpointReferenceWithCallInterval{
+			CallInterval: new("1s"),
+		}),
+		Widgets: new([]action_kit_api.Widget{
+			metricsWidget("CPU Load", cpuLoadMetric, "load"),
+		}),
 		Stop: new(action_kit_api.MutatingEndpointReference{}),
 	}
 }
@@ -257,23 +283,20 @@ func (a *cpuStressAction) Prepare(ctx context.Context, state *CPUStressActionSta
 }
 
 func (a *cpuStressAction) Start(ctx context.Context, state *CPUStressActionState) (*action_kit_api.StartResult, error) {
-	err := utils.IsExecutableOperational(steadybitStressCpuExecutableName, "--version")
+	err := utils.IsExecutableOperational(a.runner, steadybitStressCpuExecutableName, "--version")
 
 	if err != nil {
 		return nil, err
 	}
 
-	command := exec.CommandContext(context.Background(), steadybitStressCpuExecutableName, state.StressOpts.Args()...)
+	reports := &stressReports[cpuStressReport]{}
+	process, err := startStressProcess(a.runner, utils.Command{Name: steadybitStressCpuExecutableName, Args: state.StressOpts.Args(), Stdout: reports})
 
-	go func() {
-		output, err := command.CombinedOutput()
-
-		if err != nil {
-			log.Error().Msg("Failed to start cpu stress attack.")
-		}
+	if err != nil {
+		return nil, err
+	}
 
-		log.Info().Msgf("%s", output)
-	}()
+	a.processes.Store(state.ExecutionId, &cpuStressExecution{process: process, reports: reports})
 
 	return &action_kit_api.StartResult{
 		Messages: new([]action_kit_api.Message{
@@ -286,43 +309,53 @@ func (a *cpuStressAction) Start(ctx context.Context, state *CPUStressActionState
 }
 
 func (a *cpuStressAction) Status(_ context.Context, state *CPUStressActionState) (*action_kit_api.StatusResult, error) {
-	isRunning, err := utils.IsProcessRunning(steadybitStressCpuExecutableName)
+	value, ok := a.processes.Load(state.ExecutionId)
 
-	if err != nil {
-		return nil, err
+	if !ok {
+		return &action_kit_api.StatusResult{Completed: true}, nil
 	}
 
-	if isRunning {
-		return &action_kit_api.StatusResult{Completed: false}, nil
-	}
+	stress := value.(*cpuStressExecution)
+	result := stress.process.status("Stress host stopped")
+	result.Metrics = cpuLoadMetrics(state.ExecutionId, stress.reports.take())
+	return result, nil
+}
 
-	return &action_kit_api.StatusResult{
-		Completed: true,
-		Messages: &[]action_kit_api.Message{
-			{
-				Level:   extutil.Ptr(action_kit_api.Info),
-				Message: "Stress host stopped",
-			},
-		},
-	}, nil
+func (a *cpuStressAction) Stop(ctx context.Context, state *CPUStressActionState) (*action_kit_api.StopResult, error) {
+	value, ok := a.processes.LoadAndDelete(state.ExecutionId)
+
+	if !ok {
+		log.Debug().Msg("Execution run data not found, stop was already called")
+		return nil, nil
+	}
 
+	return value.(*cpuStressExecution).process.stop(ctx, "Canceled stress host")
 }
 
-func (a *cpuStressAction) Stop(_ context.Context, state *CPUStressActionState) (*action_kit_api.StopResult, error) {
-	messages := make([]action_kit_api.Message, 0)
+// cpuStressExecution is a running steadybit-stress-cpu together with its reports.
+type cpuStressExecution struct {
+	process *stressProcess
+	reports *stressReports[cpuStressReport]
+}
 
-	err := utils.StopProcess(steadybitStressCpuExecutableName)
+// cpuStressReport is written to stdout by steadybit-stress-cpu once per second. Loads are in percent of the host capacity.
+type cpuStressReport struct {
+	Time       time.Time `json:"time"`
+	HostLoad   float64   `json:"hostLoad"`
+	StressLoad float64   `json:"stressLoad"`
+}
 
-	if err != nil {
-		return nil, err
+// cpuLoadMetrics returns the achieved total load of the host and the load caused by the attack.
+func cpuLoadMetrics(executionId uuid.UUID, reports []cpuStressReport) *action_kit_api.Metrics {
+	if len(reports) == 0 {
+		return nil
 	}
-
-	messages = append(messages, action_kit_api.Message{
-		Level:   extutil.Ptr(action_kit_api.Info),
-		Message: "Canceled stress host",
-	})
-
-	return &action_kit_api.StopResult{
-		Messages: &messages,
-	}, nil
+	metrics := make(action_kit_api.Metrics, 0, 2*len(reports))
+	for _, report := range reports {
+		metrics = append(metrics,
+			newMetric(cpuLoadMetric, executionId, map[string]string{"load": "host"}, report.Time, report.HostLoad),
+			newMetric(cpuLoadMetric, executionId, map[string]string{"load": "stress"}, report.Time, report.StressLoad),
+		)
+	}
+	return &metrics
 }
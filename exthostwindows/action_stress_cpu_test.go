@@ -9,7 +9,9 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils/utilstest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func getExecutionIdByTestId() func(testId uint) uuid.UUID {
@@ -199,3 +201,42 @@ func TestActionStressCpu_Prepare(t *testing.T) {
 		})
 	}
 }
+
+func TestActionStressCpu_StatusReportsLoadMetrics(t *testing.T) {
+	reports := `{"time":"2026-01-02T10:00:00Z","hostLoad":78.5,"stressLoad":60.25,"duty":70}` + "\n" +
+		`{"time":"2026-01-02T10:00:01Z","hostLoad":80,"stressLoad":61,"duty":71}` + "\n"
+	runner := utilstest.NewRecordingRunner().Respond("--percentage 80", reports, nil)
+	action := &cpuStressAction{runner: runner}
+	state := &CPUStressActionState{
+		ExecutionId: uuid.New(),
+		StressOpts:  CpuStressOpts{Cores: 2, CpuLoad: 80, Priority: Normal, Duration: 30 * time.Second, Affinity: true},
+	}
+
+	_, err := action.Start(t.Context(), state)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"steadybit-stress-cpu --version", "steadybit-stress-cpu --duration 30 --cores 2 --priority Normal --percentage 80 --affinity"}, runner.Commands())
+
+	// the reports may be taken by several calls until the process is marked as exited
+	var metrics []action_kit_api.Metric
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		result, err := action.Status(t.Context(), state)
+		require.NoError(c, err)
+		if result.Metrics != nil {
+			metrics = append(metrics, *result.Metrics...)
+		}
+		assert.True(c, result.Completed)
+	}, time.Second, 10*time.Millisecond)
+
+	require.Len(t, metrics, 4)
+	assert.Equal(t, map[string]string{"load": "host", "execution_id": state.ExecutionId.String()}, metrics[0].Metric)
+	assert.Equal(t, 78.5, metrics[0].Value)
+	assert.Equal(t, time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC), metrics[0].Timestamp)
+	assert.Equal(t, "stress", metrics[1].Metric["load"])
+	assert.Equal(t, 60.25, metrics[1].Value)
+	assert.Equal(t, 80.0, metrics[2].Value)
+
+	// the metrics are only reported once
+	result, err := action.Status(t.Context(), state)
+	require.NoError(t, err)
+	assert.Nil(t, result.Metrics)
+}
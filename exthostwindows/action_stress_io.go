@@ -1,15 +1,15 @@
 package exthostwindows
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 	"unicode"
 
@@ -17,6 +17,7 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/steadybit/action-kit/go/action_kit_api/v2"
 	"github.com/steadybit/action-kit/go/action_kit_sdk"
+	"github.com/steadybit/extension-host-windows/exthostwindows/diskspd"
 	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
 	"github.com/steadybit/extension-kit/extbuild"
 	"github.com/steadybit/extension-kit/extutil"
@@ -49,9 +50,81 @@ func (sl IOStressLayer) IsValid() bool {
 	}
 }
 
+// IOWorkload is a preset of the IO pattern, or Custom to set it with the individual parameters.
+type IOWorkload string
+
+const (
+	CustomWorkload    IOWorkload = "Custom"
+	OLTPWorkload      IOWorkload = "OLTP database"
+	LogAppendWorkload IOWorkload = "Log append"
+)
+
+func (w IOWorkload) IsValid() bool {
+	switch w {
+	case CustomWorkload, OLTPWorkload, LogAppendWorkload:
+		return true
+	default:
+		return false
+	}
+}
+
+type IOAccessPattern string
+
+const (
+	SequentialAccess IOAccessPattern = "Sequential"
+	RandomAccess     IOAccessPattern = "Random"
+)
+
+func (p IOAccessPattern) IsValid() bool {
+	switch p {
+	case SequentialAccess, RandomAccess:
+		return true
+	default:
+		return false
+	}
+}
+
+// ioPattern is the IO issued by each diskspd thread.
+type ioPattern struct {
+	// WriteRatio is the percentage of writes, the rest are reads.
+	WriteRatio     uint
+	BlockSizeKiB   uint
+	OutstandingIOs uint
+	AccessPattern  IOAccessPattern
+}
+
+// The defaults of diskspd, which were used before the pattern could be configured.
+var defaultIOPattern = ioPattern{WriteRatio: 0, BlockSizeKiB: 64, OutstandingIOs: 2, AccessPattern: SequentialAccess}
+
+var ioWorkloadPresets = map[IOWorkload]ioPattern{
+	// small random pages, mostly read
+	OLTPWorkload: {WriteRatio: 30, BlockSizeKiB: 8, OutstandingIOs: 8, AccessPattern: RandomAccess},
+	// a transaction log waiting for each write to be flushed
+	LogAppendWorkload: {WriteRatio: 100, BlockSizeKiB: 64, OutstandingIOs: 1, AccessPattern: SequentialAccess},
+}
+
+func (p ioPattern) validate() error {
+	if p.WriteRatio > 100 {
+		return fmt.Errorf("write ratio must be between 0 and 100%%, got %d%%", p.WriteRatio)
+	}
+	if p.BlockSizeKiB < 4 || p.BlockSizeKiB > 1024 || p.BlockSizeKiB&(p.BlockSizeKiB-1) != 0 {
+		return fmt.Errorf("block size must be a power of two between 4 and 1024 KiB, got %d KiB", p.BlockSizeKiB)
+	}
+	if p.OutstandingIOs < 1 || p.OutstandingIOs > 256 {
+		return fmt.Errorf("outstanding IOs per thread must be between 1 and 256, got %d", p.OutstandingIOs)
+	}
+	if !p.AccessPattern.IsValid() {
+		return fmt.Errorf("access pattern must be %s or %s, got %s", SequentialAccess, RandomAccess, p.AccessPattern)
+	}
+	return nil
+}
+
 type ioStressAction struct {
 	description  action_kit_api.ActionDescription
 	optsProvider ioStressOptsProvider
+	runner       utils.CommandRunner
+	io           ioSampler
+	processes    sync.Map
 }
 
 type IoStressOpts struct {
@@ -60,11 +133,33 @@ type IoStressOpts struct {
 	ThreadCount        uint
 	Duration           time.Duration
 	DisableSwHwCaching bool
+	Pattern            ioPattern
+	// TestFile is the file created by diskspd in the directory of the File System layer, empty if an existing file is
+	// stressed. It is deleted when the attack is stopped.
+	TestFile    string
+	FileSizeMiB uint
 }
 
 func (o *IoStressOpts) Args() []string {
 	args := []string{fmt.Sprintf("-d%d", int(o.Duration.Seconds()))}
 	args = append(args, fmt.Sprintf("-F%d", o.ThreadCount))
+	if o.Pattern.BlockSizeKiB > 0 {
+		args = append(args, fmt.Sprintf("-b%dK", o.Pattern.BlockSizeKiB))
+	}
+	if o.Pattern.OutstandingIOs > 0 {
+		args = append(args, fmt.Sprintf("-o%d", o.Pattern.OutstandingIOs))
+	}
+	if o.Pattern.WriteRatio > 0 {
+		args = append(args, fmt.Sprintf("-w%d", o.Pattern.WriteRatio))
+	}
+	if o.Pattern.AccessPattern == RandomAccess {
+		args = append(args, "-r")
+	}
+	if o.TestFile != "" {
+		args = append(args, fmt.Sprintf("-c%dM", o.FileSizeMiB))
+	}
+	// measure the latency and skip the warm up, so the results cover the whole duration
+	args = append(args, "-L", "-W0")
 	if o.DisableSwHwCaching {
 		args = append(args, "-Sh")
 	}
@@ -73,6 +168,8 @@ func (o *IoStressOpts) Args() []string {
 		args = append(args, fmt.Sprintf("#%s", o.StressLayerInput))
 	} else if o.StressLayer == NamedPartition {
 		args = append(args, fmt.Sprintf("%s:", o.StressLayerInput))
+	} else if o.TestFile != "" {
+		args = append(args, o.TestFile)
 	} else {
 		args = append(args, o.StressLayerInput)
 	}
@@ -94,9 +191,12 @@ var (
 type ioStressOptsProvider func(request action_kit_api.PrepareActionRequestBody) (*IoStressOpts, error)
 
 func NewStressIoAction() action_kit_sdk.Action[IoStressActionState] {
+	runner := utils.NewCommandRunner()
 	return &ioStressAction{
 		description:  getStressIoDescription(),
-		optsProvider: stressIo(),
+		optsProvider: stressIo(runner),
+		runner:       runner,
+		io:           diskCounterSampler{},
 	}
 }
 
@@ -104,7 +204,7 @@ func (a *ioStressAction) NewEmptyState() IoStressActionState {
 	return IoStressActionState{}
 }
 
-func stressIo() ioStressOptsProvider {
+func stressIo(runner utils.CommandRunner) ioStressOptsProvider {
 	return func(request action_kit_api.PrepareActionRequestBody) (*IoStressOpts, error) {
 		duration := time.Duration(extutil.ToInt64(request.Config["duration"])) * time.Millisecond
 
@@ -120,10 +220,23 @@ func stressIo() ioStressOptsProvider {
 
 		stressLayerInput := extutil.ToString(request.Config["stressLayerInput"])
 
+		var testFile string
+		var fileSize uint
+
 		if stressLayer == IOStressLayers.FileSystem {
-			if _, err := os.Stat(stressLayerInput); err != nil {
+			info, err := os.Stat(stressLayerInput)
+			if err != nil {
 				return nil, err
 			}
+
+			// a test file is created in directories, existing files are stressed as they are
+			if info.IsDir() {
+				fileSize = extutil.ToUInt(request.Config["fileSize"])
+				if fileSize == 0 {
+					return nil, errors.New("test file size must be greater than 0")
+				}
+				testFile = ioStressFilePath(stressLayerInput, request.ExecutionId)
+			}
 		}
 
 		if stressLayer == IOStressLayers.NamedPartition {
@@ -146,7 +259,7 @@ func stressIo() ioStressOptsProvider {
 				return nil, err
 			}
 
-			isDeviceAvailable, err := isPhysicalDeviceAvailable(deviceId)
+			isDeviceAvailable, err := isPhysicalDeviceAvailable(runner, deviceId)
 
 			if err != nil {
 				return nil, err
@@ -165,16 +278,71 @@ func stressIo() ioStressOptsProvider {
 
 		disableSwHwCaching := extutil.ToBool(request.Config["disableSwHwCaching"])
 
+		pattern, err := ioPatternFromConfig(request.Config)
+
+		if err != nil {
+			return nil, err
+		}
+
+		destructive := extutil.ToBool(request.Config["destructive"])
+
+		// only the test file created for the attack may be written without the destructive option
+		if pattern.WriteRatio > 0 && !destructive {
+			if stressLayer != IOStressLayers.FileSystem {
+				return nil, fmt.Errorf("writes to a %s destroy the data stored on it, they require the destructive option", strings.ToLower(string(stressLayer)))
+			}
+			if testFile == "" {
+				return nil, errors.New("writes to an existing file overwrite its content, they require the destructive option")
+			}
+		}
+
 		return &IoStressOpts{
 			Duration:           duration,
 			StressLayer:        stressLayer,
 			StressLayerInput:   stressLayerInput,
 			ThreadCount:        threadCount,
 			DisableSwHwCaching: disableSwHwCaching,
+			Pattern:            *pattern,
+			TestFile:           testFile,
+			FileSizeMiB:        fileSize,
 		}, nil
 	}
 }
 
+// ioPatternFromConfig returns the pattern of the preset, or the pattern set by the parameters for the Custom workload.
+// Parameters missing in experiments created before they were added fall back to the defaults of diskspd.
+func ioPatternFromConfig(config map[string]any) (*ioPattern, error) {
+	workload := CustomWorkload
+	if value := extutil.ToString(config["workload"]); value != "" {
+		workload = IOWorkload(value)
+	}
+	if !workload.IsValid() {
+		return nil, fmt.Errorf("workload must be one of the following: %s, %s, %s, current: %s", CustomWorkload, OLTPWorkload, LogAppendWorkload, workload)
+	}
+
+	pattern, ok := ioWorkloadPresets[workload]
+	if !ok {
+		pattern = defaultIOPattern
+		if value, ok := config["writeRatio"]; ok {
+			pattern.WriteRatio = extutil.ToUInt(value)
+		}
+		if value, ok := config["blockSize"]; ok {
+			pattern.BlockSizeKiB = extutil.ToUInt(value)
+		}
+		if value, ok := config["outstandingIOs"]; ok {
+			pattern.OutstandingIOs = extutil.ToUInt(value)
+		}
+		if value := extutil.ToString(config["accessPattern"]); value != "" {
+			pattern.AccessPattern = IOAccessPattern(value)
+		}
+	}
+
+	if err := pattern.validate(); err != nil {
+		return nil, err
+	}
+	return &pattern, nil
+}
+
 func getStressIoDescription() action_kit_api.ActionDescription {
 	return action_kit_api.ActionDescription{
 		Id:          fmt.Sprintf("%s.stress-io", BaseActionID),
@@ -201,7 +369,7 @@ func getStressIoDescription() action_kit_api.ActionDescription {
 				Order:        new(1),
 				Options: &[]action_kit_api.ParameterOption{
 					action_kit_api.ExplicitParameterOption{
-						Label: "File System - requires file path in the next field",
+						Label: "File System - requires a directory or an existing file in the next field",
 						Value: string(IOStressLayers.FileSystem),
 					},
 					action_kit_api.ExplicitParameterOption{
@@ -217,7 +385,7 @@ func getStressIoDescription() action_kit_api.ActionDescription {
 			{
 				Name:        "stressLayerInput",
 				Label:       "Stress Layer Input",
-				Description: new("Based on the previous answer add the value here."),
+				Description: new("Based on the previous answer add the value here. A test file is created in directories and deleted when the attack ends."),
 				Type:        action_kit_api.ActionParameterTypeString,
 				Required:    new(true),
 				Order:       new(2),
@@ -238,6 +406,93 @@ func getStressIoDescription() action_kit_api.ActionDescription {
 				DefaultValue: new("30s"),
 				Required:     new(true),
 			},
+			{
+				Name:         "workload",
+				Label:        "Workload",
+				Description:  new("The IO pattern of the attack. Choose Custom to set the pattern with the advanced parameters, the presets ignore them."),
+				Type:         action_kit_api.ActionParameterTypeString,
+				DefaultValue: new(string(CustomWorkload)),
+				Required:     new(true),
+				Options: new([]action_kit_api.ParameterOption{
+					action_kit_api.ExplicitParameterOption{
+						Label: "Custom",
+						Value: string(CustomWorkload),
+					},
+					action_kit_api.ExplicitParameterOption{
+						Label: "OLTP database - random 8 KiB IO, 70% reads",
+						Value: string(OLTPWorkload),
+					},
+					action_kit_api.ExplicitParameterOption{
+						Label: "Log append - sequential 64 KiB writes, one at a time",
+						Value: string(LogAppendWorkload),
+					},
+				}),
+			},
+			{
+				Name:         "writeRatio",
+				Label:        "Write Ratio",
+				Description:  new("Percentage of writes, the rest are reads. Only used by the Custom workload."),
+				Type:         action_kit_api.ActionParameterTypePercentage,
+				DefaultValue: new("0"),
+				MinValue:     new(0),
+				MaxValue:     new(100),
+				Advanced:     new(true),
+			},
+			{
+				Name:         "blockSize",
+				Label:        "Block Size (KiB)",
+				Description:  new("Size of each IO, a power of two between 4 and 1024 KiB. Only used by the Custom workload."),
+				Type:         action_kit_api.ActionParameterTypeInteger,
+				DefaultValue: new("64"),
+				MinValue:     new(4),
+				MaxValue:     new(1024),
+				Advanced:     new(true),
+			},
+			{
+				Name:         "outstandingIOs",
+				Label:        "Outstanding IOs",
+				Description:  new("Number of IOs each thread keeps in flight, i.e. the queue depth per thread. Only used by the Custom workload."),
+				Type:         action_kit_api.ActionParameterTypeInteger,
+				DefaultValue: new("2"),
+				MinValue:     new(1),
+				MaxValue:     new(256),
+				Advanced:     new(true),
+			},
+			{
+				Name:         "accessPattern",
+				Label:        "Access Pattern",
+				Description:  new("Whether the IOs are at random or at sequential offsets. Only used by the Custom workload."),
+				Type:         action_kit_api.ActionParameterTypeString,
+				DefaultValue: new(string(SequentialAccess)),
+				Advanced:     new(true),
+				Options: new([]action_kit_api.ParameterOption{
+					action_kit_api.ExplicitParameterOption{
+						Label: "Sequential",
+						Value: string(SequentialAccess),
+					},
+					action_kit_api.ExplicitParameterOption{
+						Label: "Random",
+						Value: string(RandomAccess),
+					},
+				}),
+			},
+			{
+				Name:         "fileSize",
+				Label:        "Test File Size (MiB)",
+				Description:  new("Size of the test file created in the directory of the File System layer. Existing files keep their size."),
+				Type:         action_kit_api.ActionParameterTypeInteger,
+				DefaultValue: new("1024"),
+				MinValue:     new(1),
+				Advanced:     new(true),
+			},
+			{
+				Name:         "destructive",
+				Label:        "Allow Destructive Writes",
+				Description:  new("Allows writes to an existing file, a named partition or a physical disk. They overwrite the data stored on it, writes to a partition or disk bypass the file system. The test file created in a directory is written without this option."),
+				Type:         action_kit_api.ActionParameterTypeBoolean,
+				DefaultValue: new("false"),
+				Advanced:     new(true),
+			},
 			{
 				Name:         "disableSwHwCaching",
 				Label:        "Disable Software & Hardware Caching",
@@ -248,6 +503,10 @@ func getStressIoDescription() action_kit_api.ActionDescription {
 				Advanced:     new(true),
 			},
 		},
+		Widgets: new([]action_kit_api.Widget{
+			metricsWidget("IO Throughput", ioThroughputMetric, "execution_id"),
+			metricsWidget("IO Latency", ioLatencyMetric, "execution_id"),
+		}),
 		Stop: new(action_kit_api.MutatingEndpointReference{}),
 	}
 }
@@ -274,25 +533,37 @@ func (a *ioStressAction) Prepare(ctx context.Context, state *IoStressActionState
 func (a *ioStressAction) Start(ctx context.Context, state *IoStressActionState) (*action_kit_api.StartResult, error) {
 	executable := resolveExecutable("diskspd", "STEADYBIT_DISKSPD")
 
-	err := utils.IsExecutableOperational(executable, "-?")
+	err := utils.IsExecutableOperational(a.runner, executable, "-?")
 
 	if err != nil {
 		return nil, err
 	}
 
-	command := exec.CommandContext(context.Background(), executable, state.StressOpts.Args()...)
+	testFile := state.StressOpts.TestFile
 
-	log.Info().Msgf("Running command: %s, %s.", command.Path, command.Args)
+	if testFile != "" {
+		if err := ioStressFiles.add(testFile); err != nil {
+			return nil, err
+		}
+	}
 
-	go func() {
-		output, err := command.CombinedOutput()
+	process, err := startStressProcess(a.runner, utils.Command{Name: executable, Args: state.StressOpts.Args()})
 
-		if err != nil {
-			log.Error().Msgf("Failed to start io stress attack: %s.", err)
+	if err != nil {
+		if testFile != "" {
+			err = errors.Join(err, ioStressFiles.remove(testFile))
 		}
+		return nil, err
+	}
 
-		log.Info().Msgf("%s", output)
-	}()
+	stress := &ioStressExecution{process: process}
+	if a.io != nil {
+		// the results of diskspd are only available once it finished, the counters show the IO while it runs
+		if stress.counters, err = a.io.open(state.StressOpts); err != nil {
+			log.Warn().Err(err).Msg("failed to open IO performance counters, IO is only reported once diskspd finished")
+		}
+	}
+	a.processes.Store(state.ExecutionId, stress)
 
 	return &action_kit_api.StartResult{
 		Messages: new([]action_kit_api.Message{
@@ -305,65 +576,139 @@ func (a *ioStressAction) Start(ctx context.Context, state *IoStressActionState)
 }
 
 func (a *ioStressAction) Status(_ context.Context, state *IoStressActionState) (*action_kit_api.StatusResult, error) {
-	isRunning, err := utils.IsProcessRunning("diskspd")
+	value, ok := a.processes.Load(state.ExecutionId)
 
-	if err != nil {
-		return nil, err
+	if !ok {
+		return &action_kit_api.StatusResult{Completed: true}, nil
 	}
 
-	if isRunning {
-		return &action_kit_api.StatusResult{Completed: false}, nil
+	stress := value.(*ioStressExecution)
+	result := stress.process.status("Stress host stopped")
+	result.Metrics = stress.sampledMetrics(state.ExecutionId)
+	if result.Metrics == nil {
+		result.Metrics = stress.resultMetrics(state.ExecutionId)
 	}
-
-	return &action_kit_api.StatusResult{
-		Completed: true,
-		Messages: &[]action_kit_api.Message{
-			{
-				Level:   extutil.Ptr(action_kit_api.Info),
-				Message: "Stress host stopped",
-			},
-		},
-	}, nil
-
+	return result, nil
 }
 
-func (a *ioStressAction) Stop(_ context.Context, state *IoStressActionState) (*action_kit_api.StopResult, error) {
-	messages := make([]action_kit_api.Message, 0)
+func (a *ioStressAction) Stop(ctx context.Context, state *IoStressActionState) (*action_kit_api.StopResult, error) {
+	value, ok := a.processes.LoadAndDelete(state.ExecutionId)
 
-	err := utils.StopProcess("diskspd")
+	if !ok {
+		log.Debug().Msg("Execution run data not found, stop was already called")
+		return nil, nil
+	}
 
+	stress := value.(*ioStressExecution)
+	stress.closeCounters()
+	// diskspd prints its results only when it finishes on its own, which is usually just after the stop
+	stress.process.awaitExit(ctx, diskspdGracePeriod)
+	result, err := stress.process.stop(ctx, "Canceled stress host")
 	if err != nil {
 		return nil, err
 	}
+	if testFile := state.StressOpts.TestFile; testFile != "" {
+		if err := ioStressFiles.remove(testFile); err != nil {
+			return nil, err
+		}
+		*result.Messages = append(*result.Messages, action_kit_api.Message{
+			Level:   extutil.Ptr(action_kit_api.Info),
+			Message: fmt.Sprintf("Deleted test file %s.", testFile),
+		})
+	}
+	result.Metrics = stress.resultMetrics(state.ExecutionId)
+	result.Artifacts = stress.resultArtifacts()
+	return result, nil
+}
 
-	messages = append(messages, action_kit_api.Message{
-		Level:   extutil.Ptr(action_kit_api.Info),
-		Message: "Canceled stress host",
-	})
+// diskspdGracePeriod is the time diskspd gets to finish on its own when the attack is stopped.
+const diskspdGracePeriod = 5 * time.Second
 
-	return &action_kit_api.StopResult{
-		Messages: &messages,
-	}, nil
+// ioStressExecution is a running diskspd.
+type ioStressExecution struct {
+	process *stressProcess
+	// counters sample the IO while diskspd runs, nil if they couldn't be opened.
+	counters ioCounters
+	lock     sync.Mutex
+	reported atomic.Bool
+
+	parseResult sync.Once
+	result      *diskspd.Result
+}
+
+// results returns the results diskspd printed, or nil if it didn't exit yet or printed none.
+func (e *ioStressExecution) results() *diskspd.Result {
+	if !e.process.exited() {
+		return nil
+	}
+	e.parseResult.Do(func() {
+		result, err := diskspd.Parse(e.process.output.String())
+		if err != nil {
+			log.Debug().Err(err).Msg("no results of diskspd")
+			return
+		}
+		e.result = result
+	})
+	return e.result
 }
 
-func isPhysicalDeviceAvailable(deviceId uint64) (bool, error) {
-	cmd := exec.Command("powershell", "-Command", "Get-PhysicalDisk | ForEach-Object { $_.DeviceId }")
-	output, err := cmd.Output()
+// sampledMetrics returns the IO sampled from the performance counters while diskspd runs.
+func (e *ioStressExecution) sampledMetrics(executionId uuid.UUID) *action_kit_api.Metrics {
+	if e.process.exited() {
+		e.closeCounters()
+		return nil
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.counters == nil {
+		return nil
+	}
+	io, err := e.counters.Sample()
 	if err != nil {
-		return false, err
+		log.Debug().Err(err).Msg("failed to sample IO performance counters")
+		return nil
 	}
+	return new(diskIOMetrics(io, executionId, time.Now()))
+}
 
-	reader := strings.NewReader(string(output))
-	scanner := bufio.NewScanner(reader)
+func (e *ioStressExecution) closeCounters() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.counters == nil {
+		return
+	}
+	if err := e.counters.Close(); err != nil {
+		log.Warn().Err(err).Msg("failed to close IO performance counters")
+	}
+	e.counters = nil
+}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		id, err := strconv.ParseUint(line, 10, 0)
-		if err != nil {
-			return false, err
-		}
+// resultMetrics returns the results of diskspd as metrics once it exited. The results are only returned once.
+func (e *ioStressExecution) resultMetrics(executionId uuid.UUID) *action_kit_api.Metrics {
+	result := e.results()
+	if result == nil || e.reported.Swap(true) {
+		return nil
+	}
+	return new(diskspdMetrics(result, executionId, e.process.exitedAt))
+}
+
+// resultArtifacts returns the summary of the results of diskspd, if it printed any.
+func (e *ioStressExecution) resultArtifacts() *action_kit_api.Artifacts {
+	result := e.results()
+	if result == nil {
+		return nil
+	}
+	return &action_kit_api.Artifacts{diskspdArtifact(result)}
+}
+
+func isPhysicalDeviceAvailable(runner utils.CommandRunner, deviceId uint64) (bool, error) {
+	disks, err := utils.GetPhysicalDisks(runner)
+	if err != nil {
+		return false, err
+	}
 
-		if deviceId == id {
+	for _, disk := range disks {
+		if disk.DeviceId == strconv.FormatUint(deviceId, 10) {
 			return true, nil
 		}
 	}
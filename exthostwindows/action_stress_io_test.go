@@ -3,12 +3,16 @@ package exthostwindows
 import (
 	"context"
 	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/steadybit/action-kit/go/action_kit_api/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestActionStressIO_Prepare(t *testing.T) {
@@ -51,6 +55,177 @@ func TestActionStressIO_Prepare(t *testing.T) {
 					ThreadCount:        1,
 					DisableSwHwCaching: true,
 					Duration:           time.Second,
+					Pattern:            defaultIOPattern,
+				},
+			},
+		},
+		{
+			name: "Should use the preset of the workload",
+			requestBody: action_kit_api.PrepareActionRequestBody{
+				Config: map[string]any{
+					"action":             "prepare",
+					"duration":           "1000",
+					"stressLayer":        "Named Partition",
+					"stressLayerInput":   "C",
+					"threadCount":        "1",
+					"disableSwHwCaching": "true",
+					"workload":           "OLTP database",
+					"blockSize":          "256",
+					"destructive":        true,
+				},
+				ExecutionId: getExecutionId(1),
+				Target: new(action_kit_api.Target{
+					Attributes: map[string][]string{
+						"host.hostname": {hostname},
+					},
+				}),
+			},
+
+			wantedState: &IoStressActionState{
+				ExecutionId: getExecutionId(1),
+				StressOpts: IoStressOpts{
+					StressLayer:        IOStressLayers.NamedPartition,
+					StressLayerInput:   "C",
+					ThreadCount:        1,
+					DisableSwHwCaching: true,
+					Duration:           time.Second,
+					Pattern:            ioPattern{WriteRatio: 30, BlockSizeKiB: 8, OutstandingIOs: 8, AccessPattern: RandomAccess},
+				},
+			},
+		},
+		{
+			name: "Should use the custom pattern",
+			requestBody: action_kit_api.PrepareActionRequestBody{
+				Config: map[string]any{
+					"action":             "prepare",
+					"duration":           "1000",
+					"stressLayer":        "Named Partition",
+					"stressLayerInput":   "C",
+					"threadCount":        "1",
+					"disableSwHwCaching": "true",
+					"workload":           "Custom",
+					"writeRatio":         50,
+					"blockSize":          "4",
+					"outstandingIOs":     32,
+					"accessPattern":      "Random",
+					"destructive":        true,
+				},
+				ExecutionId: getExecutionId(2),
+				Target: new(action_kit_api.Target{
+					Attributes: map[string][]string{
+						"host.hostname": {hostname},
+					},
+				}),
+			},
+
+			wantedState: &IoStressActionState{
+				ExecutionId: getExecutionId(2),
+				StressOpts: IoStressOpts{
+					StressLayer:        IOStressLayers.NamedPartition,
+					StressLayerInput:   "C",
+					ThreadCount:        1,
+					DisableSwHwCaching: true,
+					Duration:           time.Second,
+					Pattern:            ioPattern{WriteRatio: 50, BlockSizeKiB: 4, OutstandingIOs: 32, AccessPattern: RandomAccess},
+				},
+			},
+		},
+		{
+			name: "Should return error invalid block size",
+			requestBody: action_kit_api.PrepareActionRequestBody{
+				Config: map[string]any{
+					"action":             "prepare",
+					"duration":           "1000",
+					"stressLayer":        "Named Partition",
+					"stressLayerInput":   "C",
+					"threadCount":        "1",
+					"disableSwHwCaching": "true",
+					"blockSize":          "48",
+				},
+				ExecutionId: uuid.New(),
+				Target: new(action_kit_api.Target{
+					Attributes: map[string][]string{
+						"host.hostname": {hostname},
+					},
+				}),
+			},
+
+			wantedError: "block size must be a power of two between 4 and 1024 KiB, got 48 KiB",
+		},
+		{
+			name: "Should return error invalid workload",
+			requestBody: action_kit_api.PrepareActionRequestBody{
+				Config: map[string]any{
+					"action":             "prepare",
+					"duration":           "1000",
+					"stressLayer":        "Named Partition",
+					"stressLayerInput":   "C",
+					"threadCount":        "1",
+					"disableSwHwCaching": "true",
+					"workload":           "Video streaming",
+				},
+				ExecutionId: uuid.New(),
+				Target: new(action_kit_api.Target{
+					Attributes: map[string][]string{
+						"host.hostname": {hostname},
+					},
+				}),
+			},
+
+			wantedError: "workload must be one of the following: Custom, OLTP database, Log append, current: Video streaming",
+		},
+		{
+			name: "Should return error writes to partition without destructive option",
+			requestBody: action_kit_api.PrepareActionRequestBody{
+				Config: map[string]any{
+					"action":             "prepare",
+					"duration":           "1000",
+					"stressLayer":        "Named Partition",
+					"stressLayerInput":   "C",
+					"threadCount":        "1",
+					"disableSwHwCaching": "true",
+					"writeRatio":         "10",
+				},
+				ExecutionId: uuid.New(),
+				Target: new(action_kit_api.Target{
+					Attributes: map[string][]string{
+						"host.hostname": {hostname},
+					},
+				}),
+			},
+
+			wantedError: "writes to a named partition destroy the data stored on it, they require the destructive option",
+		},
+		{
+			name: "Should allow writes to partition with destructive option",
+			requestBody: action_kit_api.PrepareActionRequestBody{
+				Config: map[string]any{
+					"action":             "prepare",
+					"duration":           "1000",
+					"stressLayer":        "Named Partition",
+					"stressLayerInput":   "C",
+					"threadCount":        "1",
+					"disableSwHwCaching": "true",
+					"writeRatio":         "10",
+					"destructive":        true,
+				},
+				ExecutionId: getExecutionId(3),
+				Target: new(action_kit_api.Target{
+					Attributes: map[string][]string{
+						"host.hostname": {hostname},
+					},
+				}),
+			},
+
+			wantedState: &IoStressActionState{
+				ExecutionId: getExecutionId(3),
+				StressOpts: IoStressOpts{
+					StressLayer:        IOStressLayers.NamedPartition,
+					StressLayerInput:   "C",
+					ThreadCount:        1,
+					DisableSwHwCaching: true,
+					Duration:           time.Second,
+					Pattern:            ioPattern{WriteRatio: 10, BlockSizeKiB: 64, OutstandingIOs: 2, AccessPattern: SequentialAccess},
 				},
 			},
 		},
@@ -186,7 +361,82 @@ func TestActionStressIO_Prepare(t *testing.T) {
 				assert.Equal(t, tt.wantedState.StressOpts.StressLayerInput, state.StressOpts.StressLayerInput)
 				assert.Equal(t, tt.wantedState.StressOpts.ThreadCount, state.StressOpts.ThreadCount)
 				assert.Equal(t, tt.wantedState.StressOpts.DisableSwHwCaching, state.StressOpts.DisableSwHwCaching)
+				assert.Equal(t, tt.wantedState.StressOpts.Pattern, state.StressOpts.Pattern)
+				assert.Equal(t, tt.wantedState.StressOpts.TestFile, state.StressOpts.TestFile)
+				assert.Equal(t, tt.wantedState.StressOpts.FileSizeMiB, state.StressOpts.FileSizeMiB)
 			}
 		})
 	}
 }
+
+func TestActionStressIO_PrepareFileSystem(t *testing.T) {
+	osHostname = func() (string, error) {
+		return "myhostname", nil
+	}
+	dir := t.TempDir()
+	existingFile := filepath.Join(dir, "existing.dat")
+	require.NoError(t, os.WriteFile(existingFile, []byte("data"), 0644))
+
+	prepare := func(input string, fileSize string, config map[string]any) (IoStressActionState, error) {
+		state := IoStressActionState{}
+		requestConfig := map[string]any{
+			"duration":         "1000",
+			"stressLayer":      "File System",
+			"stressLayerInput": input,
+			"threadCount":      "1",
+			"fileSize":         fileSize,
+			"writeRatio":       "50",
+		}
+		maps.Copy(requestConfig, config)
+		_, err := NewStressIoAction().Prepare(t.Context(), &state, action_kit_api.PrepareActionRequestBody{
+			Config:      requestConfig,
+			ExecutionId: uuid.MustParse("3d4f1c2a-6f0e-4b8e-9a53-0c1b2d3e4f50"),
+			Target:      new(action_kit_api.Target{Attributes: map[string][]string{"host.hostname": {"myhostname"}}}),
+		})
+		return state, err
+	}
+
+	// the created test file is written without the destructive option
+	state, err := prepare(dir, "512", nil)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "steadybit-io-stress-3d4f1c2a-6f0e-4b8e-9a53-0c1b2d3e4f50.dat"), state.StressOpts.TestFile)
+	assert.Equal(t, uint(512), state.StressOpts.FileSizeMiB)
+
+	// writes to an existing file require it
+	_, err = prepare(existingFile, "512", nil)
+	assert.EqualError(t, err, "writes to an existing file overwrite its content, they require the destructive option")
+	_, err = prepare(existingFile, "512", map[string]any{"workload": string(LogAppendWorkload)})
+	assert.EqualError(t, err, "writes to an existing file overwrite its content, they require the destructive option")
+
+	state, err = prepare(existingFile, "512", map[string]any{"destructive": true})
+	require.NoError(t, err)
+	assert.Empty(t, state.StressOpts.TestFile)
+	assert.Zero(t, state.StressOpts.FileSizeMiB)
+
+	// reading an existing file doesn't
+	_, err = prepare(existingFile, "512", map[string]any{"writeRatio": "0"})
+	require.NoError(t, err)
+
+	_, err = prepare(dir, "0", nil)
+	assert.EqualError(t, err, "test file size must be greater than 0")
+}
+
+func TestIoStressOpts_Args(t *testing.T) {
+	opts := IoStressOpts{
+		StressLayer:        FileSystem,
+		StressLayerInput:   `C:\temp\steadybit.dat`,
+		ThreadCount:        4,
+		Duration:           30 * time.Second,
+		DisableSwHwCaching: true,
+		Pattern:            ioWorkloadPresets[OLTPWorkload],
+		TestFile:           `C:\temp\steadybit-io-stress-1.dat`,
+		FileSizeMiB:        1024,
+	}
+	assert.Equal(t, []string{"-d30", "-F4", "-b8K", "-o8", "-w30", "-r", "-c1024M", "-L", "-W0", "-Sh", `C:\temp\steadybit-io-stress-1.dat`}, opts.Args())
+
+	opts.Pattern = ioWorkloadPresets[LogAppendWorkload]
+	opts.TestFile = ""
+	opts.FileSizeMiB = 0
+	opts.DisableSwHwCaching = false
+	assert.Equal(t, []string{"-d30", "-F4", "-b64K", "-o1", "-w100", "-L", "-W0", `C:\temp\steadybit.dat`}, opts.Args())
+}
@@ -14,6 +14,7 @@ import (
 )
 
 type timeTravelAction struct {
+	runner utils.CommandRunner
 }
 
 type TimeTravelActionState struct {
@@ -28,7 +29,7 @@ var (
 )
 
 func NewTimetravelAction() action_kit_sdk.Action[TimeTravelActionState] {
-	return &timeTravelAction{}
+	return &timeTravelAction{runner: utils.NewCommandRunner()}
 }
 
 func (a *timeTravelAction) NewEmptyState() TimeTravelActionState {
@@ -134,8 +135,7 @@ func (a *timeTravelAction) Prepare(_ context.Context, state *TimeTravelActionSta
 func (a *timeTravelAction) Start(ctx context.Context, state *TimeTravelActionState) (*action_kit_api.StartResult, error) {
 	if state.DisableNtp {
 		log.Info().Msg("Blocking NTP traffic")
-		command := utils.PowershellCommand("Stop-Service", "w32time")
-		output, err := command.CombinedOutput()
+		output, err := a.runner.RunPowershell(ctx, []string{"Stop-Service w32time"}, utils.PSRun)
 
 		if err != nil {
 			log.Error().Msg("Failed to block NTP traffic.")
@@ -147,8 +147,7 @@ func (a *timeTravelAction) Start(ctx context.Context, state *TimeTravelActionSta
 
 	log.Info().Dur("offset", state.Offset).Msg("Adjusting time")
 
-	command := utils.PowershellCommand(fmt.Sprintf("Set-Date -Date (Get-Date).AddMinutes(%f)", state.Offset.Minutes()))
-	output, err := command.CombinedOutput()
+	output, err := a.runner.RunPowershell(ctx, []string{fmt.Sprintf("Set-Date -Date (Get-Date).AddMinutes(%f)", state.Offset.Minutes())}, utils.PSRun)
 	if err != nil {
 		log.Error().Msg("Failed to adjust time.")
 		return nil, err
@@ -173,9 +172,7 @@ func (a *timeTravelAction) Stop(ctx context.Context, state *TimeTravelActionStat
 	log.Info().Msg("Adjusting time back.")
 	if state.DisableNtp {
 		log.Info().Msg("Unblocking NTP traffic.")
-		command := utils.PowershellCommand("Start-Service", "w32time")
-
-		output, err := command.CombinedOutput()
+		output, err := a.runner.RunPowershell(ctx, []string{"Start-Service w32time"}, utils.PSRun)
 
 		if err != nil {
 			log.Error().Msg("Failed to unblock NTP traffic.")
@@ -185,8 +182,7 @@ func (a *timeTravelAction) Stop(ctx context.Context, state *TimeTravelActionStat
 		log.Info().Msgf("%s", output)
 	}
 
-	command := utils.PowershellCommand(fmt.Sprintf("Set-Date -Date (Get-Date).AddMinutes(-%f)", state.Offset.Minutes()))
-	output, err := command.CombinedOutput()
+	output, err := a.runner.RunPowershell(ctx, []string{fmt.Sprintf("Set-Date -Date (Get-Date).AddMinutes(-%f)", state.Offset.Minutes())}, utils.PSRun)
 	if err != nil {
 		log.Error().Msg("Failed to revert time adjustment.")
 		return nil, err
@@ -194,8 +190,7 @@ func (a *timeTravelAction) Stop(ctx context.Context, state *TimeTravelActionStat
 
 	log.Info().Msgf("%s", output)
 
-	command = utils.PowershellCommand("w32tm", "/resync")
-	output, err = command.CombinedOutput()
+	output, err = a.runner.RunPowershell(ctx, []string{"w32tm /resync"}, utils.PSRun)
 	if err != nil {
 		log.Error().Msg("Failed to resync time using NTP.")
 		return nil, err
@@ -7,7 +7,9 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils/utilstest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestActionTimeTravel_Prepare(t *testing.T) {
@@ -90,3 +92,48 @@ func TestActionTimeTravel_Prepare(t *testing.T) {
 		})
 	}
 }
+
+func TestActionTimeTravel_StartStop(t *testing.T) {
+	tests := []struct {
+		name          string
+		state         TimeTravelActionState
+		wantedStart   []string
+		wantedStop    []string
+		wantedApplied bool
+	}{
+		{
+			name:        "Should stop NTP during time travel",
+			state:       TimeTravelActionState{Offset: 90 * time.Second, DisableNtp: true},
+			wantedStart: []string{"Stop-Service w32time", "Set-Date -Date (Get-Date).AddMinutes(1.500000)"},
+			wantedStop:  []string{"Start-Service w32time", "Set-Date -Date (Get-Date).AddMinutes(-1.500000)", "w32tm /resync"},
+		},
+		{
+			name:        "Should keep NTP running",
+			state:       TimeTravelActionState{Offset: time.Hour},
+			wantedStart: []string{"Set-Date -Date (Get-Date).AddMinutes(60.000000)"},
+			wantedStop:  []string{"Set-Date -Date (Get-Date).AddMinutes(-60.000000)", "w32tm /resync"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := utilstest.NewRecordingRunner()
+			action := &timeTravelAction{runner: runner}
+			state := tt.state
+
+			_, err := action.Start(t.Context(), &state)
+			require.NoError(t, err)
+			assert.True(t, state.OffsetApplied)
+			assert.Equal(t, tt.wantedStart, runner.Commands())
+
+			_, err = action.Stop(t.Context(), &state)
+			require.NoError(t, err)
+			assert.False(t, state.OffsetApplied)
+			assert.Equal(t, append(tt.wantedStart, tt.wantedStop...), runner.Commands())
+
+			// the offset is reverted only once
+			_, err = action.Stop(t.Context(), &state)
+			require.NoError(t, err)
+			assert.Len(t, runner.Commands(), len(tt.wantedStart)+len(tt.wantedStop))
+		})
+	}
+}
@@ -4,13 +4,17 @@
 package exthostwindows
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-host-windows/exthostwindows/dns"
 	"github.com/steadybit/extension-host-windows/exthostwindows/network"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
 	extension_kit "github.com/steadybit/extension-kit"
 )
 
@@ -91,6 +95,7 @@ func getRestrictedEndpoints(request action_kit_api.PrepareActionRequestBody) []a
 
 func RegisterQosPolicyCleanup() func() {
 	stop := make(chan struct{})
+	runner := utils.NewNetworkCommandRunner()
 
 	go func() {
 		ticker := time.NewTicker(60 * time.Second)
@@ -98,7 +103,7 @@ func RegisterQosPolicyCleanup() func() {
 		for {
 			select {
 			case <-ticker.C:
-				network.CleanupQosPolicies()
+				network.CleanupQosPolicies(runner)
 			case <-stop:
 				return
 			}
@@ -110,6 +115,22 @@ func RegisterQosPolicyCleanup() func() {
 	}
 }
 
+// RevertLeftoverNetworkAttacks enables the network attack journal and reverts all attacks which were still active
+// when the extension was terminated. DNS rules left over by DNS error attacks are removed as well.
+func RevertLeftoverNetworkAttacks() {
+	runner := utils.NewNetworkCommandRunner()
+	if err := dns.RemoveLeftoverNrptRules(context.Background(), runner); err != nil {
+		log.Error().Err(err).Msg("unable to remove leftover DNS rules")
+	}
+	if err := network.InitJournal(filepath.Join(applicationDataPath, "network-journal")); err != nil {
+		log.Error().Err(err).Msg("unable to initialize the network attack journal, attacks can't be reverted after a crash")
+		return
+	}
+	if err := network.RevertJournaledAttacks(context.Background(), runner); err != nil {
+		log.Error().Err(err).Msg("unable to revert leftover network attacks")
+	}
+}
+
 func resolveExecutable(executableName string, envKey string) string {
 	customExecutableLocation, customExecutableLocationExists := os.LookupEnv(envKey)
 
@@ -131,3 +152,15 @@ func resolveExecutable(executableName string, envKey string) string {
 
 	return executableName
 }
+
+// RemoveLeftoverIoStressFiles enables the registry of IO stress test files and deletes the files left over by attacks
+// which were still running when the extension was terminated.
+func RemoveLeftoverIoStressFiles() {
+	if err := ioStressFiles.init(filepath.Join(applicationDataPath, "io-stress-files")); err != nil {
+		log.Error().Err(err).Msg("unable to initialize the IO stress file registry, test files can't be deleted after a crash")
+		return
+	}
+	if err := ioStressFiles.removeLeftovers(); err != nil {
+		log.Error().Err(err).Msg("unable to delete leftover IO stress files")
+	}
+}
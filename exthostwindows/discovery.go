@@ -5,6 +5,7 @@ package exthostwindows
 
 import (
 	"context"
+	"fmt"
 	"github.com/elastic/go-sysinfo"
 	"github.com/rs/zerolog/log"
 	networkutils "github.com/steadybit/action-kit/go/action_kit_commons/network"
@@ -19,6 +20,7 @@ import (
 )
 
 type hostDiscovery struct {
+	runner utils.CommandRunner
 }
 
 var (
@@ -27,7 +29,7 @@ var (
 )
 
 func NewHostDiscovery() discovery_kit_sdk.TargetDiscovery {
-	discovery := &hostDiscovery{}
+	discovery := &hostDiscovery{runner: utils.NewCommandRunner()}
 	return discovery_kit_sdk.NewCachedTargetDiscovery(discovery,
 		discovery_kit_sdk.WithRefreshTargetsNow(),
 		discovery_kit_sdk.WithRefreshTargetsInterval(context.Background(), 30*time.Second),
@@ -174,11 +176,11 @@ func (d *hostDiscovery) DiscoverTargets(ctx context.Context) ([]discovery_kit_ap
 		target.Attributes[hostLabelAttributePrefix+key] = []string{value}
 	}
 
-	if id := awsInstanceId(ctx); id != "" {
+	if id := awsInstanceId(ctx, d.runner); id != "" {
 		target.Attributes[awsInstanceIdAttribute] = []string{id}
-	} else if id := gcpInstanceId(ctx); id != "" {
+	} else if id := gcpInstanceId(ctx, d.runner); id != "" {
 		target.Attributes[gcpInstanceIdAttribute] = []string{id}
-	} else if id := azureInstanceId(ctx); id != "" {
+	} else if id := azureInstanceId(ctx, d.runner); id != "" {
 		target.Attributes[azureInstanceIdAttribute] = []string{id}
 	}
 
@@ -186,15 +188,31 @@ func (d *hostDiscovery) DiscoverTargets(ctx context.Context) ([]discovery_kit_ap
 	return discovery_kit_commons.ApplyAttributeExcludes(targets, config.Config.DiscoveryAttributesExcludesHost), nil
 }
 
-func awsInstanceId(ctx context.Context) string {
+type computerSystem struct {
+	Manufacturer string
+	Model        string
+}
+
+type bios struct {
+	Manufacturer string
+}
+
+type azureInstanceMetadata struct {
+	Compute struct {
+		VmId string `json:"vmId"`
+	} `json:"compute"`
+}
+
+func awsInstanceId(ctx context.Context, runner utils.CommandRunner) string {
 	if awsEnv := os.Getenv("AWS_EXECUTION_ENV"); awsEnv == "" {
 		return ""
 	}
-	commands := []string{
-		"$token = Invoke-RestMethod -Method PUT -Headers @{'X-aws-ec2-metadata-token-ttl-seconds' = '60'} http://169.254.169.254/latest/api/token",
-		"Invoke-RestMethod -Method GET -Headers @{'X-aws-ec2-metadata-token' = $token} http://169.254.169.254/latest/meta-data/instance-id",
+	token, err := restMethod[string](ctx, runner, "PUT", "http://169.254.169.254/latest/api/token", map[string]string{"X-aws-ec2-metadata-token-ttl-seconds": "60"})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to retrieve AWS EC2 metadata token")
+		return ""
 	}
-	instanceId, err := utils.ExecutePowershellCommand(ctx, commands, utils.PSRun)
+	instanceId, err := restMethod[string](ctx, runner, "GET", "http://169.254.169.254/latest/meta-data/instance-id", map[string]string{"X-aws-ec2-metadata-token": token})
 	if err != nil {
 		log.Error().Err(err).Msg("failed to retrieve AWS EC2 instance id")
 		return ""
@@ -202,9 +220,16 @@ func awsInstanceId(ctx context.Context) string {
 	return instanceId
 }
 
-func gcpInstanceId(ctx context.Context) string {
-	command := "if ((Get-WmiObject Win32_BIOS).Manufacturer -eq 'Google') { Invoke-RestMethod -Headers @{'Metadata-Flavor' = 'Google'} -Uri 'http://metadata.google.internal/computeMetadata/v1/instance/id' }"
-	instanceId, err := utils.ExecutePowershellCommand(ctx, []string{command}, utils.PSRun)
+func gcpInstanceId(ctx context.Context, runner utils.CommandRunner) string {
+	b, err := cimInstance[bios](ctx, runner, "Win32_BIOS", "Manufacturer")
+	if err != nil {
+		log.Error().Err(err).Msg("failed to retrieve BIOS information")
+		return ""
+	}
+	if b.Manufacturer != "Google" {
+		return ""
+	}
+	instanceId, err := restMethod[string](ctx, runner, "GET", "http://metadata.google.internal/computeMetadata/v1/instance/id", map[string]string{"Metadata-Flavor": "Google"})
 	if err != nil {
 		log.Error().Err(err).Msg("failed to retrieve GCP instance id")
 		return ""
@@ -212,17 +237,58 @@ func gcpInstanceId(ctx context.Context) string {
 	return instanceId
 }
 
-func azureInstanceId(ctx context.Context) string {
-	commands := []string{
-		"$sys=Get-CimInstance Win32_ComputerSystem",
-		"$bios=Get-CimInstance Win32_BIOS",
-		"if($sys.Manufacturer -eq 'Microsoft Corporation' -and $sys.Model -eq 'Virtual Machine' -and $bios.Manufacturer -eq 'Microsoft Corporation') { " +
-			"try{(Invoke-RestMethod -Headers @{Metadata='true'} -Uri 'http://169.254.169.254/metadata/instance?api-version=2021-02-01').compute.vmId} catch{''}} else {''}",
+func azureInstanceId(ctx context.Context, runner utils.CommandRunner) string {
+	system, err := cimInstance[computerSystem](ctx, runner, "Win32_ComputerSystem", "Manufacturer", "Model")
+	if err != nil {
+		log.Error().Err(err).Msg("failed to retrieve computer system information")
+		return ""
 	}
-	instanceId, err := utils.ExecutePowershellCommand(ctx, commands, utils.PSRun)
+	if system.Manufacturer != "Microsoft Corporation" || system.Model != "Virtual Machine" {
+		return ""
+	}
+	b, err := cimInstance[bios](ctx, runner, "Win32_BIOS", "Manufacturer")
 	if err != nil {
-		log.Error().Err(err).Msg("failed to retrieve Azure instance id")
+		log.Error().Err(err).Msg("failed to retrieve BIOS information")
 		return ""
 	}
-	return instanceId
+	if b.Manufacturer != "Microsoft Corporation" {
+		return ""
+	}
+	// Hyper-V machines outside of Azure look the same, so failing requests are expected
+	metadata, err := restMethod[azureInstanceMetadata](ctx, runner, "GET", "http://169.254.169.254/metadata/instance?api-version=2021-02-01", map[string]string{"Metadata": "true"}, "compute")
+	if err != nil {
+		log.Debug().Err(err).Msg("failed to retrieve Azure instance id")
+		return ""
+	}
+	return metadata.Compute.VmId
+}
+
+// cimInstance returns the first instance of the class, or an empty one if there is none.
+func cimInstance[T any](ctx context.Context, runner utils.CommandRunner, className string, properties ...string) (T, error) {
+	var instance T
+	instances, err := utils.RunPowershellJson[T](ctx, runner, utils.PowershellInvocation{
+		Cmdlet: "Get-CimInstance",
+		Params: map[string]any{"ClassName": className},
+		Select: properties,
+	})
+	if err == nil && len(instances) > 0 {
+		instance = instances[0]
+	}
+	return instance, err
+}
+
+func restMethod[T any](ctx context.Context, runner utils.CommandRunner, method string, uri string, headers map[string]string, properties ...string) (T, error) {
+	var result T
+	results, err := utils.RunPowershellJson[T](ctx, runner, utils.PowershellInvocation{
+		Cmdlet: "Invoke-RestMethod",
+		Params: map[string]any{"Method": method, "Uri": uri, "Headers": headers, "TimeoutSec": 5},
+		Select: properties,
+	})
+	if err != nil {
+		return result, err
+	}
+	if len(results) == 0 {
+		return result, fmt.Errorf("no response from %s", uri)
+	}
+	return results[0], nil
 }
@@ -5,10 +5,14 @@ package exthostwindows
 
 import (
 	"context"
-	"github.com/steadybit/extension-host-windows/config"
-	"github.com/stretchr/testify/assert"
 	"os"
 	"testing"
+
+	"github.com/steadybit/extension-host-windows/config"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils/utilstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_DiscoverTargets(t *testing.T) {
@@ -19,7 +23,7 @@ func Test_DiscoverTargets(t *testing.T) {
 	_ = os.Setenv("MyEnvVar3", "MyEnvVarValue3")
 	config.Config.DiscoveryAttributesExcludesHost = []string{hostNicAttribute}
 
-	targets, _ := (&hostDiscovery{}).DiscoverTargets(context.Background())
+	targets, _ := (&hostDiscovery{runner: utils.NewCommandRunner()}).DiscoverTargets(context.Background())
 
 	assert.NotNil(t, targets)
 	assert.Len(t, targets, 1)
@@ -40,3 +44,37 @@ func Test_DiscoverTargets(t *testing.T) {
 	assert.Equal(t, attributes[hostEnvAttributePrefix+"myenvvar2"], []string{"MyEnvVarValue2"})
 	assert.Equal(t, attributes[hostEnvAttributePrefix+"myenvvar3"], []string{"MyEnvVarValue3"})
 }
+
+func Test_azureInstanceId(t *testing.T) {
+	metadata, err := os.ReadFile("testdata/azure-instance-metadata.json")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		computerSystem string
+		wantId         string
+		wantRequest    bool
+	}{
+		{
+			name:           "Azure virtual machine",
+			computerSystem: `{"Manufacturer":"Microsoft Corporation","Model":"Virtual Machine"}`,
+			wantId:         "02aab8a4-74ef-476e-8182-f6d2ba4166a6",
+			wantRequest:    true,
+		},
+		{
+			name:           "other machine",
+			computerSystem: `{"Manufacturer":"LENOVO","Model":"20XW004WGE"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := utilstest.NewRecordingRunner().
+				Respond("-Property Manufacturer,Model)", tt.computerSystem, nil).
+				Respond("-Property Manufacturer)", `{"Manufacturer":"Microsoft Corporation"}`, nil).
+				Respond("Invoke-RestMethod", string(metadata), nil)
+
+			assert.Equal(t, tt.wantId, azureInstanceId(t.Context(), runner))
+			assert.Equal(t, tt.wantRequest, len(runner.Commands()) == 3)
+		})
+	}
+}
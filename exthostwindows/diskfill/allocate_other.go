@@ -0,0 +1,19 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+//go:build !windows
+
+package diskfill
+
+import (
+	"os"
+)
+
+// allocate extends the file. Other file systems create sparse files this way, which is sufficient for tests.
+func allocate(file *os.File, size uint64) error {
+	return file.Truncate(int64(size))
+}
+
+// setSparse does nothing, files are extended sparse anyway.
+func setSparse(_ *os.File) error {
+	return nil
+}
@@ -0,0 +1,21 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package diskfill
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// allocate extends the file without writing it. NTFS allocates the clusters of files which aren't sparse, like
+// fsutil file createNew does.
+func allocate(file *os.File, size uint64) error {
+	return file.Truncate(int64(size))
+}
+
+func setSparse(file *os.File) error {
+	var returned uint32
+	return windows.DeviceIoControl(windows.Handle(file.Fd()), windows.FSCTL_SET_SPARSE, nil, 0, nil, 0, &returned, nil)
+}
@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+// Package diskfill fills a volume with a file, either at once or by writing it at a given rate.
+package diskfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Megabyte is the unit of the fill disk attack, like the drive space reported by Windows.
+const Megabyte = 1000 * 1000
+
+// ErrNotEnoughSpace is returned if the volume has less space available than requested.
+var ErrNotEnoughSpace = errors.New("not enough space on the drive")
+
+// ToLeaveFree returns the bytes to fill, so the given bytes stay available.
+func ToLeaveFree(available uint64, free uint64) (uint64, error) {
+	if available < free {
+		return 0, ErrNotEnoughSpace
+	}
+	return available - free, nil
+}
+
+// ToFill returns the bytes to fill, if they are available.
+func ToFill(available uint64, size uint64) (uint64, error) {
+	if available < size {
+		return 0, ErrNotEnoughSpace
+	}
+	return size, nil
+}
+
+// ToReachPercentage returns the bytes to fill, so the given percentage of the volume is used. Nothing is filled if
+// the volume is already used more.
+func ToReachPercentage(available uint64, total uint64, percentage uint64) uint64 {
+	if total == 0 {
+		return 0
+	}
+	used := (float64(total) - float64(available)) / float64(total) * 100
+	if used > float64(percentage) {
+		return 0
+	}
+	return uint64(float64(total) * (float64(percentage) - used) / 100)
+}
+
+// Filler fills a volume with a file. The file must not exist yet.
+type Filler struct {
+	Path string
+	Size uint64
+	// Sparse creates a sparse file when allocating, it takes no space until written.
+	Sparse bool
+	// Rate limits the bytes written per second, 0 writes as fast as possible.
+	Rate uint64
+	// BlockSize is the size of each write.
+	BlockSize uint64
+
+	written atomic.Uint64
+}
+
+// Filled returns the bytes allocated or written so far.
+func (f *Filler) Filled() uint64 {
+	return f.written.Load()
+}
+
+// Allocate creates the file with its full size at once, without writing it.
+func (f *Filler) Allocate() error {
+	file, err := os.OpenFile(f.Path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create fill file: %w", err)
+	}
+	if f.Sparse {
+		err = setSparse(file)
+		if err == nil {
+			err = file.Truncate(int64(f.Size))
+		}
+	} else {
+		err = allocate(file, f.Size)
+	}
+	err = errors.Join(err, file.Close())
+	if err != nil {
+		return fmt.Errorf("failed to allocate %d bytes for %s: %w", f.Size, f.Path, err)
+	}
+	f.written.Store(f.Size)
+	return nil
+}
+
+// Write creates the file and writes zeros until it reached its size or the context is canceled.
+func (f *Filler) Write(ctx context.Context) error {
+	if f.BlockSize == 0 {
+		return errors.New("block size must be greater than 0")
+	}
+
+	file, err := os.OpenFile(f.Path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create fill file: %w", err)
+	}
+	defer file.Close()
+
+	block := make([]byte, min(f.BlockSize, f.Size))
+	start := time.Now()
+	for written := uint64(0); written < f.Size; {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, err := file.Write(block[:min(uint64(len(block)), f.Size-written)])
+		written += uint64(n)
+		f.written.Store(written)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.Path, err)
+		}
+
+		if f.Rate > 0 {
+			due := start.Add(time.Duration(float64(written) / float64(f.Rate) * float64(time.Second)))
+			if err := sleepUntil(ctx, due); err != nil {
+				return err
+			}
+		}
+	}
+	return file.Close()
+}
+
+// Remove deletes the fill file, if it exists.
+func Remove(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete fill file: %w", err)
+	}
+	return nil
+}
+
+func sleepUntil(ctx context.Context, due time.Time) error {
+	wait := time.Until(due)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
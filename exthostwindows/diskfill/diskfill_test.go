@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package diskfill
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAmounts(t *testing.T) {
+	toLeave, err := ToLeaveFree(10*Megabyte, 3*Megabyte)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7*Megabyte), toLeave)
+	_, err = ToLeaveFree(2*Megabyte, 3*Megabyte)
+	assert.ErrorIs(t, err, ErrNotEnoughSpace)
+
+	toFill, err := ToFill(10*Megabyte, 3*Megabyte)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3*Megabyte), toFill)
+	_, err = ToFill(2*Megabyte, 3*Megabyte)
+	assert.ErrorIs(t, err, ErrNotEnoughSpace)
+
+	// 40% used, 80% wanted
+	assert.Equal(t, uint64(40*Megabyte), ToReachPercentage(60*Megabyte, 100*Megabyte, 80))
+	// already used more
+	assert.Equal(t, uint64(0), ToReachPercentage(10*Megabyte, 100*Megabyte, 80))
+	assert.Equal(t, uint64(0), ToReachPercentage(0, 0, 80))
+}
+
+func TestFiller_Allocate(t *testing.T) {
+	for _, sparse := range []bool{false, true} {
+		f := &Filler{Path: filepath.Join(t.TempDir(), "fill"), Size: 5 * Megabyte, Sparse: sparse}
+
+		require.NoError(t, f.Allocate())
+		info, err := os.Stat(f.Path)
+		require.NoError(t, err)
+		assert.Equal(t, int64(5*Megabyte), info.Size())
+		assert.Equal(t, uint64(5*Megabyte), f.Filled())
+
+		require.NoError(t, Remove(f.Path))
+		assert.NoFileExists(t, f.Path)
+	}
+}
+
+func TestFiller_AllocateRefusesExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fill")
+	require.NoError(t, os.WriteFile(path, []byte("data"), 0644))
+
+	f := &Filler{Path: path, Size: Megabyte}
+	assert.ErrorContains(t, f.Allocate(), "failed to create fill file")
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(content))
+}
+
+func TestFiller_Write(t *testing.T) {
+	f := &Filler{Path: filepath.Join(t.TempDir(), "fill"), Size: 2*Megabyte + 10, BlockSize: Megabyte}
+
+	require.NoError(t, f.Write(t.Context()))
+	info, err := os.Stat(f.Path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2*Megabyte+10), info.Size())
+	assert.Equal(t, uint64(2*Megabyte+10), f.Filled())
+}
+
+func TestFiller_WriteAtRate(t *testing.T) {
+	// 1 MB at 10 MB/s takes 100ms
+	f := &Filler{Path: filepath.Join(t.TempDir(), "fill"), Size: Megabyte, Rate: 10 * Megabyte, BlockSize: 100 * 1000}
+
+	start := time.Now()
+	require.NoError(t, f.Write(t.Context()))
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	assert.Equal(t, uint64(Megabyte), f.Filled())
+}
+
+func TestFiller_WriteStopsWhenCanceled(t *testing.T) {
+	// would take 100s
+	f := &Filler{Path: filepath.Join(t.TempDir(), "fill"), Size: 100 * Megabyte, Rate: Megabyte, BlockSize: 100 * 1000}
+	ctx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+	defer cancel()
+
+	err := f.Write(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Greater(t, f.Filled(), uint64(0))
+	assert.Less(t, f.Filled(), uint64(Megabyte))
+
+	require.NoError(t, Remove(f.Path))
+	assert.NoFileExists(t, f.Path)
+}
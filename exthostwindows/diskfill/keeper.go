@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package diskfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+)
+
+// maxAdjustments limits the adjustments kept until they are taken.
+const maxAdjustments = 600
+
+// FreeForPercentage returns the bytes to keep free, so the given percentage of the volume is used.
+func FreeForPercentage(total uint64, percentage uint64) uint64 {
+	return total - uint64(float64(total)*float64(min(percentage, 100))/100)
+}
+
+// Adjustment is a change of the size of the file held by a Keeper.
+type Adjustment struct {
+	Time time.Time
+	// Available is the free space of the volume which caused the adjustment.
+	Available uint64
+	Size      uint64
+	// Delta is the change of the size, negative if the file was shrunk.
+	Delta int64
+}
+
+// Keeper holds the free space of a volume by growing and shrinking a file, so writes and deletes of other processes
+// are compensated. The file must not exist yet.
+type Keeper struct {
+	Path string
+	// Space returns the current space of the volume.
+	Space func() (utils.VolumeSpace, error)
+	// Free returns the bytes to keep free on the volume.
+	Free func(space utils.VolumeSpace) uint64
+	// Interval is the time between two checks of the free space.
+	Interval time.Duration
+	// Tolerance is the deviation from the free space which is not adjusted, so the file isn't changed constantly.
+	Tolerance uint64
+
+	lock        sync.Mutex
+	size        uint64
+	adjustments []Adjustment
+}
+
+// Size returns the current size of the file.
+func (k *Keeper) Size() uint64 {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	return k.size
+}
+
+// TakeAdjustments returns the adjustments made since the previous call.
+func (k *Keeper) TakeAdjustments() []Adjustment {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	adjustments := k.adjustments
+	k.adjustments = nil
+	return adjustments
+}
+
+// Run creates the file and adjusts it until the context is canceled.
+func (k *Keeper) Run(ctx context.Context) error {
+	if k.Interval <= 0 {
+		return errors.New("interval must be greater than 0")
+	}
+
+	file, err := os.OpenFile(k.Path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create fill file: %w", err)
+	}
+	defer file.Close()
+
+	ticker := time.NewTicker(k.Interval)
+	defer ticker.Stop()
+	for {
+		if err := k.adjust(file); err != nil {
+			return err
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// adjust grows the file by the space available above the free space to keep, or shrinks it by the missing space.
+func (k *Keeper) adjust(file *os.File) error {
+	space, err := k.Space()
+	if err != nil {
+		return err
+	}
+	free := k.Free(space)
+
+	k.lock.Lock()
+	current := k.size
+	k.lock.Unlock()
+
+	size := current + space.Available - free
+	if space.Available < free {
+		size = current - min(current, free-space.Available)
+	}
+	delta := int64(size) - int64(current)
+	if uint64(max(delta, -delta)) <= k.Tolerance {
+		return nil
+	}
+
+	if size > current {
+		err = allocate(file, size)
+	} else {
+		err = file.Truncate(int64(size))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to resize %s from %d to %d bytes: %w", k.Path, current, size, err)
+	}
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	k.size = size
+	k.adjustments = append(k.adjustments, Adjustment{Time: time.Now(), Available: space.Available, Size: size, Delta: delta})
+	if len(k.adjustments) > maxAdjustments {
+		k.adjustments = k.adjustments[len(k.adjustments)-maxAdjustments:]
+	}
+	return nil
+}
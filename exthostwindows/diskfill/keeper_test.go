@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package diskfill
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeVolume simulates a volume used by the file of the keeper and by other writers.
+type fakeVolume struct {
+	total uint64
+	other atomic.Uint64
+	k     *Keeper
+}
+
+func (v *fakeVolume) space() (utils.VolumeSpace, error) {
+	return utils.VolumeSpace{Available: v.total - v.other.Load() - v.k.Size(), Total: v.total}, nil
+}
+
+func newKeeper(t *testing.T, total uint64, free uint64) (*Keeper, *fakeVolume) {
+	k := &Keeper{
+		Path:      filepath.Join(t.TempDir(), "fill"),
+		Free:      func(utils.VolumeSpace) uint64 { return free },
+		Interval:  10 * time.Millisecond,
+		Tolerance: Megabyte,
+	}
+	v := &fakeVolume{total: total, k: k}
+	k.Space = v.space
+	return k, v
+}
+
+func fileSize(t *testing.T, path string) uint64 {
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	return uint64(info.Size())
+}
+
+func TestFreeForPercentage(t *testing.T) {
+	assert.Equal(t, uint64(20*Megabyte), FreeForPercentage(100*Megabyte, 80))
+	assert.Equal(t, uint64(0), FreeForPercentage(100*Megabyte, 100))
+	assert.Equal(t, uint64(0), FreeForPercentage(100*Megabyte, 120))
+}
+
+func TestKeeper_HoldsFreeSpace(t *testing.T) {
+	k, v := newKeeper(t, 100*Megabyte, 10*Megabyte)
+	v.other.Store(30 * Megabyte)
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+	go func() { done <- k.Run(ctx) }()
+
+	assert.Eventually(t, func() bool { return k.Size() == 60*Megabyte }, time.Second, time.Millisecond)
+
+	// another process writes until the volume is full
+	v.other.Store(40 * Megabyte)
+	assert.Eventually(t, func() bool { return k.Size() == 50*Megabyte }, time.Second, time.Millisecond)
+
+	// another process deletes
+	v.other.Store(20 * Megabyte)
+	assert.Eventually(t, func() bool { return k.Size() == 70*Megabyte }, time.Second, time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Equal(t, uint64(70*Megabyte), fileSize(t, k.Path))
+
+	adjustments := k.TakeAdjustments()
+	require.Len(t, adjustments, 3)
+	assert.Equal(t, Adjustment{Time: adjustments[0].Time, Available: 70 * Megabyte, Size: 60 * Megabyte, Delta: 60 * Megabyte}, adjustments[0])
+	assert.Equal(t, Adjustment{Time: adjustments[1].Time, Available: 0, Size: 50 * Megabyte, Delta: -10 * Megabyte}, adjustments[1])
+	assert.Equal(t, Adjustment{Time: adjustments[2].Time, Available: 30 * Megabyte, Size: 70 * Megabyte, Delta: 20 * Megabyte}, adjustments[2])
+	assert.Empty(t, k.TakeAdjustments())
+}
+
+func TestKeeper_CannotFreeMoreThanTheFile(t *testing.T) {
+	k, v := newKeeper(t, 100*Megabyte, 10*Megabyte)
+	v.other.Store(95 * Megabyte)
+	file, err := os.Create(k.Path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	require.NoError(t, k.adjust(file))
+	assert.Equal(t, uint64(0), k.Size())
+	assert.Empty(t, k.TakeAdjustments())
+}
+
+func TestKeeper_IgnoresSmallDeviations(t *testing.T) {
+	k, v := newKeeper(t, 100*Megabyte, 10*Megabyte)
+	file, err := os.Create(k.Path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	require.NoError(t, k.adjust(file))
+	assert.Equal(t, uint64(90*Megabyte), k.Size())
+	assert.Equal(t, uint64(90*Megabyte), fileSize(t, k.Path))
+
+	v.other.Store(Megabyte / 2)
+	require.NoError(t, k.adjust(file))
+	assert.Equal(t, uint64(90*Megabyte), k.Size())
+	assert.Len(t, k.TakeAdjustments(), 1)
+}
+
+func TestKeeper_FailsIfSpaceIsUnknown(t *testing.T) {
+	k, _ := newKeeper(t, 100*Megabyte, 10*Megabyte)
+	k.Space = func() (utils.VolumeSpace, error) { return utils.VolumeSpace{}, errors.New("volume not found") }
+
+	assert.EqualError(t, k.Run(t.Context()), "volume not found")
+}
@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package exthostwindows
+
+import (
+	"encoding/base64"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-host-windows/exthostwindows/diskspd"
+)
+
+// diskspdMetrics returns the throughput and latency of all IO, the throughput of each thread and the latency
+// percentiles as metrics.
+func diskspdMetrics(result *diskspd.Result, executionId uuid.UUID, timestamp time.Time) action_kit_api.Metrics {
+	metrics := action_kit_api.Metrics{
+		newMetric(ioThroughputMetric, executionId, nil, timestamp, result.Total.MiBPerSecond),
+		newMetric(ioOperationsMetric, executionId, nil, timestamp, result.Total.IOPerSecond),
+	}
+	if result.Total.AvgLatencyMs != nil {
+		metrics = append(metrics, newMetric(ioLatencyMetric, executionId, nil, timestamp, *result.Total.AvgLatencyMs))
+	}
+	for _, thread := range result.Threads {
+		labels := map[string]string{"thread": strconv.Itoa(thread.Id)}
+		metrics = append(metrics,
+			newMetric(ioThreadThroughputMetric, executionId, labels, timestamp, thread.Total.MiBPerSecond),
+			newMetric(ioThreadOperationsMetric, executionId, labels, timestamp, thread.Total.IOPerSecond),
+		)
+	}
+	for _, percentile := range result.Percentiles {
+		if percentile.TotalMs == nil {
+			continue
+		}
+		labels := map[string]string{"percentile": strconv.FormatFloat(percentile.Percentile, 'f', -1, 64)}
+		metrics = append(metrics, newMetric(ioLatencyPercentileMetric, executionId, labels, timestamp, *percentile.TotalMs))
+	}
+	return metrics
+}
+
+// diskspdArtifact returns the summary of the results as artifact.
+func diskspdArtifact(result *diskspd.Result) action_kit_api.Artifact {
+	return action_kit_api.Artifact{
+		Label: "diskspd-summary.txt",
+		Data:  base64.StdEncoding.EncodeToString([]byte(result.Summary())),
+	}
+}
@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+// Package diskspd parses the results of diskspd, the storage load generator used by the IO stress attack.
+package diskspd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IO summarizes the IO of a thread or of all threads.
+type IO struct {
+	Bytes        uint64
+	IOs          uint64
+	MiBPerSecond float64
+	IOPerSecond  float64
+	// AvgLatencyMs is only measured if diskspd runs with -L.
+	AvgLatencyMs *float64
+}
+
+// add sums up the IO, the average latency is weighted by the number of IOs.
+func (io *IO) add(other IO) {
+	if other.AvgLatencyMs != nil {
+		latency := *other.AvgLatencyMs
+		if io.AvgLatencyMs != nil && io.IOs+other.IOs > 0 {
+			latency = (*io.AvgLatencyMs*float64(io.IOs) + latency*float64(other.IOs)) / float64(io.IOs+other.IOs)
+		}
+		io.AvgLatencyMs = &latency
+	}
+	io.Bytes += other.Bytes
+	io.IOs += other.IOs
+	io.MiBPerSecond += other.MiBPerSecond
+	io.IOPerSecond += other.IOPerSecond
+}
+
+// Thread is the IO of a thread to all of its targets.
+type Thread struct {
+	Id    int
+	Total IO
+}
+
+// Percentile is the latency of the given percentile, from 0 (min) to 100 (max). Operations that weren't performed have
+// no latency.
+type Percentile struct {
+	Percentile float64
+	ReadMs     *float64
+	WriteMs    *float64
+	TotalMs    *float64
+}
+
+// Result is the result diskspd prints once it finished.
+type Result struct {
+	Duration    time.Duration
+	Total       IO
+	Read        IO
+	Write       IO
+	Threads     []Thread
+	Percentiles []Percentile
+}
+
+// addThread adds the IO of a thread to a target, threads writing to several targets are reported once.
+func (r *Result) addThread(id int, io IO) {
+	for i := range r.Threads {
+		if r.Threads[i].Id == id {
+			r.Threads[i].Total.add(io)
+			return
+		}
+	}
+	r.Threads = append(r.Threads, Thread{Id: id, Total: io})
+}
+
+// Parse parses the output of diskspd, either the text output or the XML output of -Rxml.
+func Parse(output string) (*Result, error) {
+	if strings.HasPrefix(strings.TrimSpace(output), "<") {
+		return ParseXML(output)
+	}
+	return ParseText(output)
+}
+
+// FormatPercentile formats a percentile the way diskspd does in its text output, e.g. 99.9 as "3-nines".
+func FormatPercentile(percentile float64) string {
+	switch {
+	case percentile == 0:
+		return "min"
+	case percentile == 100:
+		return "max"
+	case percentile > 99:
+		nines := strings.TrimRight(strconv.FormatFloat(percentile, 'f', -1, 64), "0")
+		return fmt.Sprintf("%d-nines", strings.Count(nines, "9"))
+	default:
+		return fmt.Sprintf("%sth", strconv.FormatFloat(percentile, 'f', -1, 64))
+	}
+}
+
+// parsePercentile parses a percentile formatted by FormatPercentile.
+func parsePercentile(value string) (float64, error) {
+	switch value {
+	case "min":
+		return 0, nil
+	case "max":
+		return 100, nil
+	}
+	if nines, found := strings.CutSuffix(value, "-nines"); found {
+		count, err := strconv.Atoi(nines)
+		if err != nil || count < 3 {
+			return 0, fmt.Errorf("invalid percentile %q", value)
+		}
+		return strconv.ParseFloat("99."+strings.Repeat("9", count-2), 64)
+	}
+	if percentile, found := strings.CutSuffix(value, "th"); found {
+		return strconv.ParseFloat(percentile, 64)
+	}
+	return 0, fmt.Errorf("invalid percentile %q", value)
+}
+
+// Summary returns the result as a table, like the text output of diskspd without the details of the test setup.
+func (r *Result) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "diskspd results for %s\n\n", r.Duration)
+
+	fmt.Fprintf(&b, "%-10s | %15s | %12s | %10s | %10s | %8s\n", "", "bytes", "I/Os", "MiB/s", "I/O per s", "AvgLat")
+	row := func(name string, io IO) {
+		fmt.Fprintf(&b, "%-10s | %15d | %12d | %10.2f | %10.2f | %8s\n", name, io.Bytes, io.IOs, io.MiBPerSecond, io.IOPerSecond, formatMs(io.AvgLatencyMs))
+	}
+	row("total", r.Total)
+	row("read", r.Read)
+	row("write", r.Write)
+	for _, thread := range r.Threads {
+		row(fmt.Sprintf("thread %d", thread.Id), thread.Total)
+	}
+
+	if len(r.Percentiles) > 0 {
+		fmt.Fprintf(&b, "\n%-10s | %10s | %10s | %10s\n", "%-ile", "Read (ms)", "Write (ms)", "Total (ms)")
+		for _, p := range r.Percentiles {
+			fmt.Fprintf(&b, "%-10s | %10s | %10s | %10s\n", FormatPercentile(p.Percentile), formatMs(p.ReadMs), formatMs(p.WriteMs), formatMs(p.TotalMs))
+		}
+	}
+	return b.String()
+}
+
+func formatMs(ms *float64) string {
+	if ms == nil {
+		return "N/A"
+	}
+	return strconv.FormatFloat(*ms, 'f', 3, 64)
+}
@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package diskspd
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_DetectsFormat(t *testing.T) {
+	for _, file := range []string{"testdata/read-latency.txt", "testdata/random-mixed.xml"} {
+		output, err := os.ReadFile(file)
+		require.NoError(t, err)
+
+		result, err := Parse(string(output))
+		require.NoError(t, err, file)
+		assert.NotEmpty(t, result.Threads, file)
+	}
+}
+
+func TestFormatPercentile(t *testing.T) {
+	tests := map[float64]string{0: "min", 25: "25th", 99: "99th", 99.9: "3-nines", 99.9999999: "9-nines", 100: "max"}
+	for percentile, want := range tests {
+		assert.Equal(t, want, FormatPercentile(percentile))
+
+		parsed, err := parsePercentile(want)
+		require.NoError(t, err)
+		assert.Equal(t, percentile, parsed)
+	}
+
+	_, err := parsePercentile("2-nines")
+	assert.EqualError(t, err, `invalid percentile "2-nines"`)
+}
+
+func TestResult_Summary(t *testing.T) {
+	output, err := os.ReadFile("testdata/read-latency.txt")
+	require.NoError(t, err)
+	want, err := os.ReadFile("testdata/read-latency.summary.txt")
+	require.NoError(t, err)
+
+	result, err := ParseText(string(output))
+	require.NoError(t, err)
+	assert.Equal(t, strings.ReplaceAll(string(want), "\r\n", "\n"), result.Summary())
+}
@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package diskspd
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// capturedRuns are the diskspd runs scripts/capture-diskspd-testdata.ps1 captures on Windows, each once as text and
+// once with -Rxml. The command line leaves out the executable and the target file, keep both in sync when changing it.
+var capturedRuns = []struct {
+	name        string
+	commandLine string
+	threads     int
+	writes      bool
+}{
+	{name: "sequential-read", commandLine: "-d10 -F4 -b64K -o2 -c1024M -L -W0 -Sh", threads: 4},
+	{name: "random-mixed", commandLine: "-d10 -F2 -b8K -o8 -w30 -r -c1024M -L -W0 -Sh", threads: 2, writes: true},
+}
+
+func TestParse_CapturedOutput(t *testing.T) {
+	for _, run := range capturedRuns {
+		for _, format := range []string{"txt", "xml"} {
+			file := "testdata/" + run.name + "." + format
+			t.Run(run.name+"."+format, func(t *testing.T) {
+				output, err := os.ReadFile(file)
+				if errors.Is(err, fs.ErrNotExist) {
+					t.Skipf("%s not captured yet, run scripts/capture-diskspd-testdata.ps1 on Windows", file)
+				}
+				require.NoError(t, err)
+				if format == "txt" {
+					assert.Regexp(t, `Command Line: \S*diskspd(\.exe)? `+regexp.QuoteMeta(run.commandLine)+` `, string(output), "captured with another command line")
+				}
+
+				result, err := Parse(string(output))
+				require.NoError(t, err)
+
+				assert.Len(t, result.Threads, run.threads)
+				assert.Positive(t, result.Total.IOs)
+				assert.Positive(t, result.Read.IOs)
+				assert.Equal(t, run.writes, result.Write.IOs > 0)
+				assert.Equal(t, result.Total.IOs, result.Read.IOs+result.Write.IOs)
+				assert.Equal(t, result.Total.Bytes, result.Read.Bytes+result.Write.Bytes)
+				require.NotNil(t, result.Total.AvgLatencyMs)
+				assert.NotEmpty(t, result.Percentiles)
+
+				var threadIOs uint64
+				for _, thread := range result.Threads {
+					threadIOs += thread.Total.IOs
+				}
+				assert.Equal(t, result.Total.IOs, threadIOs)
+			})
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package diskspd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseText parses the text output of diskspd. Only the first timespan is parsed, the attack runs a single one.
+func ParseText(output string) (*Result, error) {
+	lines := strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n")
+
+	result := &Result{}
+	for _, line := range lines {
+		if value, found := strings.CutPrefix(strings.TrimSpace(line), "actual test time:"); found {
+			seconds, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "s"), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid test time in diskspd output: %w", err)
+			}
+			result.Duration = time.Duration(seconds * float64(time.Second))
+			break
+		}
+	}
+
+	table, err := parseTextIOTable(lines, "Total IO")
+	if err != nil {
+		return nil, err
+	}
+	result.Total = table.total
+	for _, row := range table.threads {
+		result.addThread(row.id, row.io)
+	}
+	if table, err := parseTextIOTable(lines, "Read IO"); err == nil {
+		result.Read = table.total
+	}
+	if table, err := parseTextIOTable(lines, "Write IO"); err == nil {
+		result.Write = table.total
+	}
+
+	result.Percentiles, err = parseTextPercentiles(lines)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type textIOTable struct {
+	threads []textThreadRow
+	total   IO
+}
+
+type textThreadRow struct {
+	id int
+	io IO
+}
+
+// parseTextIOTable parses the IO table with the given title. A thread has a row per target. The total row has no
+// thread column, "total:" is followed by the bytes.
+func parseTextIOTable(lines []string, title string) (textIOTable, error) {
+	start := findLine(lines, title)
+	if start < 0 || start+1 >= len(lines) {
+		return textIOTable{}, fmt.Errorf("diskspd output has no %s table", title)
+	}
+
+	header := splitRow(lines[start+1])
+	var table textIOTable
+	for _, line := range lines[start+2:] {
+		if strings.TrimSpace(line) == "" {
+			break
+		}
+		if rest, found := strings.CutPrefix(strings.TrimSpace(line), "total:"); found {
+			total, err := parseTextIO(columns(header[1:], splitRow(rest)))
+			if err != nil {
+				return textIOTable{}, err
+			}
+			table.total = total
+			return table, nil
+		}
+
+		fields := splitRow(line)
+		id, err := strconv.Atoi(fields[0])
+		if err != nil {
+			// separator line
+			continue
+		}
+		io, err := parseTextIO(columns(header, fields))
+		if err != nil {
+			return textIOTable{}, err
+		}
+		table.threads = append(table.threads, textThreadRow{id: id, io: io})
+	}
+	return textIOTable{}, fmt.Errorf("diskspd %s table has no total", title)
+}
+
+func parseTextIO(columns map[string]string) (IO, error) {
+	var io IO
+	var err error
+	if io.Bytes, err = strconv.ParseUint(columns["bytes"], 10, 64); err != nil {
+		return IO{}, fmt.Errorf("invalid bytes in diskspd output: %w", err)
+	}
+	if io.IOs, err = strconv.ParseUint(columns["I/Os"], 10, 64); err != nil {
+		return IO{}, fmt.Errorf("invalid I/Os in diskspd output: %w", err)
+	}
+	if io.MiBPerSecond, err = strconv.ParseFloat(columns["MiB/s"], 64); err != nil {
+		return IO{}, fmt.Errorf("invalid MiB/s in diskspd output: %w", err)
+	}
+	if io.IOPerSecond, err = strconv.ParseFloat(columns["I/O per s"], 64); err != nil {
+		return IO{}, fmt.Errorf("invalid I/O per s in diskspd output: %w", err)
+	}
+	if io.AvgLatencyMs, err = parseTextMs(columns["AvgLat"]); err != nil {
+		return IO{}, fmt.Errorf("invalid AvgLat in diskspd output: %w", err)
+	}
+	return io, nil
+}
+
+// parseTextPercentiles parses the latency percentiles, which are only printed if diskspd runs with -L.
+func parseTextPercentiles(lines []string) ([]Percentile, error) {
+	start := -1
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "%-ile") {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return nil, nil
+	}
+
+	header := splitRow(lines[start])
+	var percentiles []Percentile
+	for _, line := range lines[start+1:] {
+		if strings.TrimSpace(line) == "" {
+			break
+		}
+		fields := splitRow(line)
+		if strings.HasPrefix(fields[0], "---") {
+			continue
+		}
+		row := columns(header, fields)
+		percentile, err := parsePercentile(row["%-ile"])
+		if err != nil {
+			return nil, err
+		}
+		p := Percentile{Percentile: percentile}
+		if p.ReadMs, err = parseTextMs(row["Read (ms)"]); err != nil {
+			return nil, fmt.Errorf("invalid %s read latency in diskspd output: %w", row["%-ile"], err)
+		}
+		if p.WriteMs, err = parseTextMs(row["Write (ms)"]); err != nil {
+			return nil, fmt.Errorf("invalid %s write latency in diskspd output: %w", row["%-ile"], err)
+		}
+		if p.TotalMs, err = parseTextMs(row["Total (ms)"]); err != nil {
+			return nil, fmt.Errorf("invalid %s total latency in diskspd output: %w", row["%-ile"], err)
+		}
+		percentiles = append(percentiles, p)
+	}
+	return percentiles, nil
+}
+
+// parseTextMs parses a latency. Latencies that weren't measured are missing or "N/A".
+func parseTextMs(value string) (*float64, error) {
+	if value == "" || value == "N/A" {
+		return nil, nil
+	}
+	ms, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &ms, nil
+}
+
+func findLine(lines []string, line string) int {
+	for i := range lines {
+		if strings.TrimSpace(lines[i]) == line {
+			return i
+		}
+	}
+	return -1
+}
+
+func splitRow(line string) []string {
+	fields := strings.Split(line, "|")
+	for i, field := range fields {
+		fields[i] = strings.TrimSpace(field)
+	}
+	return fields
+}
+
+// columns maps the fields of a row to the header.
+func columns(header []string, fields []string) map[string]string {
+	columns := make(map[string]string, len(header))
+	for i, value := range fields {
+		if i < len(header) {
+			columns[header[i]] = value
+		}
+	}
+	return columns
+}
@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package diskspd
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseText(t *testing.T) {
+	output, err := os.ReadFile("testdata/read-latency.txt")
+	require.NoError(t, err)
+
+	result, err := ParseText(string(output))
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Second, result.Duration)
+	assert.Equal(t, IO{Bytes: 2120220672, IOs: 32352, MiBPerSecond: 202.20, IOPerSecond: 3235.20, AvgLatencyMs: new(2.472)}, result.Total)
+	assert.Equal(t, result.Total, result.Read)
+	assert.Equal(t, IO{AvgLatencyMs: new(0.0)}, result.Write)
+	assert.Equal(t, []Thread{
+		{Id: 0, Total: IO{Bytes: 536870912, IOs: 8192, MiBPerSecond: 51.20, IOPerSecond: 819.20, AvgLatencyMs: new(2.441)}},
+		{Id: 1, Total: IO{Bytes: 524288000, IOs: 8000, MiBPerSecond: 50.00, IOPerSecond: 800.00, AvgLatencyMs: new(2.500)}},
+		{Id: 2, Total: IO{Bytes: 545259520, IOs: 8320, MiBPerSecond: 52.00, IOPerSecond: 832.00, AvgLatencyMs: new(2.403)}},
+		{Id: 3, Total: IO{Bytes: 513802240, IOs: 7840, MiBPerSecond: 49.00, IOPerSecond: 784.00, AvgLatencyMs: new(2.551)}},
+	}, result.Threads)
+
+	require.Len(t, result.Percentiles, 15)
+	assert.Equal(t, Percentile{Percentile: 0, ReadMs: new(0.812), TotalMs: new(0.812)}, result.Percentiles[0])
+	assert.Equal(t, Percentile{Percentile: 99.9, ReadMs: new(8.532), TotalMs: new(8.532)}, result.Percentiles[7])
+	assert.Equal(t, Percentile{Percentile: 100, ReadMs: new(12.345), TotalMs: new(12.345)}, result.Percentiles[14])
+}
+
+func TestParseText_WithoutLatency(t *testing.T) {
+	output := "actual test time:\t5.00s\n" +
+		"\n" +
+		"Total IO\n" +
+		"thread |       bytes     |     I/Os     |    MiB/s   |  I/O per s |  file\n" +
+		"------------------------------------------------------------------------------\n" +
+		"     0 |        52428800 |          800 |      10.00 |     160.00 | C:\\a.dat (1GiB)\n" +
+		"     0 |        52428800 |          800 |      10.00 |     160.00 | C:\\b.dat (1GiB)\n" +
+		"------------------------------------------------------------------------------\n" +
+		"total:         104857600 |         1600 |      20.00 |     320.00\n"
+
+	result, err := ParseText(output)
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, result.Duration)
+	assert.Equal(t, IO{Bytes: 104857600, IOs: 1600, MiBPerSecond: 20, IOPerSecond: 320}, result.Total)
+	assert.Equal(t, []Thread{{Id: 0, Total: result.Total}}, result.Threads)
+	assert.Empty(t, result.Percentiles)
+}
+
+func TestParseText_Incomplete(t *testing.T) {
+	_, err := ParseText("Command Line: diskspd -d10 C:\\test.dat\n")
+	assert.EqualError(t, err, "diskspd output has no Total IO table")
+
+	_, err = ParseText("Total IO\nthread | bytes | I/Os | MiB/s | I/O per s | file\n---\n")
+	assert.EqualError(t, err, "diskspd Total IO table has no total")
+
+	_, err = ParseText("Total IO\nthread | bytes | I/Os | MiB/s | I/O per s | file\ntotal: 1 | 1 | fast | 1\n")
+	assert.ErrorContains(t, err, "invalid MiB/s in diskspd output")
+}
@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package diskspd
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// xmlResults is the part of the -Rxml output the attack reports.
+type xmlResults struct {
+	TimeSpans []xmlTimeSpan `xml:"TimeSpan"`
+}
+
+type xmlTimeSpan struct {
+	TestTimeSeconds float64     `xml:"TestTimeSeconds"`
+	Buckets         []xmlBucket `xml:"Latency>Bucket"`
+	Threads         []xmlThread `xml:"Thread"`
+}
+
+type xmlBucket struct {
+	Percentile        float64  `xml:"Percentile"`
+	ReadMilliseconds  *float64 `xml:"ReadMilliseconds"`
+	WriteMilliseconds *float64 `xml:"WriteMilliseconds"`
+	TotalMilliseconds *float64 `xml:"TotalMilliseconds"`
+}
+
+type xmlThread struct {
+	Id      int         `xml:"Id"`
+	Targets []xmlTarget `xml:"Target"`
+}
+
+type xmlTarget struct {
+	BytesCount                      uint64   `xml:"BytesCount"`
+	IOCount                         uint64   `xml:"IOCount"`
+	ReadBytes                       uint64   `xml:"ReadBytes"`
+	ReadCount                       uint64   `xml:"ReadCount"`
+	WriteBytes                      uint64   `xml:"WriteBytes"`
+	WriteCount                      uint64   `xml:"WriteCount"`
+	AverageReadLatencyMilliseconds  *float64 `xml:"AverageReadLatencyMilliseconds"`
+	AverageWriteLatencyMilliseconds *float64 `xml:"AverageWriteLatencyMilliseconds"`
+	AverageLatencyMilliseconds      *float64 `xml:"AverageLatencyMilliseconds"`
+}
+
+// ParseXML parses the XML output of diskspd -Rxml. Only the first timespan is parsed, the attack runs a single one.
+// Unlike the text output, the XML output has no rates, they are derived from the test time.
+func ParseXML(output string) (*Result, error) {
+	var results xmlResults
+	if err := xml.Unmarshal([]byte(output), &results); err != nil {
+		return nil, fmt.Errorf("invalid diskspd XML output: %w", err)
+	}
+	if len(results.TimeSpans) == 0 {
+		return nil, errors.New("diskspd XML output has no TimeSpan")
+	}
+	timeSpan := results.TimeSpans[0]
+	if timeSpan.TestTimeSeconds <= 0 {
+		return nil, fmt.Errorf("invalid test time in diskspd XML output: %v", timeSpan.TestTimeSeconds)
+	}
+
+	seconds := timeSpan.TestTimeSeconds
+	io := func(bytes uint64, ios uint64, latency *float64) IO {
+		return IO{
+			Bytes:        bytes,
+			IOs:          ios,
+			MiBPerSecond: float64(bytes) / (1 << 20) / seconds,
+			IOPerSecond:  float64(ios) / seconds,
+			AvgLatencyMs: latency,
+		}
+	}
+
+	result := &Result{Duration: time.Duration(seconds * float64(time.Second))}
+	for _, thread := range timeSpan.Threads {
+		for _, target := range thread.Targets {
+			total := io(target.BytesCount, target.IOCount, target.AverageLatencyMilliseconds)
+			result.addThread(thread.Id, total)
+			result.Total.add(total)
+			result.Read.add(io(target.ReadBytes, target.ReadCount, target.AverageReadLatencyMilliseconds))
+			result.Write.add(io(target.WriteBytes, target.WriteCount, target.AverageWriteLatencyMilliseconds))
+		}
+	}
+	for _, bucket := range timeSpan.Buckets {
+		result.Percentiles = append(result.Percentiles, Percentile{
+			Percentile: bucket.Percentile,
+			ReadMs:     bucket.ReadMilliseconds,
+			WriteMs:    bucket.WriteMilliseconds,
+			TotalMs:    bucket.TotalMilliseconds,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,53 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package diskspd
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseXML(t *testing.T) {
+	output, err := os.ReadFile("testdata/random-mixed.xml")
+	require.NoError(t, err)
+
+	result, err := ParseXML(string(output))
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Second, result.Duration)
+
+	assert.Equal(t, uint64(811008000), result.Total.Bytes)
+	assert.Equal(t, uint64(99000), result.Total.IOs)
+	assert.InDelta(t, 77.34, result.Total.MiBPerSecond, 0.01)
+	assert.InDelta(t, 9900, result.Total.IOPerSecond, 0.01)
+	require.NotNil(t, result.Total.AvgLatencyMs)
+	assert.InDelta(t, 0.934, *result.Total.AvgLatencyMs, 0.001)
+
+	assert.Equal(t, uint64(69300), result.Read.IOs)
+	assert.InDelta(t, 0.810, *result.Read.AvgLatencyMs, 0.001)
+	assert.Equal(t, uint64(29700), result.Write.IOs)
+	assert.InDelta(t, 1.225, *result.Write.AvgLatencyMs, 0.001)
+
+	require.Len(t, result.Threads, 2)
+	assert.Equal(t, 1, result.Threads[1].Id)
+	assert.InDelta(t, 4900, result.Threads[1].Total.IOPerSecond, 0.01)
+	assert.Equal(t, new(0.949), result.Threads[1].Total.AvgLatencyMs)
+
+	require.Len(t, result.Percentiles, 15)
+	assert.Equal(t, Percentile{Percentile: 99.9, ReadMs: new(3.215), WriteMs: new(4.870), TotalMs: new(4.102)}, result.Percentiles[7])
+}
+
+func TestParseXML_Incomplete(t *testing.T) {
+	_, err := ParseXML("<Results><System/></Results>")
+	assert.EqualError(t, err, "diskspd XML output has no TimeSpan")
+
+	_, err = ParseXML("<Results><TimeSpan><ThreadCount>1</ThreadCount></TimeSpan></Results>")
+	assert.EqualError(t, err, "invalid test time in diskspd XML output: 0")
+
+	_, err = ParseXML("<Results><TimeSpan>")
+	assert.ErrorContains(t, err, "invalid diskspd XML output")
+}
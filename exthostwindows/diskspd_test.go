@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package exthostwindows
+
+import (
+	"encoding/base64"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/steadybit/extension-host-windows/exthostwindows/diskspd"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils/utilstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskspdMetrics(t *testing.T) {
+	executionId := uuid.New()
+	timestamp := time.Date(2026, 1, 2, 10, 0, 10, 0, time.UTC)
+	result := &diskspd.Result{
+		Total:   diskspd.IO{MiBPerSecond: 202.2, IOPerSecond: 3235.2, AvgLatencyMs: new(2.472)},
+		Threads: []diskspd.Thread{{Id: 0, Total: diskspd.IO{MiBPerSecond: 202.2, IOPerSecond: 3235.2}}},
+		Percentiles: []diskspd.Percentile{
+			{Percentile: 99.9, ReadMs: new(8.532), TotalMs: new(8.532)},
+			{Percentile: 100, ReadMs: new(12.345)},
+		},
+	}
+
+	metrics := diskspdMetrics(result, executionId, timestamp)
+	assert.Equal(t, []string{ioThroughputMetric, ioOperationsMetric, ioLatencyMetric, ioThreadThroughputMetric, ioThreadOperationsMetric, ioLatencyPercentileMetric}, metricNames(metrics))
+	assert.Equal(t, 2.472, metrics[2].Value)
+	assert.Equal(t, timestamp, metrics[2].Timestamp)
+	assert.Equal(t, "0", metrics[3].Metric["thread"])
+	assert.Equal(t, "99.9", metrics[5].Metric["percentile"])
+	assert.Equal(t, 8.532, metrics[5].Value)
+}
+
+func TestActionStressIO_ReportsDiskspdResults(t *testing.T) {
+	output, err := os.ReadFile("diskspd/testdata/read-latency.txt")
+	require.NoError(t, err)
+	runner := utilstest.NewRecordingRunner().Respond("-d10", string(output), nil)
+	action := &ioStressAction{runner: runner}
+	state := &IoStressActionState{
+		ExecutionId: uuid.New(),
+		StressOpts:  IoStressOpts{StressLayer: NamedPartition, StressLayerInput: "C", ThreadCount: 4, Duration: 10 * time.Second, DisableSwHwCaching: true},
+	}
+
+	_, err = action.Start(t.Context(), state)
+	require.NoError(t, err)
+	assert.Contains(t, runner.Commands(), "diskspd -d10 -F4 -L -W0 -Sh C:")
+
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		result, err := action.Status(t.Context(), state)
+		require.NoError(c, err)
+		assert.True(c, result.Completed)
+		require.NotNil(c, result.Metrics)
+		assert.Len(c, *result.Metrics, 3+2*4+15)
+	}, time.Second, 10*time.Millisecond)
+
+	// the metrics are reported once, the summary at stop
+	result, err := action.Stop(t.Context(), state)
+	require.NoError(t, err)
+	assert.Nil(t, result.Metrics)
+	require.NotNil(t, result.Artifacts)
+	require.Len(t, *result.Artifacts, 1)
+	artifact := (*result.Artifacts)[0]
+	assert.Equal(t, "diskspd-summary.txt", artifact.Label)
+	summary, err := base64.StdEncoding.DecodeString(artifact.Data)
+	require.NoError(t, err)
+	assert.Contains(t, string(summary), "total      |      2120220672 |        32352 |     202.20 |    3235.20 |    2.472")
+}
+
+func TestIoStressExecution_SamplesIOWhileRunning(t *testing.T) {
+	executionId := uuid.New()
+	output, err := os.ReadFile("diskspd/testdata/read-latency.txt")
+	require.NoError(t, err)
+	counters := &fakeIOCounters{io: utils.DiskIO{BytesPerSecond: 100 << 20, TransfersPerSecond: 1600, AvgLatencySeconds: 0.0025}}
+	stress := &ioStressExecution{process: &stressProcess{name: "diskspd", done: make(chan struct{})}, counters: counters}
+
+	metrics := stress.sampledMetrics(executionId)
+	require.NotNil(t, metrics)
+	assert.Equal(t, []string{ioThroughputMetric, ioOperationsMetric, ioLatencyMetric}, metricNames(*metrics))
+	assert.Equal(t, 100.0, (*metrics)[0].Value)
+	assert.Equal(t, 1600.0, (*metrics)[1].Value)
+	assert.Equal(t, 2.5, (*metrics)[2].Value)
+	assert.Nil(t, stress.resultMetrics(executionId))
+
+	// once diskspd finished, its results are reported instead
+	_, _ = stress.process.output.Write(output)
+	close(stress.process.done)
+	assert.Nil(t, stress.sampledMetrics(executionId))
+	assert.True(t, counters.closed)
+	require.NotNil(t, stress.resultMetrics(executionId))
+}
@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+)
+
+// Lookups are sent to the responder with Name Resolution Policy Table (NRPT) rules. Rules created by the extension are
+// tagged with a comment, so leftovers can be removed.
+const nrptCommentPrefix = "STEADYBIT_DNS_"
+
+// AddNrptRules routes lookups matching the patterns to the name server.
+func AddNrptRules(ctx context.Context, runner utils.CommandRunner, id string, patterns []string, nameServer string) error {
+	_, err := runner.RunPowershell(ctx, []string{addNrptRuleCommand(id, patterns, nameServer), "Clear-DnsClientCache"}, utils.PSRun)
+	if err != nil {
+		return fmt.Errorf("failed to add NRPT rules: %w", err)
+	}
+	return nil
+}
+
+// RemoveNrptRules removes the rules added for the id.
+func RemoveNrptRules(ctx context.Context, runner utils.CommandRunner, id string) error {
+	_, err := runner.RunPowershell(ctx, []string{removeNrptRulesCommand(fmt.Sprintf("-eq '%s'", nrptComment(id))), "Clear-DnsClientCache"}, utils.PSRun)
+	if err != nil {
+		return fmt.Errorf("failed to remove NRPT rules: %w", err)
+	}
+	return nil
+}
+
+// RemoveLeftoverNrptRules removes all rules added by the extension.
+func RemoveLeftoverNrptRules(ctx context.Context, runner utils.CommandRunner) error {
+	_, err := runner.RunPowershell(ctx, []string{removeNrptRulesCommand(fmt.Sprintf("-like '%s*'", nrptCommentPrefix)), "Clear-DnsClientCache"}, utils.PSRun)
+	if err != nil {
+		return fmt.Errorf("failed to remove leftover NRPT rules: %w", err)
+	}
+	return nil
+}
+
+// SystemNameServers returns the name servers (host:port) configured for the network interfaces.
+func SystemNameServers(ctx context.Context, runner utils.CommandRunner) ([]string, error) {
+	out, err := runner.RunPowershell(ctx, []string{"Get-DnsClientServerAddress | Select-Object -ExpandProperty ServerAddresses | Sort-Object -Unique"}, utils.PSRun)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list name servers: %w", err)
+	}
+	return parseNameServers(out), nil
+}
+
+func parseNameServers(out string) []string {
+	var servers []string
+	for line := range strings.Lines(out) {
+		ip := net.ParseIP(strings.TrimSpace(line))
+		// skip loopback addresses, which would be answered by the responder itself
+		if ip == nil || ip.IsLoopback() {
+			continue
+		}
+		servers = append(servers, net.JoinHostPort(ip.String(), "53"))
+	}
+	return servers
+}
+
+func addNrptRuleCommand(id string, patterns []string, nameServer string) string {
+	namespaces := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		namespaces[i] = fmt.Sprintf("'%s'", nrptNamespace(pattern))
+	}
+	return fmt.Sprintf("Add-DnsClientNrptRule -Namespace %s -NameServers '%s' -Comment '%s'", strings.Join(namespaces, ","), nameServer, nrptComment(id))
+}
+
+func removeNrptRulesCommand(commentCondition string) string {
+	return fmt.Sprintf("Get-DnsClientNrptRule | Where-Object { $_.Comment %s } | ForEach-Object { Remove-DnsClientNrptRule -Name $_.Name -Force }", commentCondition)
+}
+
+// nrptNamespace returns the NRPT namespace for the pattern. A namespace with a leading dot matches all subdomains,
+// a single dot matches all names.
+func nrptNamespace(pattern string) string {
+	pattern = strings.TrimSuffix(pattern, ".")
+	if pattern == "*" {
+		return "."
+	}
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return "." + suffix
+	}
+	return pattern
+}
+
+func nrptComment(id string) string {
+	return nrptCommentPrefix + id
+}
@@ -0,0 +1,34 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package dns
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNrptNamespace(t *testing.T) {
+	assert.Equal(t, "example.com", nrptNamespace("example.com"))
+	assert.Equal(t, "example.com", nrptNamespace("example.com."))
+	assert.Equal(t, ".example.com", nrptNamespace("*.example.com"))
+	assert.Equal(t, ".", nrptNamespace("*"))
+}
+
+func TestAddNrptRuleCommand(t *testing.T) {
+	assert.Equal(t,
+		"Add-DnsClientNrptRule -Namespace 'example.com','.steadybit.com' -NameServers '127.0.0.1' -Comment 'STEADYBIT_DNS_42'",
+		addNrptRuleCommand("42", []string{"example.com", "*.steadybit.com"}, "127.0.0.1"))
+}
+
+func TestRemoveNrptRulesCommand(t *testing.T) {
+	assert.Equal(t,
+		"Get-DnsClientNrptRule | Where-Object { $_.Comment -eq 'STEADYBIT_DNS_42' } | ForEach-Object { Remove-DnsClientNrptRule -Name $_.Name -Force }",
+		removeNrptRulesCommand("-eq 'STEADYBIT_DNS_42'"))
+}
+
+func TestParseNameServers(t *testing.T) {
+	out := "10.0.0.2\r\n127.0.0.1\r\n::1\r\nfec0:0:0:ffff::1\r\n\r\n"
+	assert.Equal(t, []string{"10.0.0.2:53", "[fec0:0:0:ffff::1]:53"}, parseNameServers(out))
+}
@@ -0,0 +1,315 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+type Mode string
+
+const (
+	ModeNXDomain Mode = "nxdomain"
+	ModeServFail Mode = "servfail"
+	ModeDelay    Mode = "delay"
+)
+
+const maxMessageSize = 65535
+
+var upstreamTimeout = 5 * time.Second
+
+// tcpIdleTimeout is how long a tcp connection is kept open without receiving a query.
+const tcpIdleTimeout = 10 * time.Second
+
+// Config describes which lookups are affected and how.
+type Config struct {
+	Mode Mode
+	// Patterns are host names, a leading "*." matches all subdomains, "*" matches all names.
+	Patterns []string
+	Delay    time.Duration
+	// Upstream name servers (host:port) answering lookups which are not failed.
+	Upstream []string
+}
+
+func (c Config) Validate() error {
+	switch c.Mode {
+	case ModeNXDomain, ModeServFail:
+	case ModeDelay:
+		if c.Delay <= 0 {
+			return errors.New("delay must be greater than 0")
+		}
+	default:
+		return fmt.Errorf("unsupported mode %q", c.Mode)
+	}
+	if len(c.Patterns) == 0 {
+		return errors.New("at least one host name is required")
+	}
+	for _, pattern := range c.Patterns {
+		if err := validatePattern(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Responder answers DNS queries, failing or delaying the lookups matching the configured patterns and forwarding all
+// other lookups to the upstream name servers.
+type Responder struct {
+	config   Config
+	exchange func(ctx context.Context, network string, query []byte, server string) ([]byte, error)
+}
+
+func NewResponder(config Config) *Responder {
+	return &Responder{config: config, exchange: exchangeUpstream}
+}
+
+// Respond returns the answer to the query received on the network ("udp" or "tcp"). Forwarded queries are sent to the
+// upstream name servers on the same network, as lookups are retried on tcp if the answer on udp was truncated.
+func (r *Responder) Respond(ctx context.Context, network string, query []byte) ([]byte, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dns query: %w", err)
+	}
+	question, err := parser.Question()
+	if err != nil {
+		return errorResponse(header, nil, dnsmessage.RCodeFormatError)
+	}
+
+	name := question.Name.String()
+	if !r.matches(name) {
+		return r.forward(ctx, network, header, question, query)
+	}
+
+	log.Trace().Str("name", name).Str("mode", string(r.config.Mode)).Msg("injecting dns error")
+	switch r.config.Mode {
+	case ModeNXDomain:
+		return errorResponse(header, &question, dnsmessage.RCodeNameError)
+	case ModeServFail:
+		return errorResponse(header, &question, dnsmessage.RCodeServerFailure)
+	case ModeDelay:
+		select {
+		case <-time.After(r.config.Delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return r.forward(ctx, network, header, question, query)
+	default:
+		return nil, fmt.Errorf("unsupported mode %q", r.config.Mode)
+	}
+}
+
+func (r *Responder) matches(name string) bool {
+	for _, pattern := range r.config.Patterns {
+		if MatchesPattern(name, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Responder) forward(ctx context.Context, network string, header dnsmessage.Header, question dnsmessage.Question, query []byte) ([]byte, error) {
+	var errs error
+	for _, server := range r.config.Upstream {
+		answer, err := r.exchange(ctx, network, query, server)
+		if err == nil {
+			return answer, nil
+		}
+		errs = errors.Join(errs, err)
+	}
+	log.Debug().Err(errs).Str("name", question.Name.String()).Msg("no upstream name server answered")
+	return errorResponse(header, &question, dnsmessage.RCodeServerFailure)
+}
+
+// Serve answers udp queries received on the connection until the context is done.
+func (r *Responder) Serve(ctx context.Context, conn net.PacketConn) error {
+	go func() {
+		<-ctx.Done()
+		_ = conn.Close()
+	}()
+
+	buffer := make([]byte, maxMessageSize)
+	for {
+		n, addr, err := conn.ReadFrom(buffer)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		query := make([]byte, n)
+		copy(query, buffer[:n])
+		go func() {
+			answer, err := r.Respond(ctx, "udp", query)
+			if err != nil {
+				log.Debug().Err(err).Msg("failed to answer dns query")
+				return
+			}
+			if _, err := conn.WriteTo(answer, addr); err != nil && ctx.Err() == nil {
+				log.Debug().Err(err).Msg("failed to send dns answer")
+			}
+		}()
+	}
+}
+
+// ServeTCP answers tcp queries received on the connections accepted by the listener until the context is done.
+func (r *Responder) ServeTCP(ctx context.Context, listener net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go r.serveConn(ctx, conn)
+	}
+}
+
+// serveConn answers the queries received on the connection in order, until the client closes it or stays idle.
+func (r *Responder) serveConn(ctx context.Context, conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(tcpIdleTimeout))
+		query, err := readTCPMessage(conn)
+		if err != nil {
+			return
+		}
+		answer, err := r.Respond(ctx, "tcp", query)
+		if err != nil {
+			log.Debug().Err(err).Msg("failed to answer dns query")
+			return
+		}
+		if err := writeTCPMessage(conn, answer); err != nil {
+			if ctx.Err() == nil {
+				log.Debug().Err(err).Msg("failed to send dns answer")
+			}
+			return
+		}
+	}
+}
+
+// readTCPMessage reads a message prefixed with its two byte length, as dns messages are sent on tcp.
+func readTCPMessage(conn net.Conn) ([]byte, error) {
+	var length uint16
+	if err := binary.Read(conn, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+	message := make([]byte, length)
+	if _, err := io.ReadFull(conn, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func writeTCPMessage(conn net.Conn, message []byte) error {
+	if len(message) > maxMessageSize {
+		return fmt.Errorf("dns message of %d bytes exceeds the maximum size", len(message))
+	}
+	_, err := conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(message))), message...))
+	return err
+}
+
+func errorResponse(query dnsmessage.Header, question *dnsmessage.Question, rcode dnsmessage.RCode) ([]byte, error) {
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:                 query.ID,
+		Response:           true,
+		OpCode:             query.OpCode,
+		RecursionDesired:   query.RecursionDesired,
+		RecursionAvailable: true,
+		RCode:              rcode,
+	})
+	if question != nil {
+		if err := builder.StartQuestions(); err != nil {
+			return nil, err
+		}
+		if err := builder.Question(*question); err != nil {
+			return nil, err
+		}
+	}
+	return builder.Finish()
+}
+
+// exchangeUpstream sends the query to the name server on the network ("udp" or "tcp") and returns the answer.
+func exchangeUpstream(ctx context.Context, network string, query []byte, server string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, upstreamTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if network == "tcp" {
+		if err := writeTCPMessage(conn, query); err != nil {
+			return nil, err
+		}
+		return readTCPMessage(conn)
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	answer := make([]byte, maxMessageSize)
+	n, err := conn.Read(answer)
+	if err != nil {
+		return nil, err
+	}
+	return answer[:n], nil
+}
+
+// MatchesPattern reports whether the host name matches the pattern. A pattern "*.example.com" matches all subdomains
+// of example.com, "*" matches all names. Names are compared case-insensitive.
+func MatchesPattern(name, pattern string) bool {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	pattern = strings.ToLower(strings.TrimSuffix(pattern, "."))
+	if pattern == "*" {
+		return true
+	}
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(name, "."+suffix)
+	}
+	return name == pattern
+}
+
+func validatePattern(pattern string) error {
+	if pattern == "*" {
+		return nil
+	}
+	name := strings.TrimSuffix(strings.TrimPrefix(pattern, "*."), ".")
+	if name == "" || len(name) > 253 {
+		return fmt.Errorf("invalid host name pattern %q", pattern)
+	}
+	for label := range strings.SplitSeq(name, ".") {
+		if label == "" || len(label) > 63 || strings.ContainsFunc(label, func(r rune) bool {
+			return !(r == '-' || r == '_' || ('0' <= r && r <= '9') || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z'))
+		}) {
+			return fmt.Errorf("invalid host name pattern %q", pattern)
+		}
+	}
+	return nil
+}
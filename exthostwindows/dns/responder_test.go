@@ -0,0 +1,268 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package dns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestMatchesPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		want    bool
+	}{
+		{name: "example.com.", pattern: "example.com", want: true},
+		{name: "Example.COM.", pattern: "example.com", want: true},
+		{name: "www.example.com.", pattern: "example.com", want: false},
+		{name: "www.example.com.", pattern: "*.example.com", want: true},
+		{name: "a.b.example.com.", pattern: "*.example.com", want: true},
+		{name: "example.com.", pattern: "*.example.com", want: false},
+		{name: "notexample.com.", pattern: "*.example.com", want: false},
+		{name: "steadybit.com.", pattern: "*", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" "+tt.pattern, func(t *testing.T) {
+			assert.Equal(t, tt.want, MatchesPattern(tt.name, tt.pattern))
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{name: "nxdomain", config: Config{Mode: ModeNXDomain, Patterns: []string{"example.com"}}},
+		{name: "wildcard", config: Config{Mode: ModeServFail, Patterns: []string{"*.example.com", "*"}}},
+		{name: "delay", config: Config{Mode: ModeDelay, Patterns: []string{"example.com"}, Delay: time.Second}},
+		{name: "delay missing", config: Config{Mode: ModeDelay, Patterns: []string{"example.com"}}, wantErr: "delay must be greater than 0"},
+		{name: "unknown mode", config: Config{Mode: "refused", Patterns: []string{"example.com"}}, wantErr: "unsupported mode \"refused\""},
+		{name: "no patterns", config: Config{Mode: ModeNXDomain}, wantErr: "at least one host name is required"},
+		{name: "invalid pattern", config: Config{Mode: ModeNXDomain, Patterns: []string{"exa'mple.com"}}, wantErr: "invalid host name pattern \"exa'mple.com\""},
+		{name: "wildcard in the middle", config: Config{Mode: ModeNXDomain, Patterns: []string{"www.*.com"}}, wantErr: "invalid host name pattern \"www.*.com\""},
+		{name: "empty label", config: Config{Mode: ModeNXDomain, Patterns: []string{"example..com"}}, wantErr: "invalid host name pattern \"example..com\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRespond(t *testing.T) {
+	upstreamAnswer := []byte("upstream answer")
+	tests := []struct {
+		name      string
+		config    Config
+		query     string
+		upstream  error
+		wantRCode dnsmessage.RCode
+		// wantForward expects the query to be answered by the upstream name server
+		wantForward bool
+	}{
+		{
+			name:      "nxdomain for matching name",
+			config:    Config{Mode: ModeNXDomain, Patterns: []string{"example.com"}},
+			query:     "example.com.",
+			wantRCode: dnsmessage.RCodeNameError,
+		},
+		{
+			name:      "servfail for matching subdomain",
+			config:    Config{Mode: ModeServFail, Patterns: []string{"*.example.com"}},
+			query:     "www.example.com.",
+			wantRCode: dnsmessage.RCodeServerFailure,
+		},
+		{
+			name:        "forward other names",
+			config:      Config{Mode: ModeNXDomain, Patterns: []string{"example.com"}},
+			query:       "steadybit.com.",
+			wantForward: true,
+		},
+		{
+			name:        "forward delayed names",
+			config:      Config{Mode: ModeDelay, Patterns: []string{"example.com"}, Delay: time.Millisecond},
+			query:       "example.com.",
+			wantForward: true,
+		},
+		{
+			name:      "servfail if upstream fails",
+			config:    Config{Mode: ModeNXDomain, Patterns: []string{"example.com"}},
+			query:     "steadybit.com.",
+			upstream:  errors.New("timeout"),
+			wantRCode: dnsmessage.RCodeServerFailure,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Upstream = []string{"192.0.2.1:53"}
+			r := NewResponder(tt.config)
+			r.exchange = func(_ context.Context, network string, _ []byte, server string) ([]byte, error) {
+				assert.Equal(t, "udp", network)
+				assert.Equal(t, "192.0.2.1:53", server)
+				return upstreamAnswer, tt.upstream
+			}
+
+			answer, err := r.Respond(t.Context(), "udp", buildQuery(t, 42, tt.query))
+			require.NoError(t, err)
+			if tt.wantForward {
+				assert.Equal(t, upstreamAnswer, answer)
+				return
+			}
+
+			var msg dnsmessage.Message
+			require.NoError(t, msg.Unpack(answer))
+			assert.Equal(t, uint16(42), msg.Header.ID)
+			assert.True(t, msg.Header.Response)
+			assert.True(t, msg.Header.RecursionDesired)
+			assert.Equal(t, tt.wantRCode, msg.Header.RCode)
+			require.Len(t, msg.Questions, 1)
+			assert.Equal(t, tt.query, msg.Questions[0].Name.String())
+			assert.Empty(t, msg.Answers)
+		})
+	}
+}
+
+func TestRespondDelay(t *testing.T) {
+	r := NewResponder(Config{Mode: ModeDelay, Patterns: []string{"example.com"}, Delay: 100 * time.Millisecond, Upstream: []string{"192.0.2.1:53"}})
+	r.exchange = func(context.Context, string, []byte, string) ([]byte, error) {
+		return []byte("answer"), nil
+	}
+
+	start := time.Now()
+	_, err := r.Respond(t.Context(), "udp", buildQuery(t, 1, "example.com."))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, err = r.Respond(ctx, "udp", buildQuery(t, 2, "example.com."))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRespondInvalidQuery(t *testing.T) {
+	_, err := NewResponder(Config{Mode: ModeNXDomain, Patterns: []string{"*"}}).Respond(t.Context(), "udp", []byte{1, 2, 3})
+	assert.Error(t, err)
+}
+
+func TestServe(t *testing.T) {
+	upstream, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = upstream.Close() }()
+	go func() {
+		// echo the queries, which is enough to recognize forwarded lookups
+		buffer := make([]byte, maxMessageSize)
+		for {
+			n, addr, err := upstream.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			_, _ = upstream.WriteTo(buffer[:n], addr)
+		}
+	}()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+	go func() {
+		done <- NewResponder(Config{Mode: ModeNXDomain, Patterns: []string{"*.example.com"}, Upstream: []string{upstream.LocalAddr().String()}}).Serve(ctx, conn)
+	}()
+
+	query := buildQuery(t, 7, "www.example.com.")
+	var msg dnsmessage.Message
+	require.NoError(t, msg.Unpack(exchange(t, conn.LocalAddr().String(), query)))
+	assert.Equal(t, dnsmessage.RCodeNameError, msg.Header.RCode)
+
+	query = buildQuery(t, 8, "steadybit.com.")
+	assert.Equal(t, query, exchange(t, conn.LocalAddr().String(), query))
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestServeTCP(t *testing.T) {
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = upstream.Close() }()
+	go func() {
+		// echo the queries, which is enough to recognize forwarded lookups
+		for {
+			conn, err := upstream.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				if query, err := readTCPMessage(conn); err == nil {
+					_ = writeTCPMessage(conn, query)
+				}
+			}()
+		}
+	}()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+	go func() {
+		done <- NewResponder(Config{Mode: ModeServFail, Patterns: []string{"example.com"}, Upstream: []string{upstream.Addr().String()}}).ServeTCP(ctx, listener)
+	}()
+
+	// the Windows DNS client retries lookups on tcp after truncated answers, it may send several queries per connection
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+
+	require.NoError(t, writeTCPMessage(conn, buildQuery(t, 9, "example.com.")))
+	answer, err := readTCPMessage(conn)
+	require.NoError(t, err)
+	var msg dnsmessage.Message
+	require.NoError(t, msg.Unpack(answer))
+	assert.Equal(t, uint16(9), msg.Header.ID)
+	assert.Equal(t, dnsmessage.RCodeServerFailure, msg.Header.RCode)
+
+	query := buildQuery(t, 10, "steadybit.com.")
+	require.NoError(t, writeTCPMessage(conn, query))
+	answer, err = readTCPMessage(conn)
+	require.NoError(t, err)
+	assert.Equal(t, query, answer)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func exchange(t *testing.T, server string, query []byte) []byte {
+	answer, err := exchangeUpstream(t.Context(), "udp", query, server)
+	require.NoError(t, err)
+	return answer
+}
+
+func buildQuery(t *testing.T, id uint16, name string) []byte {
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, RecursionDesired: true})
+	require.NoError(t, builder.StartQuestions())
+	require.NoError(t, builder.Question(dnsmessage.Question{
+		Name:  dnsmessage.MustNewName(name),
+		Type:  dnsmessage.TypeA,
+		Class: dnsmessage.ClassINET,
+	}))
+	query, err := builder.Finish()
+	require.NoError(t, err)
+	return query
+}
@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package exthostwindows
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// ioStressFilePrefix is the prefix of the test files created by IO stress attacks.
+const ioStressFilePrefix = "steadybit-io-stress-"
+
+// ioStressFileRegistry keeps track of the test files created by IO stress attacks on disk, so they can be deleted
+// after the extension crashed. Without a directory the files are only deleted when the attack is stopped.
+type ioStressFileRegistry struct {
+	lock sync.Mutex
+	dir  string
+}
+
+var ioStressFiles = &ioStressFileRegistry{}
+
+// ioStressFilePath returns the unique path of the test file of an execution.
+func ioStressFilePath(dir string, executionId uuid.UUID) string {
+	return filepath.Join(dir, fmt.Sprintf("%s%s.dat", ioStressFilePrefix, executionId))
+}
+
+// init enables the registry in the given directory.
+func (r *ioStressFileRegistry) init(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create IO stress file registry: %w", err)
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.dir = dir
+	return nil
+}
+
+// add records the test file before it is created.
+func (r *ioStressFileRegistry) add(path string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.dir == "" {
+		return nil
+	}
+	if err := os.WriteFile(r.entryPath(path), []byte(path), 0644); err != nil {
+		return fmt.Errorf("failed to record IO stress file %s: %w", path, err)
+	}
+	return nil
+}
+
+// remove deletes the test file and its record.
+func (r *ioStressFileRegistry) remove(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete IO stress file: %w", err)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.dir == "" {
+		return nil
+	}
+	if err := os.Remove(r.entryPath(path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warn().Err(err).Str("file", path).Msg("failed to remove record of IO stress file")
+	}
+	return nil
+}
+
+// removeLeftovers deletes all recorded test files. Such leftovers exist if the extension was terminated during an
+// attack.
+func (r *ioStressFileRegistry) removeLeftovers() error {
+	r.lock.Lock()
+	dir := r.dir
+	r.lock.Unlock()
+	if dir == "" {
+		return nil
+	}
+
+	entries, err := filepath.Glob(filepath.Join(dir, "*.path"))
+	if err != nil {
+		return fmt.Errorf("failed to list IO stress files: %w", err)
+	}
+
+	var errs error
+	for _, entry := range entries {
+		content, err := os.ReadFile(entry)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+		path := string(content)
+		// only delete files created by the extension, even if the record was tampered with
+		if !strings.HasPrefix(filepath.Base(path), ioStressFilePrefix) {
+			log.Error().Str("file", path).Msg("Removing invalid record of IO stress file")
+			_ = os.Remove(entry)
+			continue
+		}
+		log.Warn().Str("file", path).Msg("Found leftover IO stress file, deleting it")
+		if err := r.remove(path); err != nil {
+			errs = errors.Join(errs, err)
+		}
+	}
+	if errs != nil {
+		return fmt.Errorf("failed to delete leftover IO stress files: %w", errs)
+	}
+	return nil
+}
+
+func (r *ioStressFileRegistry) entryPath(path string) string {
+	return filepath.Join(r.dir, filepath.Base(path)+".path")
+}
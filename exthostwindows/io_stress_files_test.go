@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package exthostwindows
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIoStressFileRegistry_RemovesFileAndRecord(t *testing.T) {
+	registry := &ioStressFileRegistry{}
+	require.NoError(t, registry.init(t.TempDir()))
+	path := ioStressFilePath(t.TempDir(), uuid.New())
+
+	require.NoError(t, registry.add(path))
+	require.NoError(t, os.WriteFile(path, []byte("data"), 0644))
+	require.NoError(t, registry.remove(path))
+
+	assert.NoFileExists(t, path)
+	assert.NoFileExists(t, registry.entryPath(path))
+	// removing twice is fine, e.g. if the attack is stopped after the file was deleted as leftover
+	require.NoError(t, registry.remove(path))
+}
+
+func TestIoStressFileRegistry_RemovesLeftovers(t *testing.T) {
+	registryDir := t.TempDir()
+	dataDir := t.TempDir()
+	crashed := &ioStressFileRegistry{}
+	require.NoError(t, crashed.init(registryDir))
+	leftover := ioStressFilePath(dataDir, uuid.New())
+	require.NoError(t, crashed.add(leftover))
+	require.NoError(t, os.WriteFile(leftover, []byte("data"), 0644))
+	notCreated := ioStressFilePath(dataDir, uuid.New())
+	require.NoError(t, crashed.add(notCreated))
+	// records of files not created by the extension are ignored
+	userFile := filepath.Join(dataDir, "important.dat")
+	require.NoError(t, os.WriteFile(userFile, []byte("data"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(registryDir, "important.dat.path"), []byte(userFile), 0644))
+
+	restarted := &ioStressFileRegistry{}
+	require.NoError(t, restarted.init(registryDir))
+	require.NoError(t, restarted.removeLeftovers())
+
+	assert.NoFileExists(t, leftover)
+	assert.FileExists(t, userFile)
+	entries, err := os.ReadDir(registryDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestIoStressFileRegistry_WithoutDirectory(t *testing.T) {
+	registry := &ioStressFileRegistry{}
+	path := ioStressFilePath(t.TempDir(), uuid.New())
+
+	require.NoError(t, registry.add(path))
+	require.NoError(t, os.WriteFile(path, []byte("data"), 0644))
+	require.NoError(t, registry.remove(path))
+	assert.NoFileExists(t, path)
+	require.NoError(t, registry.removeLeftovers())
+}
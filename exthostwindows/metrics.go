@@ -0,0 +1,157 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package exthostwindows
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-sysinfo"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+	"github.com/steadybit/extension-kit/extutil"
+)
+
+// Metrics reported by the resource attacks, so experiment reports show whether an attack reached its target.
+const (
+	cpuLoadMetric         = "cpu_load"
+	committedMemoryMetric = "committed_memory_bytes"
+	diskFreeMetric        = "disk_free_bytes"
+	ioThroughputMetric    = "io_throughput_mib_per_second"
+	ioOperationsMetric    = "io_operations_per_second"
+	ioLatencyMetric       = "io_latency_milliseconds"
+
+	ioThreadThroughputMetric  = "io_thread_throughput_mib_per_second"
+	ioThreadOperationsMetric  = "io_thread_operations_per_second"
+	ioLatencyPercentileMetric = "io_latency_percentile_milliseconds"
+
+	// reported once per adjustment when filling the disk continuously
+	diskFillFileMetric       = "disk_fill_file_bytes"
+	diskFillAdjustmentMetric = "disk_fill_adjustment_bytes"
+)
+
+// newMetric returns a metric labeled with the execution id, so the metrics of attacks running at the same time can be
+// told apart.
+func newMetric(name string, executionId uuid.UUID, labels map[string]string, timestamp time.Time, value float64) action_kit_api.Metric {
+	metric := map[string]string{"execution_id": executionId.String()}
+	for key, label := range labels {
+		metric[key] = label
+	}
+	return action_kit_api.Metric{
+		Name:      extutil.Ptr(name),
+		Metric:    metric,
+		Timestamp: timestamp,
+		Value:     value,
+	}
+}
+
+// metricsWidget shows a metric as line chart, one chart per value of the label from.
+func metricsWidget(title string, metricName string, from string) action_kit_api.LineChartWidget {
+	return action_kit_api.LineChartWidget{
+		Type:  action_kit_api.ComSteadybitWidgetLineChart,
+		Title: title,
+		Identity: action_kit_api.LineChartWidgetIdentityConfig{
+			MetricName: metricName,
+			From:       from,
+			Mode:       action_kit_api.ComSteadybitWidgetLineChartIdentityModeWidgetPerValue,
+		},
+	}
+}
+
+// memorySampler samples the committed memory of the host, i.e. the memory backed by RAM or the page file.
+type memorySampler interface {
+	committedMemory() (uint64, error)
+}
+
+type systemMemorySampler struct{}
+
+func (systemMemorySampler) committedMemory() (uint64, error) {
+	host, err := sysinfo.Host()
+	if err != nil {
+		return 0, err
+	}
+	memory, err := host.Memory()
+	if err != nil {
+		return 0, err
+	}
+	// on Windows the virtual memory is the commit limit and the used part is the commit charge
+	return memory.VirtualUsed, nil
+}
+
+// diskSampler samples the free space of a drive.
+type diskSampler interface {
+	freeSpace(driveLetter string) (uint64, error)
+}
+
+// driveSpaceSampler queries the file system directly, as it is polled every second.
+type driveSpaceSampler struct{}
+
+func (driveSpaceSampler) freeSpace(driveLetter string) (uint64, error) {
+	space, err := utils.GetVolumeSpace(driveLetter + `:\`)
+	if err != nil {
+		return 0, err
+	}
+	return space.Available, nil
+}
+
+// ioSampler samples the IO of the disk stressed by an IO stress attack while it runs.
+type ioSampler interface {
+	open(opts IoStressOpts) (ioCounters, error)
+}
+
+type ioCounters interface {
+	Sample() (utils.DiskIO, error)
+	Close() error
+}
+
+// diskCounterSampler samples the performance counters of the physical disk or of the partition of the drive letter.
+type diskCounterSampler struct{}
+
+func (diskCounterSampler) open(opts IoStressOpts) (ioCounters, error) {
+	object := utils.LogicalDiskCounters
+	drive := opts.StressLayerInput + ":"
+	if opts.StressLayer == FileSystem {
+		drive = filepath.VolumeName(opts.StressLayerInput)
+	}
+	match := func(instance string) bool {
+		return strings.EqualFold(instance, drive)
+	}
+	if opts.StressLayer == PhysicalDisk {
+		object = utils.PhysicalDiskCounters
+		// instances are named by the disk number followed by the drive letters, e.g. "0 C: D:"
+		match = func(instance string) bool {
+			number, _, _ := strings.Cut(instance, " ")
+			return number == opts.StressLayerInput
+		}
+	}
+
+	counters, err := utils.OpenDiskCounters(object, match)
+	if err != nil {
+		return nil, err
+	}
+	return counters, nil
+}
+
+// diskIOMetrics returns the sampled IO as the metrics diskspd reports when it finished.
+func diskIOMetrics(io utils.DiskIO, executionId uuid.UUID, timestamp time.Time) action_kit_api.Metrics {
+	return action_kit_api.Metrics{
+		newMetric(ioThroughputMetric, executionId, nil, timestamp, io.BytesPerSecond/(1<<20)),
+		newMetric(ioOperationsMetric, executionId, nil, timestamp, io.TransfersPerSecond),
+		newMetric(ioLatencyMetric, executionId, nil, timestamp, io.AvgLatencySeconds*1000),
+	}
+}
+
+// sampledMetrics returns the sampled value as metric. If sampling fails, no metric is returned, as the attack itself
+// isn't affected.
+func sampledMetrics(name string, executionId uuid.UUID, labels map[string]string, sample func() (uint64, error)) *action_kit_api.Metrics {
+	value, err := sample()
+	if err != nil {
+		log.Warn().Err(err).Str("metric", name).Msg("failed to sample metric")
+		return nil
+	}
+	return &action_kit_api.Metrics{newMetric(name, executionId, labels, time.Now(), float64(value))}
+}
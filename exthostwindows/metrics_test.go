@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package exthostwindows
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMemorySampler struct {
+	committed uint64
+	err       error
+}
+
+func (s fakeMemorySampler) committedMemory() (uint64, error) {
+	return s.committed, s.err
+}
+
+type fakeDiskSampler map[string]uint64
+
+func (s fakeDiskSampler) freeSpace(driveLetter string) (uint64, error) {
+	free, ok := s[driveLetter]
+	if !ok {
+		return 0, errors.New("volume not found")
+	}
+	return free, nil
+}
+
+type fakeIOSampler struct {
+	counters *fakeIOCounters
+}
+
+func (s fakeIOSampler) open(IoStressOpts) (ioCounters, error) {
+	return s.counters, nil
+}
+
+type fakeIOCounters struct {
+	io     utils.DiskIO
+	closed bool
+}
+
+func (c *fakeIOCounters) Sample() (utils.DiskIO, error) {
+	return c.io, nil
+}
+
+func (c *fakeIOCounters) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestNewMetric_IsLabeledWithExecutionId(t *testing.T) {
+	executionId := uuid.New()
+	timestamp := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	metric := newMetric(diskFreeMetric, executionId, map[string]string{"drive": "C"}, timestamp, 42)
+
+	assert.Equal(t, diskFreeMetric, *metric.Name)
+	assert.Equal(t, map[string]string{"execution_id": executionId.String(), "drive": "C"}, metric.Metric)
+	assert.Equal(t, timestamp, metric.Timestamp)
+	assert.Equal(t, 42.0, metric.Value)
+}
+
+func TestSampledMetrics(t *testing.T) {
+	executionId := uuid.New()
+
+	metrics := sampledMetrics(committedMemoryMetric, executionId, nil, fakeMemorySampler{committed: 1 << 30}.committedMemory)
+	require.NotNil(t, metrics)
+	require.Len(t, *metrics, 1)
+	assert.Equal(t, float64(1<<30), (*metrics)[0].Value)
+
+	assert.Nil(t, sampledMetrics(committedMemoryMetric, executionId, nil, fakeMemorySampler{err: errors.New("failed")}.committedMemory))
+}
+
+func TestActionFillDisk_StatusReportsFreeSpace(t *testing.T) {
+	action := &fillDiskAction{disk: fakeDiskSampler{"D": 5_000_000_000}}
+	state := &FillDiskActionState{ExecutionId: uuid.New(), StressOpts: FillDiskOpts{Path: `d:\data`}}
+
+	result, err := action.Status(t.Context(), state)
+	require.NoError(t, err)
+	assert.False(t, result.Completed)
+	require.NotNil(t, result.Metrics)
+	require.Len(t, *result.Metrics, 1)
+	metric := (*result.Metrics)[0]
+	assert.Equal(t, diskFreeMetric, *metric.Name)
+	assert.Equal(t, "D", metric.Metric["drive"])
+	assert.Equal(t, 5e9, metric.Value)
+}
+
+func metricNames(metrics []action_kit_api.Metric) []string {
+	names := make([]string, 0, len(metrics))
+	for _, metric := range metrics {
+		names = append(names, *metric.Name)
+	}
+	return names
+}
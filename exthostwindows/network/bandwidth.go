@@ -4,14 +4,20 @@
 package network
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"github.com/steadybit/action-kit/go/action_kit_commons/network"
 	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
 	"net"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
+// belowMinimumRate matches bandwidths the qos policies can't limit to.
+var belowMinimumRate = regexp.MustCompile(`^[0-7]$`)
+
 type LimitBandwidthOpts struct {
 	Bandwidth    string
 	IncludeCidrs []net.IPNet
@@ -22,40 +28,76 @@ func (o *LimitBandwidthOpts) WinDivertCommands(_ Mode) ([]string, error) {
 	return nil, nil
 }
 
-func (o *LimitBandwidthOpts) QoSCommands(mode Mode) ([]string, error) {
-	bandwidth, err := o.parseBandwidth()
+func (o *LimitBandwidthOpts) QoSCommands(mode Mode) ([]utils.PowershellInvocation, error) {
+	bitsPerSecond, err := o.parseBandwidth()
 	if err != nil {
 		return nil, err
 	}
 
-	var cmds []string
+	var invocations []utils.PowershellInvocation
 	for i, includeCidr := range o.IncludeCidrs {
 		if mode == ModeAdd {
-			additionalParameters := ""
+			params := map[string]any{
+				"Name":                            o.policyName(i),
+				"Precedence":                      255,
+				"Confirm":                         false,
+				"ThrottleRateActionBitsPerSecond": bitsPerSecond,
+				"IPDstPrefixMatchCondition":       includeCidr.String(),
+			}
 			if o.PortRange.From != 0 && o.PortRange.To != 0 {
-				additionalParameters = fmt.Sprintf("%s -IPDstPortStartMatchCondition %d -IPDstPortEndMatchCondition %d", additionalParameters, o.PortRange.From, o.PortRange.To)
+				params["IPDstPortStartMatchCondition"] = o.PortRange.From
+				params["IPDstPortEndMatchCondition"] = o.PortRange.To
 			}
-			netQosPolicyCommand := fmt.Sprintf("New-NetQosPolicy -Name %s%s_%d -Precedence 255 -Confirm:$false -ThrottleRateActionBitsPerSecond %s -IPDstPrefixMatchCondition '%s' %s",
-				qosPolicyPrefix, bandwidth, i, bandwidth, includeCidr.String(), additionalParameters)
-			cmds = append(cmds, utils.BuildSystemCommandFor(netQosPolicyCommand)...)
+			invocations = append(invocations, utils.PowershellInvocation{Cmdlet: "New-NetQosPolicy", Params: params})
 		} else {
-			netQosPolicyCommand := fmt.Sprintf("Remove-NetQosPolicy -Name %s%s_%d -Confirm:$false", qosPolicyPrefix, bandwidth, i)
-			cmds = append(cmds, utils.BuildSystemCommandFor(netQosPolicyCommand)...)
+			invocations = append(invocations, utils.PowershellInvocation{Cmdlet: "Remove-NetQosPolicy", Params: map[string]any{"Name": o.policyName(i), "Confirm": false}})
 		}
 	}
-	return cmds, nil
+	return invocations, nil
 }
 
-func (o *LimitBandwidthOpts) parseBandwidth() (string, error) {
-	expression, err := regexp.Compile("^[0-7]$")
-	if err != nil {
-		return "", err
+// policyName returns a name which is unique per attack, so attacks on different targets don't interfere.
+func (o *LimitBandwidthOpts) policyName(i int) string {
+	hash := sha256.Sum256([]byte(o.String()))
+	return fmt.Sprintf("%s%s_%s_%d", qosPolicyPrefix, o.Bandwidth, hex.EncodeToString(hash[:4]), i)
+}
+
+func (o *LimitBandwidthOpts) trafficFilter() Filter {
+	portRange := network.PortRangeAny
+	if o.PortRange.From != 0 && o.PortRange.To != 0 {
+		portRange = o.PortRange
+	}
+	return Filter{
+		Include:   network.NewNetWithPortRanges(o.IncludeCidrs, portRange),
+		Direction: DirectionOutgoing,
+	}
+}
+
+// parseBandwidth returns the bandwidth in bits per second.
+func (o *LimitBandwidthOpts) parseBandwidth() (uint64, error) {
+	if belowMinimumRate.MatchString(o.Bandwidth) {
+		return 0, fmt.Errorf("windows qos policy does not support rate settings below 8bit/s. (%s)", o.Bandwidth)
 	}
-	if expression.MatchString(o.Bandwidth) {
-		return "", fmt.Errorf("windows qos policy does not support rate settings below 8bit/s. (%s)", o.Bandwidth)
+	return ParseBandwidth(o.Bandwidth)
+}
+
+// ParseBandwidth returns the bandwidth in bits per second. Units are interpreted like the numeric multipliers of
+// powershell, e.g. 1KB is 1024 bits, so all backends limit to the same rate.
+func ParseBandwidth(bandwidth string) (uint64, error) {
+	numericBandwidth := bandwidth
+	multiplier := uint64(1)
+	for i, suffix := range []string{"KB", "MB", "GB", "TB"} {
+		if numeric, ok := strings.CutSuffix(numericBandwidth, suffix); ok {
+			numericBandwidth = numeric
+			multiplier = 1 << (10 * (i + 1))
+			break
+		}
+	}
+	numeric, err := strconv.ParseUint(numericBandwidth, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid bandwidth %s: %w", bandwidth, err)
 	}
-	bandwidth := utils.SanitizePowershellArg(o.Bandwidth)
-	return bandwidth, nil
+	return numeric * multiplier, nil
 }
 
 func (o *LimitBandwidthOpts) String() string {
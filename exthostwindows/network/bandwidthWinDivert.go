@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package network
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+)
+
+// minBandwidthBurst is the minimum size of the token bucket in bytes, so that at least one full-sized packet fits.
+const minBandwidthBurst = 1500
+
+// LimitBandwidthWinDivertOpts limits the bandwidth of the traffic matching the filter with a token bucket. Unlike the
+// QoS policies used by LimitBandwidthOpts it also shapes incoming traffic.
+type LimitBandwidthWinDivertOpts struct {
+	Filter
+	// BitsPerSecond is the rate the token bucket is refilled with.
+	BitsPerSecond uint64
+	Duration      time.Duration
+	WinDivertInstance
+}
+
+func (o *LimitBandwidthWinDivertOpts) QoSCommands(_ Mode) ([]utils.PowershellInvocation, error) {
+	return nil, nil
+}
+
+func (o *LimitBandwidthWinDivertOpts) WinDivertCommands(mode Mode) ([]string, error) {
+	var cmds []string
+
+	if mode == ModeAdd {
+		if o.BitsPerSecond == 0 {
+			return nil, errors.New("bandwidth must be greater than 0")
+		}
+		filterFile, err := buildWinDivertFilterFile(o.Filter)
+		if err != nil {
+			return nil, err
+		}
+		o.FilterFile = filterFile
+		start, err := o.startCommand("--mode=bandwidth", o.durationArg(o.Duration), fmt.Sprintf("--rate=%d", o.BitsPerSecond), fmt.Sprintf("--burst=%d", o.burst()))
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, start)
+
+	} else {
+		cmds = append(cmds, o.stopCommands()...)
+		o.removeFilterFile()
+	}
+
+	return cmds, nil
+}
+
+// burst returns the size of the token bucket in bytes. It holds the traffic of 100ms, so the rate is not exceeded by
+// long bursts after idle periods.
+func (o *LimitBandwidthWinDivertOpts) burst() uint64 {
+	return max(o.BitsPerSecond/8/10, minBandwidthBurst)
+}
+
+func (o *LimitBandwidthWinDivertOpts) String() string {
+	var sb strings.Builder
+	sb.WriteString("limit bandwidth to ")
+	sb.WriteString(strconv.FormatUint(o.BitsPerSecond, 10))
+	sb.WriteString("bit/s")
+	o.Filter.writeStringForFilters(&sb)
+	return sb.String()
+}
@@ -4,20 +4,19 @@
 package network
 
 import (
+	"github.com/google/uuid"
 	"github.com/steadybit/action-kit/go/action_kit_commons/network"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"net"
 	"testing"
 )
 
 func Test_LimitBandwidth_create_one_policy_per_ip(t *testing.T) {
-	err := removeSteadybitQosPolicies(t.Context())
+	err := removeSteadybitQosPolicies(t.Context(), execRunner)
 	require.NoError(t, err)
 
-	expectedPolicyName1 := "STEADYBIT_QOS_100MB_0"
 	_, ipNet1, _ := net.ParseCIDR("1.1.1.1/32")
-
-	expectedPolicyName2 := "STEADYBIT_QOS_100MB_1"
 	_, ipNet2, _ := net.ParseCIDR("1.1.1.2/32")
 
 	limitBandwidthOpts := LimitBandwidthOpts{
@@ -30,21 +29,35 @@ func Test_LimitBandwidth_create_one_policy_per_ip(t *testing.T) {
 			To:   9876,
 		},
 	}
+	expectedPolicyName1 := limitBandwidthOpts.policyName(0)
+	expectedPolicyName2 := limitBandwidthOpts.policyName(1)
+	require.Regexp(t, "^STEADYBIT_QOS_100MB_[0-9a-f]{8}_0$", expectedPolicyName1)
 
-	err = Apply(t.Context(), &limitBandwidthOpts)
+	executionId := uuid.New()
+	err = Apply(t.Context(), execRunner, executionId, &limitBandwidthOpts)
 	require.NoError(t, err)
 
 	defer func() {
-		err := Revert(t.Context(), &limitBandwidthOpts)
+		err := Revert(t.Context(), execRunner, executionId, &limitBandwidthOpts)
 		require.NoError(t, err)
-		policies, err := listSteadybitQosPolicyNames(t.Context())
+		policies, err := listSteadybitQosPolicyNames(t.Context(), execRunner)
 		require.NoError(t, err)
 		require.NotContains(t, policies, expectedPolicyName1)
 		require.NotContains(t, policies, expectedPolicyName2)
 	}()
 
-	policies, err := listSteadybitQosPolicyNames(t.Context())
+	policies, err := listSteadybitQosPolicyNames(t.Context(), execRunner)
 	require.NoError(t, err)
 	require.Contains(t, policies, expectedPolicyName1)
 	require.Contains(t, policies, expectedPolicyName2)
 }
+
+func TestParseBandwidth(t *testing.T) {
+	for bandwidth, want := range map[string]uint64{"800": 800, "8KB": 8 * 1024, "1000MB": 1000 * 1024 * 1024, "2GB": 2 << 30, "1TB": 1 << 40} {
+		got, err := ParseBandwidth(bandwidth)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, bandwidth)
+	}
+	_, err := ParseBandwidth("fastMB")
+	assert.EqualError(t, err, `invalid bandwidth fastMB: strconv.ParseUint: parsing "fast": invalid syntax`)
+}
@@ -4,19 +4,19 @@
 package network
 
 import (
-	"fmt"
-	"os"
 	"strings"
 	"time"
+
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
 )
 
 type BlackholeOpts struct {
 	Filter
-	Duration   time.Duration
-	FilterFile string
+	Duration time.Duration
+	WinDivertInstance
 }
 
-func (o *BlackholeOpts) QoSCommands(_ Mode) ([]string, error) {
+func (o *BlackholeOpts) QoSCommands(_ Mode) ([]utils.PowershellInvocation, error) {
 	return nil, nil
 }
 
@@ -31,12 +31,15 @@ func (o *BlackholeOpts) WinDivertCommands(mode Mode) ([]string, error) {
 		o.FilterFile = filterFile
 
 		cmds = append(cmds, "ipconfig /flushdns")
-		cmds = append(cmds, fmt.Sprintf("wdna.exe --file=%q --mode=drop --percentage=100 --duration=%d", filterFile, int(o.Duration.Seconds())))
+		start, err := o.startCommand("--mode=drop", "--percentage=100", o.durationArg(o.Duration))
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, start)
 
 	} else {
-		cmds = append(cmds, "wdna_shutdown")
-		cmds = append(cmds, "cmd /c \"sc stop windivert || exit /b 0\"") // don't fail on error
-		_ = os.Remove(o.FilterFile)
+		cmds = append(cmds, o.stopCommands()...)
+		o.removeFilterFile()
 	}
 
 	return cmds, nil
@@ -5,20 +5,21 @@ package network
 
 import (
 	"fmt"
-	"os"
 	"strings"
 	"time"
+
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
 )
 
 type DelayOpts struct {
 	Filter
-	Delay      time.Duration
-	Duration   time.Duration
-	Jitter     bool
-	FilterFile string
+	Delay    time.Duration
+	Duration time.Duration
+	Jitter   bool
+	WinDivertInstance
 }
 
-func (o *DelayOpts) QoSCommands(_ Mode) ([]string, error) {
+func (o *DelayOpts) QoSCommands(_ Mode) ([]utils.PowershellInvocation, error) {
 	return nil, nil
 }
 
@@ -26,23 +27,25 @@ func (o *DelayOpts) WinDivertCommands(mode Mode) ([]string, error) {
 	var cmds []string
 
 	if mode == ModeAdd {
-		jitter := ""
-		if o.Jitter {
-			jitter = "--jitter"
-		}
-
 		filterFile, err := buildWinDivertFilterFile(o.Filter)
 		if err != nil {
 			return nil, err
 		}
 		o.FilterFile = filterFile
 
-		cmds = append(cmds, fmt.Sprintf("wdna.exe --file=%q --mode=delay --duration=%d --time=%d %s", filterFile, int(o.Duration.Seconds()), o.Delay.Milliseconds(), jitter))
+		args := []string{"--mode=delay", o.durationArg(o.Duration), fmt.Sprintf("--time=%d", o.Delay.Milliseconds())}
+		if o.Jitter {
+			args = append(args, "--jitter")
+		}
+		start, err := o.startCommand(args...)
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, start)
 
 	} else {
-		cmds = append(cmds, "wdna_shutdown")
-		cmds = append(cmds, "cmd /c \"sc stop windivert || exit /b 0\"") // don't fail on error
-		_ = os.Remove(o.FilterFile)
+		cmds = append(cmds, o.stopCommands()...)
+		o.removeFilterFile()
 	}
 
 	return cmds, nil
@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package network
+
+import (
+	"context"
+
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+)
+
+// DryRunResult describes what applying a network attack would do.
+type DryRunResult struct {
+	Attack string `json:"attack"`
+	// WinDivertFilter is the content of the filter file passed to wdna.
+	WinDivertFilter string `json:"winDivertFilter,omitempty"`
+	// WinDivertCommands are the scripts Apply runs. The start script passes the parameters of Start-Process base64
+	// encoded, WinDivertParameters are the decoded ones.
+	WinDivertCommands   []string       `json:"winDivertCommands,omitempty"`
+	WinDivertParameters map[string]any `json:"winDivertParameters,omitempty"`
+	QoSCommands         []string       `json:"qosCommands,omitempty"`
+	// Excludes are the (condensed) excludes protecting restricted endpoints and the extension itself.
+	Excludes []string `json:"excludes,omitempty"`
+}
+
+// DryRun returns the filter and the commands Apply would run for the attack, without running them. The local ports of
+// process scoped attacks are resolved, as they are part of the filter.
+func DryRun(ctx context.Context, runner utils.CommandRunner, opts WinOpts) (*DryRunResult, error) {
+	if _, err := resolveLocalPorts(ctx, runner, opts); err != nil {
+		return nil, err
+	}
+
+	result := &DryRunResult{Attack: opts.String()}
+
+	qosCommands, err := opts.QoSCommands(ModeAdd)
+	if err != nil {
+		return nil, err
+	}
+	for _, invocation := range qosCommands {
+		result.QoSCommands = append(result.QoSCommands, invocation.String())
+	}
+
+	if attack, ok := opts.(filteredAttack); ok {
+		filter := attack.networkFilter()
+		for _, exclude := range filter.Exclude {
+			result.Excludes = append(result.Excludes, exclude.String())
+		}
+		if _, ok := opts.(winDivertAttack); ok {
+			if result.WinDivertFilter, err = buildWinDivertFilter(*filter); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	winDivertCommands, err := opts.WinDivertCommands(ModeAdd)
+	if err != nil {
+		return nil, err
+	}
+	result.WinDivertCommands = winDivertCommands
+	if attack, ok := opts.(winDivertAttack); ok {
+		instance := attack.winDivertInstance()
+		// the filter file is only needed by wdna
+		instance.removeFilterFile()
+		result.WinDivertParameters = instance.start.Params
+	}
+
+	return result, nil
+}
@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package network
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	akn "github.com/steadybit/action-kit/go/action_kit_commons/network"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils/utilstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRun_WinDivert(t *testing.T) {
+	opts := &PackageLossOpts{
+		Filter: Filter{
+			Include:   akn.NewNetWithPortRanges([]net.IPNet{mustParseCIDR(t, "10.0.0.0/24")}, akn.PortRangeAny),
+			Exclude:   akn.NewNetWithPortRanges([]net.IPNet{mustParseCIDR(t, "10.0.0.1/32")}, akn.PortRange{From: 8084, To: 8084}),
+			Direction: DirectionOutgoing,
+		},
+		Loss:     50,
+		Duration: 30 * time.Second,
+	}
+
+	result, err := DryRun(t.Context(), utilstest.NewRecordingRunner(), opts)
+	require.NoError(t, err)
+
+	assert.Equal(t, opts.String(), result.Attack)
+	assert.Equal(t, "(tcp or udp) and outbound and ip.DstAddr >= 10.0.0.0 and ip.DstAddr <= 10.0.0.255 and ((tcp.DstPort >= 1 and tcp.DstPort <= 65534) or (udp.DstPort >= 1 and udp.DstPort <= 65534)) and (ip.DstAddr == 10.0.0.1 ? not (tcp.DstPort == 8084 or udp.DstPort == 8084) : true) and (ip.SrcAddr == 10.0.0.1 ? not (tcp.SrcPort == 8084 or udp.SrcPort == 8084) : true)", result.WinDivertFilter)
+	assert.Equal(t, []string{"10.0.0.1/32 8084"}, result.Excludes)
+	assert.Empty(t, result.QoSCommands)
+	require.Len(t, result.WinDivertCommands, 1)
+	assert.Equal(t, opts.fileArg()+" --mode=drop --duration=30 --percentage=50", wdnaArgs(t, result.WinDivertCommands[0]))
+	assert.Equal(t, []string{opts.fileArg(), "--mode=drop", "--duration=30", "--percentage=50"}, result.WinDivertParameters["ArgumentList"])
+
+	// nothing is left behind
+	_, err = os.Stat(opts.FilterFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDryRun_ReturnsAppliedCommands(t *testing.T) {
+	initTestJournal(t)
+	newAttack := func() *DelayOpts {
+		return &DelayOpts{
+			Filter:   Filter{Include: akn.NewNetWithPortRanges([]net.IPNet{mustParseCIDR(t, "10.0.0.0/24")}, akn.PortRangeAny), Direction: DirectionOutgoing},
+			Delay:    500 * time.Millisecond,
+			Jitter:   true,
+			Duration: 30 * time.Second,
+		}
+	}
+
+	applied := newAttack()
+	executionId := uuid.New()
+	runner := utilstest.NewRecordingRunner().Respond("Start-Process", `[{"Id":4711}]`, nil)
+	// waiting for the WinDivert service fails without Windows, the commands are run nonetheless
+	_ = Apply(t.Context(), runner, executionId, applied)
+	t.Cleanup(func() { _ = Revert(t.Context(), utilstest.NewRecordingRunner(), executionId, applied) })
+	require.Len(t, runner.Commands(), 1)
+
+	dryRun := newAttack()
+	result, err := DryRun(t.Context(), utilstest.NewRecordingRunner(), dryRun)
+	require.NoError(t, err)
+	require.Len(t, result.WinDivertCommands, 1)
+
+	// the commands only differ in the filter file, which is created for each run
+	appliedScript, appliedParams := splitStartCommand(t, runner.Commands()[0])
+	dryRunScript, dryRunParams := splitStartCommand(t, result.WinDivertCommands[0])
+	assert.Equal(t, appliedScript, dryRunScript)
+	require.Equal(t, applied.fileArg(), appliedParams["ArgumentList"].([]any)[0])
+	appliedParams["ArgumentList"].([]any)[0] = dryRun.fileArg()
+	assert.Equal(t, appliedParams, dryRunParams)
+
+	rendered, err := json.Marshal(result.WinDivertParameters)
+	require.NoError(t, err)
+	var returnedParams map[string]any
+	require.NoError(t, json.Unmarshal(rendered, &returnedParams))
+	assert.Equal(t, dryRunParams, returnedParams)
+}
+
+// splitStartCommand returns the start command without the encoded parameters and the decoded parameters.
+func splitStartCommand(t *testing.T, command string) (string, map[string]any) {
+	pattern := regexp.MustCompile(`FromBase64String\('([^']*)'\)`)
+	encoded := pattern.FindStringSubmatch(command)
+	require.NotNil(t, encoded, "not a start command: %s", command)
+	decoded, err := base64.StdEncoding.DecodeString(encoded[1])
+	require.NoError(t, err)
+	var params map[string]any
+	require.NoError(t, json.Unmarshal(decoded, &params))
+	return pattern.ReplaceAllString(command, "FromBase64String('')"), params
+}
+
+func TestDryRun_QoS(t *testing.T) {
+	opts := &LimitBandwidthOpts{
+		Bandwidth:    "100MB",
+		IncludeCidrs: []net.IPNet{mustParseCIDR(t, "10.0.0.1/32")},
+		PortRange:    akn.PortRange{From: 443, To: 443},
+	}
+
+	result, err := DryRun(t.Context(), utilstest.NewRecordingRunner(), opts)
+	require.NoError(t, err)
+
+	assert.Empty(t, result.WinDivertFilter)
+	assert.Empty(t, result.WinDivertCommands)
+	assert.Contains(t, strings.Join(result.QoSCommands, "\n"), "-IPDstPortEndMatchCondition 443 -IPDstPortStartMatchCondition 443 -IPDstPrefixMatchCondition '10.0.0.1/32'")
+}
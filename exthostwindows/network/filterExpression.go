@@ -0,0 +1,394 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package network
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	akn "github.com/steadybit/action-kit/go/action_kit_commons/network"
+)
+
+// filterExpression is a node of a WinDivert filter expression, see https://reqrypt.org/windivert-doc.html#filter_language
+type filterExpression interface {
+	precedence() int
+	writeTo(sb *strings.Builder)
+	validate() error
+}
+
+// operator precedences, nodes with a lower precedence are put in parentheses when nested
+const (
+	precedenceTernary = iota
+	precedenceOr
+	precedenceAnd
+	precedenceNot
+	precedenceAtom
+)
+
+type fieldKind int
+
+const (
+	fieldKindFlag fieldKind = iota
+	fieldKindNumber
+	fieldKindPort
+	fieldKindIPv4
+	fieldKindIPv6
+)
+
+// filterFields lists the WinDivert fields used by the extension.
+var filterFields = map[string]fieldKind{
+	"inbound":      fieldKindFlag,
+	"outbound":     fieldKindFlag,
+	"ip":           fieldKindFlag,
+	"ipv6":         fieldKindFlag,
+	"tcp":          fieldKindFlag,
+	"udp":          fieldKindFlag,
+	"icmp":         fieldKindFlag,
+	"icmpv6":       fieldKindFlag,
+	"ifIdx":        fieldKindNumber,
+	"tcp.SrcPort":  fieldKindPort,
+	"tcp.DstPort":  fieldKindPort,
+	"udp.SrcPort":  fieldKindPort,
+	"udp.DstPort":  fieldKindPort,
+	"ip.SrcAddr":   fieldKindIPv4,
+	"ip.DstAddr":   fieldKindIPv4,
+	"ipv6.SrcAddr": fieldKindIPv6,
+	"ipv6.DstAddr": fieldKindIPv6,
+}
+
+type comparisonOperator string
+
+const (
+	opEqual        comparisonOperator = "=="
+	opNotEqual     comparisonOperator = "!="
+	opLess         comparisonOperator = "<"
+	opLessEqual    comparisonOperator = "<="
+	opGreater      comparisonOperator = ">"
+	opGreaterEqual comparisonOperator = ">="
+)
+
+var comparisonOperators = []comparisonOperator{opEqual, opNotEqual, opLessEqual, opGreaterEqual, opLess, opGreater}
+
+// boolLiteral is the constant true or false.
+type boolLiteral bool
+
+func (e boolLiteral) precedence() int { return precedenceAtom }
+
+func (e boolLiteral) writeTo(sb *strings.Builder) {
+	sb.WriteString(strconv.FormatBool(bool(e)))
+}
+
+func (e boolLiteral) validate() error { return nil }
+
+// flagExpr tests a field without value, e.g. tcp or outbound.
+type flagExpr string
+
+func (e flagExpr) precedence() int { return precedenceAtom }
+
+func (e flagExpr) writeTo(sb *strings.Builder) {
+	sb.WriteString(string(e))
+}
+
+func (e flagExpr) validate() error {
+	kind, ok := filterFields[string(e)]
+	if !ok {
+		return fmt.Errorf("unknown filter field %q", string(e))
+	}
+	if kind != fieldKindFlag {
+		return fmt.Errorf("filter field %q needs to be compared with a value", string(e))
+	}
+	return nil
+}
+
+// comparisonExpr compares a field with a value, e.g. tcp.DstPort == 80.
+type comparisonExpr struct {
+	Field string
+	Op    comparisonOperator
+	Value string
+}
+
+func (e comparisonExpr) precedence() int { return precedenceAtom }
+
+func (e comparisonExpr) writeTo(sb *strings.Builder) {
+	sb.WriteString(e.Field)
+	sb.WriteString(" ")
+	sb.WriteString(string(e.Op))
+	sb.WriteString(" ")
+	sb.WriteString(e.Value)
+}
+
+func (e comparisonExpr) validate() error {
+	kind, ok := filterFields[e.Field]
+	if !ok {
+		return fmt.Errorf("unknown filter field %q", e.Field)
+	}
+
+	switch kind {
+	case fieldKindFlag:
+		return fmt.Errorf("filter field %q can't be compared with a value", e.Field)
+	case fieldKindNumber:
+		if _, err := strconv.ParseUint(e.Value, 10, 32); err != nil {
+			return fmt.Errorf("invalid value %q for filter field %q", e.Value, e.Field)
+		}
+	case fieldKindPort:
+		if _, err := strconv.ParseUint(e.Value, 10, 16); err != nil {
+			return fmt.Errorf("invalid port %q for filter field %q", e.Value, e.Field)
+		}
+	case fieldKindIPv4, fieldKindIPv6:
+		ip := net.ParseIP(e.Value)
+		if ip == nil || (ip.To4() != nil) != (kind == fieldKindIPv4) {
+			return fmt.Errorf("invalid address %q for filter field %q", e.Value, e.Field)
+		}
+	}
+	return nil
+}
+
+// andExpr matches if all operands match. Without operands it matches everything.
+type andExpr []filterExpression
+
+func (e andExpr) precedence() int { return precedenceAnd }
+
+func (e andExpr) writeTo(sb *strings.Builder) {
+	writeOperands(sb, e, " and ", precedenceAnd)
+}
+
+func (e andExpr) validate() error {
+	return validateOperands(e)
+}
+
+// orExpr matches if any operand matches. Without operands it matches nothing.
+type orExpr []filterExpression
+
+func (e orExpr) precedence() int { return precedenceOr }
+
+func (e orExpr) writeTo(sb *strings.Builder) {
+	writeOperands(sb, e, " or ", precedenceOr)
+}
+
+func (e orExpr) validate() error {
+	return validateOperands(e)
+}
+
+// notExpr negates the operand.
+type notExpr struct {
+	Operand filterExpression
+}
+
+func (e notExpr) precedence() int { return precedenceNot }
+
+func (e notExpr) writeTo(sb *strings.Builder) {
+	sb.WriteString("not ")
+	writeOperand(sb, e.Operand, precedenceAtom)
+}
+
+func (e notExpr) validate() error {
+	return e.Operand.validate()
+}
+
+// ternaryExpr matches Then if Condition matches, otherwise Else.
+type ternaryExpr struct {
+	Condition filterExpression
+	Then      filterExpression
+	Else      filterExpression
+}
+
+func (e ternaryExpr) precedence() int { return precedenceTernary }
+
+func (e ternaryExpr) writeTo(sb *strings.Builder) {
+	writeOperand(sb, e.Condition, precedenceOr)
+	sb.WriteString(" ? ")
+	writeOperand(sb, e.Then, precedenceOr)
+	sb.WriteString(" : ")
+	writeOperand(sb, e.Else, precedenceOr)
+}
+
+func (e ternaryExpr) validate() error {
+	return validateOperands([]filterExpression{e.Condition, e.Then, e.Else})
+}
+
+func writeOperands(sb *strings.Builder, operands []filterExpression, separator string, precedence int) {
+	if len(operands) == 0 {
+		// an empty and matches everything, an empty or nothing
+		boolLiteral(precedence == precedenceAnd).writeTo(sb)
+		return
+	}
+	for i, operand := range operands {
+		if i > 0 {
+			sb.WriteString(separator)
+		}
+		// nested and/or are always put in parentheses to keep the filter readable
+		writeOperand(sb, operand, precedenceNot)
+	}
+}
+
+func writeOperand(sb *strings.Builder, operand filterExpression, minPrecedence int) {
+	if operand.precedence() < minPrecedence {
+		sb.WriteString("(")
+		operand.writeTo(sb)
+		sb.WriteString(")")
+	} else {
+		operand.writeTo(sb)
+	}
+}
+
+func validateOperands(operands []filterExpression) error {
+	for _, operand := range operands {
+		if err := operand.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderFilter returns the WinDivert syntax of the expression.
+func renderFilter(expr filterExpression) string {
+	var sb strings.Builder
+	expr.writeTo(&sb)
+	return sb.String()
+}
+
+// simplifyFilter removes redundant clauses from the expression without changing which packets it matches.
+func simplifyFilter(expr filterExpression) filterExpression {
+	switch e := expr.(type) {
+	case andExpr:
+		return simplifyOperands(e, true)
+	case orExpr:
+		return simplifyOperands(e, false)
+	case notExpr:
+		operand := simplifyFilter(e.Operand)
+		switch o := operand.(type) {
+		case boolLiteral:
+			return !o
+		case notExpr:
+			return o.Operand
+		}
+		return notExpr{Operand: operand}
+	case ternaryExpr:
+		condition := simplifyFilter(e.Condition)
+		then := simplifyFilter(e.Then)
+		otherwise := simplifyFilter(e.Else)
+		if c, ok := condition.(boolLiteral); ok {
+			if c {
+				return then
+			}
+			return otherwise
+		}
+		if renderFilter(then) == renderFilter(otherwise) {
+			return then
+		}
+		t, thenIsLiteral := then.(boolLiteral)
+		_, elseIsLiteral := otherwise.(boolLiteral)
+		if thenIsLiteral && elseIsLiteral {
+			// both are different literals, as equal branches were handled above
+			if t {
+				return condition
+			}
+			return simplifyFilter(notExpr{Operand: condition})
+		}
+		return ternaryExpr{Condition: condition, Then: then, Else: otherwise}
+	default:
+		return expr
+	}
+}
+
+// simplifyOperands simplifies an and (isAnd) or an or expression. Nested expressions of the same kind are flattened,
+// neutral literals and duplicates are dropped.
+func simplifyOperands(operands []filterExpression, isAnd bool) filterExpression {
+	var result []filterExpression
+	seen := map[string]bool{}
+
+	var add func(operand filterExpression) bool
+	add = func(operand filterExpression) bool {
+		operand = simplifyFilter(operand)
+		switch o := operand.(type) {
+		case boolLiteral:
+			// returns false if the whole expression is decided by the literal
+			return bool(o) == isAnd
+		case andExpr:
+			if isAnd {
+				for _, nested := range o {
+					if !add(nested) {
+						return false
+					}
+				}
+				return true
+			}
+		case orExpr:
+			if !isAnd {
+				for _, nested := range o {
+					if !add(nested) {
+						return false
+					}
+				}
+				return true
+			}
+		}
+		key := renderFilter(operand)
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, operand)
+		}
+		return true
+	}
+
+	for _, operand := range operands {
+		if !add(operand) {
+			return boolLiteral(!isAnd)
+		}
+	}
+
+	switch len(result) {
+	case 0:
+		return boolLiteral(isAnd)
+	case 1:
+		return result[0]
+	}
+	if isAnd {
+		return andExpr(result)
+	}
+	return orExpr(result)
+}
+
+// ipRangeExpr matches the addresses of the network in the given address field ("SrcAddr" or "DstAddr").
+func ipRangeExpr(addrField string, ipNet net.IPNet) (filterExpression, error) {
+	family, err := getFamily(ipNet)
+	if err != nil {
+		return nil, err
+	}
+	layer := "ip"
+	if family == FamilyV6 {
+		layer = "ipv6"
+	}
+
+	startIp, endIp, err := getStartEndIP(ipNet)
+	if err != nil {
+		return nil, err
+	}
+
+	field := layer + "." + addrField
+	if startIp.Equal(endIp) {
+		return comparisonExpr{Field: field, Op: opEqual, Value: startIp.String()}, nil
+	}
+	if ones, _ := ipNet.Mask.Size(); ones == 0 {
+		// the whole address space, so any packet of the ip version
+		return flagExpr(layer), nil
+	}
+	return andExpr{
+		comparisonExpr{Field: field, Op: opGreaterEqual, Value: startIp.String()},
+		comparisonExpr{Field: field, Op: opLessEqual, Value: endIp.String()},
+	}, nil
+}
+
+// portRangeExpr matches the ports of the range in the given port field, e.g. tcp.DstPort.
+func portRangeExpr(field string, portRange akn.PortRange) filterExpression {
+	if portRange.From == portRange.To {
+		return comparisonExpr{Field: field, Op: opEqual, Value: strconv.Itoa(int(portRange.From))}
+	}
+	return andExpr{
+		comparisonExpr{Field: field, Op: opGreaterEqual, Value: strconv.Itoa(int(portRange.From))},
+		comparisonExpr{Field: field, Op: opLessEqual, Value: strconv.Itoa(int(portRange.To))},
+	}
+}
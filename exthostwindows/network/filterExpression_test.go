@@ -0,0 +1,206 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package network
+
+import (
+	"testing"
+
+	akn "github.com/steadybit/action-kit/go/action_kit_commons/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderFilter(t *testing.T) {
+	tests := []struct {
+		name string
+		expr filterExpression
+		want string
+	}{
+		{
+			name: "flag",
+			expr: flagExpr("tcp"),
+			want: "tcp",
+		},
+		{
+			name: "comparison",
+			expr: comparisonExpr{Field: "tcp.DstPort", Op: opNotEqual, Value: "80"},
+			want: "tcp.DstPort != 80",
+		},
+		{
+			name: "nested and/or are put in parentheses",
+			expr: andExpr{orExpr{flagExpr("tcp"), flagExpr("udp")}, andExpr{flagExpr("ip"), flagExpr("outbound")}},
+			want: "(tcp or udp) and (ip and outbound)",
+		},
+		{
+			name: "not",
+			expr: notExpr{Operand: orExpr{flagExpr("tcp"), flagExpr("udp")}},
+			want: "not (tcp or udp)",
+		},
+		{
+			name: "ternary",
+			expr: andExpr{flagExpr("outbound"), ternaryExpr{Condition: flagExpr("tcp"), Then: comparisonExpr{Field: "tcp.DstPort", Op: opEqual, Value: "80"}, Else: boolLiteral(true)}},
+			want: "outbound and (tcp ? tcp.DstPort == 80 : true)",
+		},
+		{
+			name: "empty and",
+			expr: andExpr{},
+			want: "true",
+		},
+		{
+			name: "empty or",
+			expr: orExpr{},
+			want: "false",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, renderFilter(tt.expr))
+		})
+	}
+}
+
+func TestSimplifyFilter(t *testing.T) {
+	tests := []struct {
+		name string
+		expr filterExpression
+		want string
+	}{
+		{
+			name: "flattens nested and",
+			expr: andExpr{flagExpr("tcp"), andExpr{flagExpr("ip"), andExpr{flagExpr("outbound")}}},
+			want: "tcp and ip and outbound",
+		},
+		{
+			name: "drops duplicates",
+			expr: orExpr{flagExpr("tcp"), flagExpr("udp"), flagExpr("tcp")},
+			want: "tcp or udp",
+		},
+		{
+			name: "drops neutral literals",
+			expr: andExpr{boolLiteral(true), flagExpr("tcp"), orExpr{boolLiteral(false), flagExpr("udp")}},
+			want: "tcp and udp",
+		},
+		{
+			name: "false and",
+			expr: andExpr{flagExpr("tcp"), boolLiteral(false)},
+			want: "false",
+		},
+		{
+			name: "true or",
+			expr: orExpr{flagExpr("tcp"), boolLiteral(true)},
+			want: "true",
+		},
+		{
+			name: "single operand",
+			expr: orExpr{andExpr{flagExpr("tcp")}},
+			want: "tcp",
+		},
+		{
+			name: "double negation",
+			expr: notExpr{Operand: notExpr{Operand: flagExpr("tcp")}},
+			want: "tcp",
+		},
+		{
+			name: "ternary with constant condition",
+			expr: ternaryExpr{Condition: boolLiteral(false), Then: flagExpr("tcp"), Else: flagExpr("udp")},
+			want: "udp",
+		},
+		{
+			name: "ternary with equal branches",
+			expr: ternaryExpr{Condition: flagExpr("ip"), Then: flagExpr("tcp"), Else: flagExpr("tcp")},
+			want: "tcp",
+		},
+		{
+			name: "ternary with literal branches",
+			expr: ternaryExpr{Condition: flagExpr("ip"), Then: boolLiteral(false), Else: boolLiteral(true)},
+			want: "not ip",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, renderFilter(simplifyFilter(tt.expr)))
+		})
+	}
+}
+
+func TestValidateFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    filterExpression
+		wantErr string
+	}{
+		{
+			name: "valid",
+			expr: andExpr{flagExpr("tcp"), comparisonExpr{Field: "ipv6.DstAddr", Op: opEqual, Value: "::1"}},
+		},
+		{
+			name:    "unknown field",
+			expr:    flagExpr("tcp.Foo"),
+			wantErr: "unknown filter field",
+		},
+		{
+			name:    "flag with value",
+			expr:    comparisonExpr{Field: "tcp", Op: opEqual, Value: "1"},
+			wantErr: "can't be compared with a value",
+		},
+		{
+			name:    "field without value",
+			expr:    flagExpr("tcp.DstPort"),
+			wantErr: "needs to be compared with a value",
+		},
+		{
+			name:    "port out of range",
+			expr:    comparisonExpr{Field: "tcp.DstPort", Op: opEqual, Value: "65536"},
+			wantErr: "invalid port",
+		},
+		{
+			name:    "ipv6 address in ipv4 field",
+			expr:    orExpr{comparisonExpr{Field: "ip.DstAddr", Op: opEqual, Value: "::1"}},
+			wantErr: "invalid address",
+		},
+		{
+			name:    "nested in ternary",
+			expr:    ternaryExpr{Condition: flagExpr("ip"), Then: flagExpr("bogus"), Else: boolLiteral(true)},
+			wantErr: "unknown filter field",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.expr.validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIpRangeExpr(t *testing.T) {
+	tests := []struct {
+		cidr string
+		want string
+	}{
+		{cidr: "1.1.1.1/32", want: "ip.DstAddr == 1.1.1.1"},
+		{cidr: "1.1.1.1/24", want: "ip.DstAddr >= 1.1.1.0 and ip.DstAddr <= 1.1.1.255"},
+		{cidr: "0.0.0.0/0", want: "ip"},
+		{cidr: "fd00::/8", want: "ipv6.DstAddr >= fd00:: and ipv6.DstAddr <= fdff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"},
+		{cidr: "::/0", want: "ipv6"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cidr, func(t *testing.T) {
+			ipNet, err := akn.ParseCIDR(tt.cidr)
+			require.NoError(t, err)
+			expr, err := ipRangeExpr("DstAddr", *ipNet)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, renderFilter(expr))
+			assert.NoError(t, expr.validate())
+		})
+	}
+}
+
+func TestPortRangeExpr(t *testing.T) {
+	assert.Equal(t, "udp.SrcPort == 53", renderFilter(portRangeExpr("udp.SrcPort", akn.PortRange{From: 53, To: 53})))
+	assert.Equal(t, "udp.SrcPort >= 8000 and udp.SrcPort <= 8002", renderFilter(portRangeExpr("udp.SrcPort", akn.PortRange{From: 8000, To: 8002})))
+}
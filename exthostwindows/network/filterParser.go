@@ -0,0 +1,204 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package network
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"unicode"
+)
+
+// parseFilter parses a WinDivert filter into an expression and validates it.
+func parseFilter(filter string) (filterExpression, error) {
+	p := &filterParser{input: filter}
+	expr, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	p.skipWhitespace()
+	if p.pos < len(p.input) {
+		return nil, p.errorf("unexpected %q", p.input[p.pos:])
+	}
+	if err := expr.validate(); err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+type filterParser struct {
+	input string
+	pos   int
+}
+
+func (p *filterParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("invalid filter at position %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *filterParser) skipWhitespace() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+// consume skips the token if it is next in the input.
+func (p *filterParser) consume(token string) bool {
+	p.skipWhitespace()
+	if !strings.HasPrefix(p.input[p.pos:], token) {
+		return false
+	}
+	// keywords must not be followed by further identifier characters
+	end := p.pos + len(token)
+	if isIdentifierChar(token[len(token)-1]) && end < len(p.input) && isIdentifierChar(p.input[end]) {
+		return false
+	}
+	p.pos = end
+	return true
+}
+
+func (p *filterParser) parseTernary() (filterExpression, error) {
+	condition, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.consume("?") {
+		return condition, nil
+	}
+	then, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if !p.consume(":") {
+		return nil, p.errorf("expected ':'")
+	}
+	otherwise, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	return ternaryExpr{Condition: condition, Then: then, Else: otherwise}, nil
+}
+
+func (p *filterParser) parseOr() (filterExpression, error) {
+	var operands orExpr
+	for {
+		operand, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+		if !p.consume("or") && !p.consume("||") {
+			break
+		}
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return operands, nil
+}
+
+func (p *filterParser) parseAnd() (filterExpression, error) {
+	var operands andExpr
+	for {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+		if !p.consume("and") && !p.consume("&&") {
+			break
+		}
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return operands, nil
+}
+
+func (p *filterParser) parseNot() (filterExpression, error) {
+	if p.consume("not") || p.consumeNegation() {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{Operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+// consumeNegation consumes a '!' which is not part of the '!=' operator.
+func (p *filterParser) consumeNegation() bool {
+	p.skipWhitespace()
+	if strings.HasPrefix(p.input[p.pos:], "!") && !strings.HasPrefix(p.input[p.pos:], "!=") {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parsePrimary() (filterExpression, error) {
+	if p.consume("(") {
+		expr, err := p.parseTernary()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, p.errorf("expected ')'")
+		}
+		return expr, nil
+	}
+	if p.consume("true") {
+		return boolLiteral(true), nil
+	}
+	if p.consume("false") {
+		return boolLiteral(false), nil
+	}
+
+	field := p.readIdentifier()
+	if field == "" {
+		if p.pos >= len(p.input) {
+			return nil, p.errorf("unexpected end of filter")
+		}
+		return nil, p.errorf("unexpected %q", p.input[p.pos:])
+	}
+
+	for _, op := range comparisonOperators {
+		if p.consume(string(op)) {
+			value := p.readValue()
+			if value == "" {
+				return nil, p.errorf("expected value for %q", field)
+			}
+			return comparisonExpr{Field: field, Op: op, Value: value}, nil
+		}
+	}
+	return flagExpr(field), nil
+}
+
+func (p *filterParser) readIdentifier() string {
+	p.skipWhitespace()
+	start := p.pos
+	for p.pos < len(p.input) && (isIdentifierChar(p.input[p.pos]) || p.input[p.pos] == '.') {
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+// readValue reads a number or an address. As IPv6 addresses contain colons, a trailing colon is only part of the value
+// if the value is a valid address with it.
+func (p *filterParser) readValue() string {
+	p.skipWhitespace()
+	start := p.pos
+	for p.pos < len(p.input) && (isIdentifierChar(p.input[p.pos]) || p.input[p.pos] == '.' || p.input[p.pos] == ':') {
+		p.pos++
+	}
+	value := p.input[start:p.pos]
+	for strings.HasSuffix(value, ":") && net.ParseIP(value) == nil {
+		value = value[:len(value)-1]
+		p.pos--
+	}
+	return value
+}
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
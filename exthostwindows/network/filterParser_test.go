@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package network
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		want   filterExpression
+	}{
+		{
+			name:   "flag",
+			filter: "tcp",
+			want:   flagExpr("tcp"),
+		},
+		{
+			name:   "precedence of and over or",
+			filter: "tcp and outbound or udp",
+			want:   orExpr{andExpr{flagExpr("tcp"), flagExpr("outbound")}, flagExpr("udp")},
+		},
+		{
+			name:   "parentheses",
+			filter: "tcp and (outbound or udp)",
+			want:   andExpr{flagExpr("tcp"), orExpr{flagExpr("outbound"), flagExpr("udp")}},
+		},
+		{
+			name:   "c style operators",
+			filter: "!tcp && udp.DstPort!=53 || false",
+			want:   orExpr{andExpr{notExpr{Operand: flagExpr("tcp")}, comparisonExpr{Field: "udp.DstPort", Op: opNotEqual, Value: "53"}}, boolLiteral(false)},
+		},
+		{
+			name:   "ternary",
+			filter: "ip.DstAddr == 1.1.1.1 ? not tcp : true",
+			want:   ternaryExpr{Condition: comparisonExpr{Field: "ip.DstAddr", Op: opEqual, Value: "1.1.1.1"}, Then: notExpr{Operand: flagExpr("tcp")}, Else: boolLiteral(true)},
+		},
+		{
+			name:   "ipv6 address before ternary colon",
+			filter: "ipv6 ? ipv6.DstAddr >= ::1: false",
+			want:   ternaryExpr{Condition: flagExpr("ipv6"), Then: comparisonExpr{Field: "ipv6.DstAddr", Op: opGreaterEqual, Value: "::1"}, Else: boolLiteral(false)},
+		},
+		{
+			name:   "ipv4 address before ternary colon",
+			filter: "(ip.DstAddr <= 1.1.1.0)? tcp: udp",
+			want:   ternaryExpr{Condition: comparisonExpr{Field: "ip.DstAddr", Op: opLessEqual, Value: "1.1.1.0"}, Then: flagExpr("tcp"), Else: flagExpr("udp")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := parseFilter(tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, expr)
+		})
+	}
+}
+
+func TestParseFilter_Invalid(t *testing.T) {
+	tests := []struct {
+		filter  string
+		wantErr string
+	}{
+		{filter: "", wantErr: "unexpected end of filter"},
+		{filter: "tcp and", wantErr: "unexpected end of filter"},
+		{filter: "(tcp or udp", wantErr: "expected ')'"},
+		{filter: "tcp ? udp", wantErr: "expected ':'"},
+		{filter: "tcp.DstPort ==", wantErr: "expected value"},
+		{filter: "tcp udp", wantErr: "unexpected \"udp\""},
+		{filter: "tcp.DstPort == 80000", wantErr: "invalid port"},
+		{filter: "outbound and icmpv7", wantErr: "unknown filter field"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			_, err := parseFilter(tt.filter)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
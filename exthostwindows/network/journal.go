@@ -0,0 +1,228 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+)
+
+// The journal keeps track of applied network attacks on disk, so they can be reverted after the extension crashed.
+var (
+	journalLock = sync.Mutex{}
+	journalDir  = ""
+)
+
+var journalKinds = map[string]func() WinOpts{
+	"blackhole":               func() WinOpts { return &BlackholeOpts{} },
+	"delay":                   func() WinOpts { return &DelayOpts{} },
+	"packageLoss":             func() WinOpts { return &PackageLossOpts{} },
+	"packageCorruption":       func() WinOpts { return &CorruptPackagesOpts{} },
+	"packageDuplication":      func() WinOpts { return &DuplicateOpts{} },
+	"packageReordering":       func() WinOpts { return &ReorderOpts{} },
+	"tcpReset":                func() WinOpts { return &TcpResetOpts{} },
+	"limitBandwidth":          func() WinOpts { return &LimitBandwidthOpts{} },
+	"limitBandwidthWinDivert": func() WinOpts { return &LimitBandwidthWinDivertOpts{} },
+}
+
+type journalEntry struct {
+	ExecutionId uuid.UUID       `json:"executionId"`
+	Kind        string          `json:"kind"`
+	Opts        json.RawMessage `json:"opts"`
+	AppliedAt   time.Time       `json:"appliedAt"`
+}
+
+// journaledAttack is an attack read from the journal together with the execution which applied it.
+type journaledAttack struct {
+	executionId uuid.UUID
+	opts        WinOpts
+}
+
+// InitJournal enables the attack journal in the given directory.
+func InitJournal(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create journal directory: %w", err)
+	}
+	journalLock.Lock()
+	defer journalLock.Unlock()
+	journalDir = dir
+	return nil
+}
+
+// RevertJournaledAttacks reverts all attacks which are still recorded in the journal.
+// Such leftovers exist if the extension was terminated while an attack was active.
+func RevertJournaledAttacks(ctx context.Context, runner utils.CommandRunner) error {
+	entries, err := readJournalEntries()
+	if err != nil {
+		return err
+	}
+
+	var errs error
+	for _, entry := range entries {
+		log.Warn().Str("attack", entry.opts.String()).Str("executionId", entry.executionId.String()).Msg("Found leftover network attack in journal, reverting it")
+		if err := Revert(ctx, runner, entry.executionId, entry.opts); err != nil {
+			errs = errors.Join(errs, err)
+		}
+	}
+	if errs != nil {
+		return fmt.Errorf("failed to revert journaled network attacks: %w", errs)
+	}
+	return nil
+}
+
+func writeJournalEntry(executionId uuid.UUID, opts WinOpts) error {
+	journalLock.Lock()
+	defer journalLock.Unlock()
+	if journalDir == "" {
+		return nil
+	}
+
+	kind, ok := journalKindOf(opts)
+	if !ok {
+		log.Debug().Str("type", fmt.Sprintf("%T", opts)).Msg("network attack type is not journaled")
+		return nil
+	}
+
+	rawOpts, err := json.Marshal(opts)
+	if err != nil {
+		return fmt.Errorf("failed to serialize network attack for the journal: %w", err)
+	}
+
+	content, err := json.Marshal(journalEntry{ExecutionId: executionId, Kind: kind, Opts: rawOpts, AppliedAt: time.Now()})
+	if err != nil {
+		return fmt.Errorf("failed to serialize journal entry: %w", err)
+	}
+
+	// write to a temporary file first, so a crash never leaves a partially written entry behind
+	tmpFile, err := os.CreateTemp(journalDir, "entry-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write journal entry: %w", err)
+	}
+	_, err = tmpFile.Write(content)
+	err = errors.Join(err, tmpFile.Close())
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), journalEntryPath(kind, executionId))
+	}
+	if err != nil {
+		_ = os.Remove(tmpFile.Name())
+		return fmt.Errorf("failed to write journal entry: %w", err)
+	}
+	return nil
+}
+
+// readJournalEntry returns the journaled version of the attack applied by the execution. It contains state which was
+// only known when the attack was applied (e.g. the filter file). Returns nil if the attack is not journaled.
+func readJournalEntry(executionId uuid.UUID, opts WinOpts) WinOpts {
+	journalLock.Lock()
+	defer journalLock.Unlock()
+	if journalDir == "" {
+		return nil
+	}
+
+	kind, ok := journalKindOf(opts)
+	if !ok {
+		return nil
+	}
+
+	journaled, err := readJournalFile(journalEntryPath(kind, executionId))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Warn().Err(err).Msg("failed to read journal entry")
+		}
+		return nil
+	}
+	return journaled.opts
+}
+
+func removeJournalEntry(executionId uuid.UUID, opts WinOpts) {
+	journalLock.Lock()
+	defer journalLock.Unlock()
+	if journalDir == "" {
+		return
+	}
+
+	kind, ok := journalKindOf(opts)
+	if !ok {
+		return
+	}
+
+	if err := os.Remove(journalEntryPath(kind, executionId)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warn().Err(err).Msg("failed to remove journal entry")
+	}
+}
+
+func readJournalEntries() ([]journaledAttack, error) {
+	journalLock.Lock()
+	defer journalLock.Unlock()
+	if journalDir == "" {
+		return nil, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(journalDir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list journal entries: %w", err)
+	}
+
+	var entries []journaledAttack
+	for _, file := range files {
+		entry, err := readJournalFile(file)
+		if err != nil {
+			log.Error().Err(err).Str("file", file).Msg("Removing unreadable journal entry")
+			_ = os.Remove(file)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func readJournalFile(file string) (journaledAttack, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return journaledAttack{}, err
+	}
+
+	var entry journalEntry
+	if err := json.Unmarshal(content, &entry); err != nil {
+		return journaledAttack{}, fmt.Errorf("invalid journal entry: %w", err)
+	}
+
+	newOpts, ok := journalKinds[entry.Kind]
+	if !ok {
+		return journaledAttack{}, fmt.Errorf("unknown network attack kind %q", entry.Kind)
+	}
+
+	opts := newOpts()
+	if err := json.Unmarshal(entry.Opts, opts); err != nil {
+		return journaledAttack{}, fmt.Errorf("invalid network attack in journal entry: %w", err)
+	}
+	return journaledAttack{executionId: entry.ExecutionId, opts: opts}, nil
+}
+
+func journalKindOf(opts WinOpts) (string, bool) {
+	for kind, newOpts := range journalKinds {
+		if reflect.TypeOf(newOpts()) == reflect.TypeOf(opts) {
+			return kind, true
+		}
+	}
+	return "", false
+}
+
+// journalEntryPath returns the file of the attack applied by the execution. Each execution has its own entry, so
+// entries of identical attacks never overwrite each other.
+func journalEntryPath(kind string, executionId uuid.UUID) string {
+	return filepath.Join(journalDir, fmt.Sprintf("%s-%s.json", strings.ToLower(kind), executionId))
+}
@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package network
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	akn "github.com/steadybit/action-kit/go/action_kit_commons/network"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils/utilstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func initTestJournal(t *testing.T) string {
+	dir := t.TempDir()
+	require.NoError(t, InitJournal(dir))
+	t.Cleanup(func() {
+		journalDir = ""
+	})
+	return dir
+}
+
+func TestJournal_ReadReturnsAppliedState(t *testing.T) {
+	initTestJournal(t)
+
+	applied := &DelayOpts{
+		Filter:            Filter{Include: akn.NewNetWithPortRanges(akn.NetAny, akn.PortRangeAny), Direction: DirectionOutgoing},
+		Delay:             500 * time.Millisecond,
+		Duration:          30 * time.Second,
+		WinDivertInstance: WinDivertInstance{FilterFile: "C:\\Temp\\wdna-filter-1.txt", Pid: 4711},
+	}
+	executionId := uuid.New()
+	require.NoError(t, writeJournalEntry(executionId, applied))
+
+	fromState := *applied
+	fromState.WinDivertInstance = WinDivertInstance{}
+	journaled := readJournalEntry(executionId, &fromState)
+	require.NotNil(t, journaled)
+	assert.Equal(t, applied, journaled)
+	assert.Nil(t, readJournalEntry(uuid.New(), &fromState))
+
+	removeJournalEntry(executionId, &fromState)
+	assert.Nil(t, readJournalEntry(executionId, &fromState))
+}
+
+func TestJournal_KeepsAnEntryPerExecution(t *testing.T) {
+	initTestJournal(t)
+
+	newAttack := func(pid int, filterFile string) *DelayOpts {
+		return &DelayOpts{Delay: time.Second, WinDivertInstance: WinDivertInstance{FilterFile: filterFile, Pid: pid}}
+	}
+	first, second := uuid.New(), uuid.New()
+	require.NoError(t, writeJournalEntry(first, newAttack(4711, "wdna-filter-1.txt")))
+	require.NoError(t, writeJournalEntry(second, newAttack(4712, "wdna-filter-2.txt")))
+
+	assert.Equal(t, newAttack(4711, "wdna-filter-1.txt"), readJournalEntry(first, newAttack(0, "")))
+	assert.Equal(t, newAttack(4712, "wdna-filter-2.txt"), readJournalEntry(second, newAttack(0, "")))
+	entries, err := readJournalEntries()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []journaledAttack{
+		{executionId: first, opts: newAttack(4711, "wdna-filter-1.txt")},
+		{executionId: second, opts: newAttack(4712, "wdna-filter-2.txt")},
+	}, entries)
+}
+
+func TestJournal_DisabledWithoutDirectory(t *testing.T) {
+	executionId := uuid.New()
+	require.NoError(t, writeJournalEntry(executionId, &BlackholeOpts{Duration: time.Second}))
+	assert.Nil(t, readJournalEntry(executionId, &BlackholeOpts{Duration: time.Second}))
+
+	entries, err := readJournalEntries()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestJournal_SkipsUnknownAttackTypes(t *testing.T) {
+	dir := initTestJournal(t)
+
+	require.NoError(t, writeJournalEntry(uuid.New(), &MockNetworkOpt{}))
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestJournal_RemovesUnreadableEntries(t *testing.T) {
+	dir := initTestJournal(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0644))
+
+	entries, err := readJournalEntries()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	assert.NoFileExists(t, filepath.Join(dir, "broken.json"))
+}
+
+func TestRevertJournaledAttacks(t *testing.T) {
+	dir := initTestJournal(t)
+	journalKinds["mock"] = func() WinOpts { return &MockNetworkOpt{} }
+	defer delete(journalKinds, "mock")
+
+	err := generateAndRunCommands(t.Context(), utilstest.NewRecordingRunner(), uuid.New(), &MockNetworkOpt{}, ModeAdd)
+	require.NoError(t, err)
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	// simulate a restart of the extension
+	popActiveFw("windows", &MockNetworkOpt{})
+
+	err = RevertJournaledAttacks(t.Context(), utilstest.NewRecordingRunner())
+	require.NoError(t, err)
+
+	files, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
@@ -6,13 +6,14 @@ package network
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 	aku "github.com/steadybit/action-kit/go/action_kit_commons/utils"
 	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
-	"golang.org/x/sys/windows/svc"
 )
 
 var (
@@ -22,26 +23,43 @@ var (
 	activeFirewall = map[string][]WinOpts{}
 )
 
-func Apply(ctx context.Context, opts WinOpts) error {
-	return generateAndRunCommands(ctx, opts, ModeAdd)
+// Apply applies the attack of the execution. The execution id identifies the attack in the journal.
+func Apply(ctx context.Context, runner utils.CommandRunner, executionId uuid.UUID, opts WinOpts) error {
+	return generateAndRunCommands(ctx, runner, executionId, opts, ModeAdd)
 }
 
-func Revert(ctx context.Context, opts WinOpts) error {
-	return generateAndRunCommands(ctx, opts, ModeDelete)
+// Revert reverts the attack applied by the execution.
+func Revert(ctx context.Context, runner utils.CommandRunner, executionId uuid.UUID, opts WinOpts) error {
+	return generateAndRunCommands(ctx, runner, executionId, opts, ModeDelete)
 }
 
-func CleanupQosPolicies() {
+func CleanupQosPolicies(runner utils.CommandRunner) {
 	runLock.LockKey("windows")
 	defer func() { _ = runLock.UnlockKey("windows") }()
 	if !activeFw() {
-		err := removeSteadybitQosPolicies(context.Background())
+		err := removeSteadybitQosPolicies(context.Background(), runner)
 		if err != nil {
 			log.Error().Err(err).Msg("Error removing Steadybit QoS policies")
 		}
 	}
 }
 
-func generateAndRunCommands(ctx context.Context, opts WinOpts, mode Mode) error {
+func generateAndRunCommands(ctx context.Context, runner utils.CommandRunner, executionId uuid.UUID, opts WinOpts, mode Mode) error {
+	runLock.LockKey("windows")
+	defer func() { _ = runLock.UnlockKey("windows") }()
+
+	if mode == ModeDelete {
+		// the applied attack also knows about state created while applying it, e.g. the filter file or the wdna process
+		if applied := findActiveFw("windows", opts); applied != nil {
+			opts = applied
+			stopLocalPortsRefresh(applied)
+		} else if journaled := readJournalEntry(executionId, opts); journaled != nil {
+			opts = journaled
+		}
+	} else if _, err := resolveLocalPorts(ctx, runner, opts); err != nil {
+		return err
+	}
+
 	qosCommands, err := opts.QoSCommands(mode)
 	if err != nil {
 		return err
@@ -52,29 +70,42 @@ func generateAndRunCommands(ctx context.Context, opts WinOpts, mode Mode) error
 		return err
 	}
 
-	runLock.LockKey("windows")
-	defer func() { _ = runLock.UnlockKey("windows") }()
-
 	if mode == ModeAdd {
 		if err := pushActiveFw(opts); err != nil {
 			return err
 		}
+		if err := writeJournalEntry(executionId, opts); err != nil {
+			popActiveFw("windows", opts)
+			return err
+		}
 	}
 
 	if len(qosCommands) > 0 {
-		if _, qosErr := executeQoSCommands(ctx, qosCommands); qosErr != nil {
+		if qosErr := executeQoSCommands(ctx, runner, qosCommands, mode); qosErr != nil {
 			err = errors.Join(err, qosErr)
 		}
 	}
 
 	if len(winDivertCommands) > 0 {
-		if _, wdErr := executeWinDivertCommands(ctx, winDivertCommands, mode); wdErr != nil {
+		if wdErr := executeWinDivertCommands(ctx, runner, executionId, opts, winDivertCommands, mode); wdErr != nil {
 			err = errors.Join(err, wdErr)
 		}
 	}
 
+	if mode == ModeAdd && err == nil {
+		startLocalPortsRefresh(runner, executionId, opts)
+	}
+
 	if mode == ModeDelete {
 		popActiveFw("windows", opts)
+		if _, ok := opts.(winDivertAttack); ok && !hasActiveWinDivert("windows") {
+			if wdErr := stopWinDivertService(ctx, runner); wdErr != nil {
+				err = errors.Join(err, wdErr)
+			}
+		}
+		if err == nil {
+			removeJournalEntry(executionId, opts)
+		}
 	}
 
 	return err
@@ -91,8 +122,9 @@ func pushActiveFw(opts WinOpts) error {
 	defer activeFWLock.Unlock()
 
 	for _, active := range activeFirewall["windows"] {
-		if opts.String() != active.String() {
-			return errors.New("running multiple network attacks at the same time is not supported")
+		// identical attacks overlap as well, each execution needs its own filter and wdna instance
+		if attacksOverlap(opts, active) {
+			return fmt.Errorf("network attack overlaps with an already running attack: %s", active.String())
 		}
 	}
 
@@ -100,6 +132,30 @@ func pushActiveFw(opts WinOpts) error {
 	return nil
 }
 
+func findActiveFw(id string, opts WinOpts) WinOpts {
+	activeFWLock.Lock()
+	defer activeFWLock.Unlock()
+
+	for _, a := range activeFirewall[id] {
+		if opts.String() == a.String() {
+			return a
+		}
+	}
+	return nil
+}
+
+func hasActiveWinDivert(id string) bool {
+	activeFWLock.Lock()
+	defer activeFWLock.Unlock()
+
+	for _, a := range activeFirewall[id] {
+		if _, ok := a.(winDivertAttack); ok {
+			return true
+		}
+	}
+	return false
+}
+
 func popActiveFw(id string, opts WinOpts) {
 	activeFWLock.Lock()
 	defer activeFWLock.Unlock()
@@ -116,23 +172,56 @@ func popActiveFw(id string, opts WinOpts) {
 	}
 }
 
-func executeWinDivertCommands(ctx context.Context, cmds []string, mode Mode) (string, error) {
-	out, err := utils.ExecutePowershellCommand(ctx, utils.SanitizePowershellArgs(cmds...), utils.PSStart)
-	if err == nil {
-		timeout := 15 * time.Second
-		if mode == ModeAdd {
-			err = awaitWinDivertServiceStatus(svc.Running, timeout)
-			log.Debug().Msgf("WinDivert service is running")
-		} else {
-			err = awaitWinDivertServiceStatus(svc.Stopped, timeout)
-			log.Debug().Msgf("WinDivert service is stopped")
+func executeWinDivertCommands(ctx context.Context, runner utils.CommandRunner, executionId uuid.UUID, opts WinOpts, cmds []string, mode Mode) error {
+	// wdna gets its arguments as parameters of the start invocation, the stop commands only contain process ids
+	out, err := runner.RunPowershell(ctx, cmds, utils.PSRun)
+	if err != nil || mode != ModeAdd {
+		return err
+	}
+
+	if attack, ok := opts.(winDivertAttack); ok {
+		pid, err := parseWdnaPid(out)
+		if err != nil {
+			return err
+		}
+		instance := attack.winDivertInstance()
+		instance.Pid = pid
+		if instance.StartedAt.IsZero() {
+			instance.StartedAt = time.Now()
+		}
+		if err := writeJournalEntry(executionId, opts); err != nil {
+			log.Warn().Err(err).Msg("failed to record wdna process id in the journal")
 		}
 	}
-	return out, err
+
+	if err := awaitWinDivertServiceRunning(15 * time.Second); err != nil {
+		return err
+	}
+	log.Debug().Msgf("WinDivert service is running")
+	return nil
 }
 
-func executeQoSCommands(ctx context.Context, cmds []string) (string, error) {
-	logCurrentQoSRules(ctx, "before")
-	defer logCurrentQoSRules(ctx, "after")
-	return utils.ExecutePowershellCommand(ctx, cmds, utils.PSRun)
+func stopWinDivertService(ctx context.Context, runner utils.CommandRunner) error {
+	if _, err := runner.RunPowershell(ctx, []string{stopWinDivertServiceCommand}, utils.PSRun); err != nil {
+		return err
+	}
+	if err := awaitWinDivertServiceStopped(15 * time.Second); err != nil {
+		return err
+	}
+	log.Debug().Msgf("WinDivert service is stopped")
+	return nil
+}
+
+func executeQoSCommands(ctx context.Context, runner utils.CommandRunner, invocations []utils.PowershellInvocation, mode Mode) error {
+	logCurrentQoSRules(ctx, runner, "before")
+	defer logCurrentQoSRules(ctx, runner, "after")
+	var errs error
+	for _, invocation := range invocations {
+		errs = errors.Join(errs, runQosInvocation(ctx, runner, invocation))
+	}
+	if errs == nil && mode == ModeAdd {
+		// a policy may not be created although the cmdlet succeeded, e.g. if it conflicts with an existing one
+		errs = verifyQosPolicies(ctx, runner, invocations)
+	}
+	return errs
 }
@@ -4,45 +4,210 @@
 package network
 
 import (
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	akn "github.com/steadybit/action-kit/go/action_kit_commons/network"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils/utilstest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"testing"
 )
 
+// execRunner runs the commands of tests which need a Windows host.
+var execRunner = utils.NewCommandRunner()
+
 func TestCleanupQosPolicies(t *testing.T) {
 	// Cleanup works if no leftover policies exist
-	CleanupQosPolicies()
+	CleanupQosPolicies(execRunner)
 
 	// Cleanup does not run if experiment is active
 	createQosPolicy(t, qosPolicyPrefix+"test_policy_cleanup")
 	opts := &MockNetworkOpt{}
-	err := generateAndRunCommands(t.Context(), opts, ModeAdd)
+	executionId := uuid.New()
+	err := generateAndRunCommands(t.Context(), execRunner, executionId, opts, ModeAdd)
 	require.NoError(t, err)
 
-	CleanupQosPolicies()
+	CleanupQosPolicies(execRunner)
 
-	policies, err := listSteadybitQosPolicyNames(t.Context())
+	policies, err := listSteadybitQosPolicyNames(t.Context(), execRunner)
 	require.NoError(t, err)
 	assert.NotEmpty(t, policies)
 
 	// Cleanup removes leftover policies after the experiment has finished
-	err = generateAndRunCommands(t.Context(), opts, ModeDelete)
+	err = generateAndRunCommands(t.Context(), execRunner, executionId, opts, ModeDelete)
 	require.NoError(t, err)
 
-	CleanupQosPolicies()
+	CleanupQosPolicies(execRunner)
 
-	policies, err = listSteadybitQosPolicyNames(t.Context())
+	policies, err = listSteadybitQosPolicyNames(t.Context(), execRunner)
 	require.NoError(t, err)
 	assert.Empty(t, policies)
 }
 
+func TestApplyAndRevert_RunCommands(t *testing.T) {
+	initTestJournal(t)
+	opts := &LimitBandwidthOpts{Bandwidth: "1MB", IncludeCidrs: []net.IPNet{mustParseCIDR(t, "10.0.0.0/24")}}
+	runner := utilstest.NewRecordingRunner().
+		Respond("Get-NetQosPolicy", qosPoliciesJson(t, qosPolicy{Name: opts.policyName(0), ThrottleRateAction: 1 << 20}), nil)
+
+	executionId := uuid.New()
+	require.NoError(t, Apply(t.Context(), runner, executionId, opts))
+	require.NoError(t, Revert(t.Context(), runner, executionId, opts))
+
+	addCommands, err := opts.QoSCommands(ModeAdd)
+	require.NoError(t, err)
+	deleteCommands, err := opts.QoSCommands(ModeDelete)
+	require.NoError(t, err)
+	// the current QoS policies are only listed for trace logging
+	commands := slices.DeleteFunc(runner.Commands(), func(command string) bool { return strings.Contains(command, "Get-NetQosPolicy") })
+	require.Len(t, addCommands, 1)
+	require.Len(t, deleteCommands, 1)
+	assert.Equal(t, []string{addCommands[0].String(), deleteCommands[0].String()}, commands)
+	assert.False(t, activeFw())
+}
+
+func TestAttacksOverlap(t *testing.T) {
+	database := akn.NewNetWithPortRanges([]net.IPNet{mustParseCIDR(t, "10.0.0.5/32")}, akn.PortRange{From: 5432, To: 5432})
+	cache := akn.NewNetWithPortRanges([]net.IPNet{mustParseCIDR(t, "10.0.0.6/32")}, akn.PortRange{From: 6379, To: 6379})
+	databaseOtherPort := akn.NewNetWithPortRanges([]net.IPNet{mustParseCIDR(t, "10.0.0.5/32")}, akn.PortRange{From: 8080, To: 8080})
+	subnet := akn.NewNetWithPortRanges([]net.IPNet{mustParseCIDR(t, "10.0.0.0/24")}, akn.PortRangeAny)
+
+	tests := []struct {
+		name     string
+		a        WinOpts
+		b        WinOpts
+		overlaps bool
+	}{
+		{
+			name:     "different hosts",
+			a:        &DelayOpts{Filter: Filter{Include: database}, Delay: time.Second},
+			b:        &PackageLossOpts{Filter: Filter{Include: cache}, Loss: 50},
+			overlaps: false,
+		},
+		{
+			name:     "different ports on the same host",
+			a:        &DelayOpts{Filter: Filter{Include: database}, Delay: time.Second},
+			b:        &PackageLossOpts{Filter: Filter{Include: databaseOtherPort}, Loss: 50},
+			overlaps: false,
+		},
+		{
+			name:     "host within subnet",
+			a:        &DelayOpts{Filter: Filter{Include: database}, Delay: time.Second},
+			b:        &BlackholeOpts{Filter: Filter{Include: subnet}},
+			overlaps: true,
+		},
+		{
+			name:     "different directions",
+			a:        &DelayOpts{Filter: Filter{Include: subnet, Direction: DirectionIncoming}, Delay: time.Second},
+			b:        &BlackholeOpts{Filter: Filter{Include: subnet, Direction: DirectionOutgoing}},
+			overlaps: false,
+		},
+		{
+			name:     "unset direction is outgoing",
+			a:        &DelayOpts{Filter: Filter{Include: subnet}, Delay: time.Second},
+			b:        &BlackholeOpts{Filter: Filter{Include: subnet, Direction: DirectionOutgoing}},
+			overlaps: true,
+		},
+		{
+			name:     "all directions",
+			a:        &DelayOpts{Filter: Filter{Include: subnet, Direction: DirectionIncoming}, Delay: time.Second},
+			b:        &BlackholeOpts{Filter: Filter{Include: subnet, Direction: DirectionAll}},
+			overlaps: true,
+		},
+		{
+			name:     "different interfaces",
+			a:        &DelayOpts{Filter: Filter{Include: subnet, InterfaceIndexes: []int{1}}, Delay: time.Second},
+			b:        &BlackholeOpts{Filter: Filter{Include: subnet, InterfaceIndexes: []int{2, 3}}},
+			overlaps: false,
+		},
+		{
+			name:     "different protocols",
+			a:        &DelayOpts{Filter: Filter{Include: subnet, Protocols: []Protocol{ProtocolUDP}}, Delay: time.Second},
+			b:        &BlackholeOpts{Filter: Filter{Include: subnet, Protocols: []Protocol{ProtocolICMP}}},
+			overlaps: false,
+		},
+		{
+			name:     "default protocols",
+			a:        &DelayOpts{Filter: Filter{Include: subnet}, Delay: time.Second},
+			b:        &BlackholeOpts{Filter: Filter{Include: subnet, Protocols: []Protocol{ProtocolTCP}}},
+			overlaps: true,
+		},
+		{
+			name:     "different processes",
+			a:        &DelayOpts{Filter: Filter{Include: subnet, Process: "sqlservr.exe", LocalPorts: []uint16{1433}}, Delay: time.Second},
+			b:        &BlackholeOpts{Filter: Filter{Include: subnet, Process: "w3wp.exe", LocalPorts: []uint16{80, 443}}},
+			overlaps: false,
+		},
+		{
+			name:     "process and all traffic",
+			a:        &DelayOpts{Filter: Filter{Include: subnet, Process: "sqlservr.exe", LocalPorts: []uint16{1433}}, Delay: time.Second},
+			b:        &BlackholeOpts{Filter: Filter{Include: subnet}},
+			overlaps: true,
+		},
+		{
+			name:     "bandwidth limit on delayed host",
+			a:        &DelayOpts{Filter: Filter{Include: database}, Delay: time.Second},
+			b:        &LimitBandwidthOpts{Bandwidth: "1MB", IncludeCidrs: []net.IPNet{mustParseCIDR(t, "10.0.0.0/24")}},
+			overlaps: true,
+		},
+		{
+			name:     "bandwidth limit on other host",
+			a:        &DelayOpts{Filter: Filter{Include: database}, Delay: time.Second},
+			b:        &LimitBandwidthOpts{Bandwidth: "1MB", IncludeCidrs: []net.IPNet{mustParseCIDR(t, "10.0.1.0/24")}},
+			overlaps: false,
+		},
+		{
+			name:     "unknown traffic",
+			a:        &DelayOpts{Filter: Filter{Include: database}, Delay: time.Second},
+			b:        &MockNetworkOpt{},
+			overlaps: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.overlaps, attacksOverlap(tt.a, tt.b))
+			assert.Equal(t, tt.overlaps, attacksOverlap(tt.b, tt.a))
+		})
+	}
+}
+
+func TestPushActiveFw_RejectsOnlyOverlappingAttacks(t *testing.T) {
+	database := &DelayOpts{Filter: Filter{Include: akn.NewNetWithPortRanges([]net.IPNet{mustParseCIDR(t, "10.0.0.5/32")}, akn.PortRangeAny)}, Delay: time.Second}
+	cache := &PackageLossOpts{Filter: Filter{Include: akn.NewNetWithPortRanges([]net.IPNet{mustParseCIDR(t, "10.0.0.6/32")}, akn.PortRangeAny)}, Loss: 50}
+	subnet := &BlackholeOpts{Filter: Filter{Include: akn.NewNetWithPortRanges([]net.IPNet{mustParseCIDR(t, "10.0.0.0/24")}, akn.PortRangeAny)}}
+	t.Cleanup(func() {
+		popActiveFw("windows", database)
+		popActiveFw("windows", cache)
+	})
+
+	require.NoError(t, pushActiveFw(database))
+	require.NoError(t, pushActiveFw(cache))
+	assert.ErrorContains(t, pushActiveFw(subnet), "overlaps with an already running attack")
+	assert.Nil(t, findActiveFw("windows", subnet))
+
+	sameAsDatabase := *database
+	assert.ErrorContains(t, pushActiveFw(&sameAsDatabase), "overlaps with an already running attack")
+	assert.Len(t, activeFirewall["windows"], 2)
+}
+
+func mustParseCIDR(t *testing.T, cidr string) net.IPNet {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	require.NoError(t, err)
+	return *ipNet
+}
+
 type MockNetworkOpt struct{}
 
 func (o *MockNetworkOpt) WinDivertCommands(_ Mode) ([]string, error) {
 	return nil, nil
 }
 
-func (o *MockNetworkOpt) QoSCommands(_ Mode) ([]string, error) {
+func (o *MockNetworkOpt) QoSCommands(_ Mode) ([]utils.PowershellInvocation, error) {
 	return nil, nil
 }
 
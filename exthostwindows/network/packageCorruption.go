@@ -5,19 +5,20 @@ package network
 
 import (
 	"fmt"
-	"os"
 	"strings"
 	"time"
+
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
 )
 
 type CorruptPackagesOpts struct {
 	Filter
 	Corruption uint
 	Duration   time.Duration
-	FilterFile string
+	WinDivertInstance
 }
 
-func (o *CorruptPackagesOpts) QoSCommands(_ Mode) ([]string, error) {
+func (o *CorruptPackagesOpts) QoSCommands(_ Mode) ([]utils.PowershellInvocation, error) {
 	return nil, nil
 }
 
@@ -30,12 +31,15 @@ func (o *CorruptPackagesOpts) WinDivertCommands(mode Mode) ([]string, error) {
 			return nil, err
 		}
 		o.FilterFile = filterFile
-		cmds = append(cmds, fmt.Sprintf("wdna.exe --file=%q --mode=corrupt --duration=%d --percentage=%d", filterFile, int(o.Duration.Seconds()), o.Corruption))
+		start, err := o.startCommand("--mode=corrupt", o.durationArg(o.Duration), fmt.Sprintf("--percentage=%d", o.Corruption))
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, start)
 
 	} else {
-		cmds = append(cmds, "wdna_shutdown")
-		cmds = append(cmds, "cmd /c \"sc stop windivert || exit /b 0\"") // don't fail on error
-		_ = os.Remove(o.FilterFile)
+		cmds = append(cmds, o.stopCommands()...)
+		o.removeFilterFile()
 	}
 
 	return cmds, nil
@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package network
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+)
+
+type DuplicateOpts struct {
+	Filter
+	Duplication uint
+	Correlation uint
+	Duration    time.Duration
+	WinDivertInstance
+}
+
+func (o *DuplicateOpts) QoSCommands(_ Mode) ([]utils.PowershellInvocation, error) {
+	return nil, nil
+}
+
+func (o *DuplicateOpts) WinDivertCommands(mode Mode) ([]string, error) {
+	var cmds []string
+
+	if mode == ModeAdd {
+		filterFile, err := buildWinDivertFilterFile(o.Filter)
+		if err != nil {
+			return nil, err
+		}
+		o.FilterFile = filterFile
+		start, err := o.startCommand("--mode=duplicate", o.durationArg(o.Duration), fmt.Sprintf("--percentage=%d", o.Duplication), fmt.Sprintf("--correlation=%d", o.Correlation))
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, start)
+
+	} else {
+		cmds = append(cmds, o.stopCommands()...)
+		o.removeFilterFile()
+	}
+
+	return cmds, nil
+}
+
+func (o *DuplicateOpts) String() string {
+	var sb strings.Builder
+	sb.WriteString("duplicating packages of ")
+	sb.WriteString(fmt.Sprintf("%d%% (correlation: %d%%)", o.Duplication, o.Correlation))
+	o.Filter.writeStringForFilters(&sb)
+	return sb.String()
+}
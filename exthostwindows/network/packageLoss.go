@@ -5,19 +5,20 @@ package network
 
 import (
 	"fmt"
-	"os"
 	"strings"
 	"time"
+
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
 )
 
 type PackageLossOpts struct {
 	Filter
-	Loss       uint
-	Duration   time.Duration
-	filterFile string
+	Loss     uint
+	Duration time.Duration
+	WinDivertInstance
 }
 
-func (o *PackageLossOpts) QoSCommands(_ Mode) ([]string, error) {
+func (o *PackageLossOpts) QoSCommands(_ Mode) ([]utils.PowershellInvocation, error) {
 	return nil, nil
 }
 
@@ -29,13 +30,16 @@ func (o *PackageLossOpts) WinDivertCommands(mode Mode) ([]string, error) {
 		if err != nil {
 			return nil, err
 		}
-		o.filterFile = filterFile
-		cmds = append(cmds, fmt.Sprintf("wdna.exe --file=%q --mode=drop --duration=%d --percentage=%d", filterFile, int(o.Duration.Seconds()), o.Loss))
+		o.FilterFile = filterFile
+		start, err := o.startCommand("--mode=drop", o.durationArg(o.Duration), fmt.Sprintf("--percentage=%d", o.Loss))
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, start)
 
 	} else {
-		cmds = append(cmds, "wdna_shutdown")
-		cmds = append(cmds, "cmd /c \"sc stop windivert || exit /b 0\"") // don't fail on error
-		_ = os.Remove(o.filterFile)
+		cmds = append(cmds, o.stopCommands()...)
+		o.removeFilterFile()
 	}
 
 	return cmds, nil
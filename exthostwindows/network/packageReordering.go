@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package network
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+)
+
+type ReorderOpts struct {
+	Filter
+	Reorder     uint
+	Correlation uint
+	Duration    time.Duration
+	WinDivertInstance
+}
+
+func (o *ReorderOpts) QoSCommands(_ Mode) ([]utils.PowershellInvocation, error) {
+	return nil, nil
+}
+
+func (o *ReorderOpts) WinDivertCommands(mode Mode) ([]string, error) {
+	var cmds []string
+
+	if mode == ModeAdd {
+		filterFile, err := buildWinDivertFilterFile(o.Filter)
+		if err != nil {
+			return nil, err
+		}
+		o.FilterFile = filterFile
+		start, err := o.startCommand("--mode=reorder", o.durationArg(o.Duration), fmt.Sprintf("--percentage=%d", o.Reorder), fmt.Sprintf("--correlation=%d", o.Correlation))
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, start)
+
+	} else {
+		cmds = append(cmds, o.stopCommands()...)
+		o.removeFilterFile()
+	}
+
+	return cmds, nil
+}
+
+func (o *ReorderOpts) String() string {
+	var sb strings.Builder
+	sb.WriteString("reordering packages of ")
+	sb.WriteString(fmt.Sprintf("%d%% (correlation: %d%%)", o.Reorder, o.Correlation))
+	o.Filter.writeStringForFilters(&sb)
+	return sb.String()
+}
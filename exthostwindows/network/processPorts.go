@@ -0,0 +1,164 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	stopprocess "github.com/steadybit/extension-host-windows/exthostwindows/process"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+)
+
+const localPortsRefreshInterval = 5 * time.Second
+
+var (
+	localPortRefreshersLock = sync.Mutex{}
+	localPortRefreshers     = map[WinOpts]context.CancelFunc{}
+)
+
+// findProcessLocalPorts returns the local TCP and UDP ports of all processes with the pid or image name.
+var findProcessLocalPorts = func(ctx context.Context, runner utils.CommandRunner, process string) ([]uint16, error) {
+	pids := stopprocess.FindProcessIdsByImageName(process)
+	if len(pids) == 0 {
+		log.Debug().Str("process", process).Msg("no process found, no local ports to affect")
+		return nil, nil
+	}
+
+	pidList := make([]string, len(pids))
+	for i, pid := range pids {
+		pidList[i] = strconv.Itoa(pid)
+	}
+	command := fmt.Sprintf("$pids = @(%[1]s); @(Get-NetTCPConnection -OwningProcess $pids -ErrorAction SilentlyContinue | Select-Object -ExpandProperty LocalPort) + @(Get-NetUDPEndpoint -OwningProcess $pids -ErrorAction SilentlyContinue | Select-Object -ExpandProperty LocalPort) | Sort-Object -Unique", strings.Join(pidList, ","))
+	out, err := runner.RunPowershell(ctx, []string{command}, utils.PSRun)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find local ports of process %s: %w", process, err)
+	}
+	return parseLocalPorts(out)
+}
+
+func parseLocalPorts(out string) ([]uint16, error) {
+	var ports []uint16
+	for line := range strings.Lines(out) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		port, err := strconv.ParseUint(line, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid local port %q: %w", line, err)
+		}
+		ports = append(ports, uint16(port))
+	}
+	slices.Sort(ports)
+	return slices.Compact(ports), nil
+}
+
+// resolveLocalPorts updates the local ports of process scoped attacks.
+func resolveLocalPorts(ctx context.Context, runner utils.CommandRunner, opts WinOpts) (bool, error) {
+	attack, ok := opts.(filteredAttack)
+	if !ok || attack.networkFilter().Process == "" {
+		return false, nil
+	}
+
+	filter := attack.networkFilter()
+	ports, err := findProcessLocalPorts(ctx, runner, filter.Process)
+	if err != nil {
+		return false, err
+	}
+	if slices.Equal(ports, filter.LocalPorts) {
+		return false, nil
+	}
+	log.Debug().Str("process", filter.Process).Any("ports", ports).Msg("local ports of process changed")
+	filter.LocalPorts = ports
+	return true, nil
+}
+
+// startLocalPortsRefresh periodically re-applies process scoped attacks when the ports used by the process change.
+func startLocalPortsRefresh(runner utils.CommandRunner, executionId uuid.UUID, opts WinOpts) {
+	if attack, ok := opts.(filteredAttack); !ok || attack.networkFilter().Process == "" {
+		return
+	}
+	if _, ok := opts.(winDivertAttack); !ok {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	localPortRefreshersLock.Lock()
+	localPortRefreshers[opts] = cancel
+	localPortRefreshersLock.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(localPortsRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := refreshLocalPorts(ctx, runner, executionId, opts); err != nil {
+					log.Warn().Err(err).Str("attack", opts.String()).Msg("failed to refresh local ports of process")
+				}
+			}
+		}
+	}()
+}
+
+func stopLocalPortsRefresh(opts WinOpts) {
+	localPortRefreshersLock.Lock()
+	defer localPortRefreshersLock.Unlock()
+	if cancel, ok := localPortRefreshers[opts]; ok {
+		cancel()
+		delete(localPortRefreshers, opts)
+	}
+}
+
+func refreshLocalPorts(ctx context.Context, runner utils.CommandRunner, executionId uuid.UUID, opts WinOpts) error {
+	runLock.LockKey("windows")
+	defer func() { _ = runLock.UnlockKey("windows") }()
+
+	if ctx.Err() != nil {
+		// the attack was reverted while waiting for the lock
+		return nil
+	}
+
+	// refresh the applied attack, which the overlap detection checks new attacks against
+	active := findActiveFw("windows", opts)
+	if active == nil {
+		return nil
+	}
+	changed, err := resolveLocalPorts(ctx, runner, active)
+	if err != nil || !changed {
+		return err
+	}
+	// an attack left over after a crash is reverted with the current ports, even if restarting wdna fails
+	if err := writeJournalEntry(executionId, active); err != nil {
+		return err
+	}
+
+	// restart the wdna instance with a filter for the current ports
+	instance := active.(winDivertAttack).winDivertInstance()
+	previous := *instance
+	if previous.Pid <= 0 {
+		return errors.New("process id of the running wdna instance is unknown")
+	}
+	if _, err := runner.RunPowershell(ctx, previous.stopCommands(), utils.PSRun); err != nil {
+		return err
+	}
+	previous.removeFilterFile()
+
+	cmds, err := active.WinDivertCommands(ModeAdd)
+	if err != nil {
+		return err
+	}
+	return executeWinDivertCommands(ctx, runner, executionId, active, cmds, ModeAdd)
+}
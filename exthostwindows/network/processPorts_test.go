@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package network
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	akn "github.com/steadybit/action-kit/go/action_kit_commons/network"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils/utilstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLocalPorts(t *testing.T) {
+	ports, err := parseLocalPorts("1433\r\n50001\r\n\r\n135\r\n1433\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{135, 1433, 50001}, ports)
+
+	ports, err = parseLocalPorts("")
+	require.NoError(t, err)
+	assert.Empty(t, ports)
+
+	_, err = parseLocalPorts("1433\r\nLocalPort")
+	assert.ErrorContains(t, err, "invalid local port")
+}
+
+func TestResolveLocalPorts(t *testing.T) {
+	ports := []uint16{1433}
+	original := findProcessLocalPorts
+	findProcessLocalPorts = func(_ context.Context, _ utils.CommandRunner, process string) ([]uint16, error) {
+		assert.Equal(t, "sqlservr.exe", process)
+		return ports, nil
+	}
+	t.Cleanup(func() { findProcessLocalPorts = original })
+
+	opts := &DelayOpts{Filter: Filter{Process: "sqlservr.exe"}, Delay: time.Second}
+
+	changed, err := resolveLocalPorts(t.Context(), utilstest.NewRecordingRunner(), opts)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, []uint16{1433}, opts.LocalPorts)
+
+	changed, err = resolveLocalPorts(t.Context(), utilstest.NewRecordingRunner(), opts)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	ports = []uint16{1433, 50001}
+	changed, err = resolveLocalPorts(t.Context(), utilstest.NewRecordingRunner(), opts)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, []uint16{1433, 50001}, opts.LocalPorts)
+}
+
+func TestResolveLocalPorts_IgnoresAttacksWithoutProcess(t *testing.T) {
+	original := findProcessLocalPorts
+	findProcessLocalPorts = func(_ context.Context, _ utils.CommandRunner, _ string) ([]uint16, error) {
+		t.Fatal("must not look up ports")
+		return nil, nil
+	}
+	t.Cleanup(func() { findProcessLocalPorts = original })
+
+	changed, err := resolveLocalPorts(t.Context(), utilstest.NewRecordingRunner(), &DelayOpts{Delay: time.Second})
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	changed, err = resolveLocalPorts(t.Context(), utilstest.NewRecordingRunner(), &MockNetworkOpt{})
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestRefreshLocalPorts_UpdatesActiveAttackAndJournal(t *testing.T) {
+	initTestJournal(t)
+	ports := []uint16{1433}
+	original := findProcessLocalPorts
+	findProcessLocalPorts = func(_ context.Context, _ utils.CommandRunner, _ string) ([]uint16, error) {
+		return ports, nil
+	}
+	t.Cleanup(func() { findProcessLocalPorts = original })
+
+	newAttack := func() *DelayOpts {
+		return &DelayOpts{Filter: Filter{Include: akn.NewNetWithPortRanges(akn.NetAny, akn.PortRangeAny), Process: "sqlservr.exe"}, Delay: time.Second}
+	}
+	applied := newAttack()
+	_, err := resolveLocalPorts(t.Context(), utilstest.NewRecordingRunner(), applied)
+	require.NoError(t, err)
+	applied.Pid = 4711
+	require.NoError(t, pushActiveFw(applied))
+	t.Cleanup(func() {
+		popActiveFw("windows", applied)
+		applied.removeFilterFile()
+	})
+	executionId := uuid.New()
+	require.NoError(t, writeJournalEntry(executionId, applied))
+
+	ports = []uint16{50001}
+	runner := utilstest.NewRecordingRunner().Respond("Start-Process", `[{"Id":4712}]`, nil)
+	// restarting wdna fails without the WinDivert service, the ports are updated nonetheless
+	_ = refreshLocalPorts(t.Context(), runner, executionId, newAttack())
+
+	assert.Equal(t, []uint16{50001}, findActiveFw("windows", applied).(*DelayOpts).LocalPorts)
+	journaled := readJournalEntry(executionId, newAttack())
+	require.NotNil(t, journaled)
+	assert.Equal(t, []uint16{50001}, journaled.(*DelayOpts).LocalPorts)
+
+	onOldPorts, onNewPorts := newAttack(), newAttack()
+	onOldPorts.LocalPorts, onNewPorts.LocalPorts = []uint16{1433}, []uint16{50001}
+	onOldPorts.Delay, onNewPorts.Delay = 2*time.Second, 3*time.Second
+	assert.ErrorContains(t, pushActiveFw(onNewPorts), "overlaps with an already running attack")
+	require.NoError(t, pushActiveFw(onOldPorts))
+	popActiveFw("windows", onOldPorts)
+}
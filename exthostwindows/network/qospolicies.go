@@ -7,6 +7,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/rs/zerolog/log"
@@ -15,43 +16,129 @@ import (
 
 const qosPolicyPrefix = "STEADYBIT_QOS_"
 
-func logCurrentQoSRules(ctx context.Context, when string) {
+// qosPolicy is a QoS policy as returned by Get-NetQosPolicy.
+type qosPolicy struct {
+	Name                         string
+	Precedence                   uint32
+	IPDstPrefixMatchCondition    string
+	IPDstPortStartMatchCondition uint16
+	IPDstPortEndMatchCondition   uint16
+	// ThrottleRateAction is the limit in bits per second.
+	ThrottleRateAction uint64
+}
+
+var qosPolicyProperties = []string{"Name", "Precedence", "IPDstPrefixMatchCondition", "IPDstPortStartMatchCondition", "IPDstPortEndMatchCondition", "ThrottleRateAction"}
+
+// QosError is returned if a QoS policy couldn't be created or removed.
+type QosError struct {
+	Cmdlet string
+	Policy string
+	// Result is the result of the privileged invocation, if it was run.
+	Result utils.PrivilegedResult
+	Err    error
+}
+
+func (e *QosError) Error() string {
+	return fmt.Sprintf("%s %s failed: %s", e.Cmdlet, e.Policy, e.Err)
+}
+
+func (e *QosError) Unwrap() error {
+	return e.Err
+}
+
+// QosErrors returns all QosError contained in the error, e.g. joined by errors.Join.
+func QosErrors(err error) []*QosError {
+	var qosErr *QosError
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case interface{ Unwrap() []error }:
+		var result []*QosError
+		for _, err := range e.Unwrap() {
+			result = append(result, QosErrors(err)...)
+		}
+		return result
+	default:
+		if errors.As(err, &qosErr) {
+			return []*QosError{qosErr}
+		}
+		return nil
+	}
+}
+
+// runQosInvocation runs the invocation with SYSTEM privileges and returns a QosError if it failed.
+func runQosInvocation(ctx context.Context, runner utils.CommandRunner, invocation utils.PowershellInvocation) error {
+	policy, _ := invocation.Params["Name"].(string)
+	result, err := runner.RunPrivileged(ctx, invocation)
+	if err == nil {
+		err = result.Err()
+	}
+	if err != nil {
+		return &QosError{Cmdlet: invocation.Cmdlet, Policy: policy, Result: result, Err: err}
+	}
+	return nil
+}
+
+// verifyQosPolicies makes sure that the policies created by the invocations exist and have the requested rate.
+func verifyQosPolicies(ctx context.Context, runner utils.CommandRunner, invocations []utils.PowershellInvocation) error {
+	policies, err := listSteadybitQosPolicies(ctx, runner)
+	if err != nil {
+		return err
+	}
+	var errs error
+	for _, invocation := range invocations {
+		if invocation.Cmdlet != "New-NetQosPolicy" {
+			continue
+		}
+		name, _ := invocation.Params["Name"].(string)
+		i := slices.IndexFunc(policies, func(policy qosPolicy) bool { return policy.Name == name })
+		if i < 0 {
+			errs = errors.Join(errs, &QosError{Cmdlet: invocation.Cmdlet, Policy: name, Err: errors.New("policy does not exist after it was created")})
+			continue
+		}
+		if rate, ok := invocation.Params["ThrottleRateActionBitsPerSecond"].(uint64); ok && policies[i].ThrottleRateAction != rate {
+			errs = errors.Join(errs, &QosError{Cmdlet: invocation.Cmdlet, Policy: name, Err: fmt.Errorf("policy throttles to %d bits per second instead of %d", policies[i].ThrottleRateAction, rate)})
+		}
+	}
+	return errs
+}
+
+func logCurrentQoSRules(ctx context.Context, runner utils.CommandRunner, when string) {
 	if !log.Trace().Enabled() {
 		return
 	}
-	policies, err := listSteadybitQosPolicies(ctx)
+	policies, err := listSteadybitQosPolicies(ctx, runner)
 	if err != nil {
 		log.Trace().Err(err).Msg("failed to get current QoS rules")
 	} else {
-		log.Trace().Str("when", when).Strs("policies", policies).Msg("current QoS policies")
+		log.Trace().Str("when", when).Any("policies", policies).Msg("current QoS policies")
 	}
 }
 
-func listSteadybitQosPolicies(ctx context.Context) ([]string, error) {
-	return listQosPolicies(ctx, "Get-NetQosPolicy | Where-Object { $_.Name -like \""+qosPolicyPrefix+"*\" }", "\r\n\r\n")
-}
-
-func listSteadybitQosPolicyNames(ctx context.Context) ([]string, error) {
-	return listQosPolicies(ctx, "Get-NetQosPolicy | Where-Object { $_.Name -like \""+qosPolicyPrefix+"*\" } | Select-Object -ExpandProperty Name", "\r\n")
+func listSteadybitQosPolicies(ctx context.Context, runner utils.CommandRunner) ([]qosPolicy, error) {
+	policies, err := utils.RunPowershellJson[qosPolicy](ctx, runner, utils.PowershellInvocation{Cmdlet: "Get-NetQosPolicy", Select: qosPolicyProperties})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list QoS policies: %w", err)
+	}
+	return slices.DeleteFunc(policies, func(policy qosPolicy) bool {
+		return !strings.HasPrefix(policy.Name, qosPolicyPrefix)
+	}), nil
 }
 
-func listQosPolicies(ctx context.Context, command string, separator string) ([]string, error) {
-	result, err := utils.ExecutePowershellCommand(ctx, []string{command}, utils.PSRun)
+func listSteadybitQosPolicyNames(ctx context.Context, runner utils.CommandRunner) ([]string, error) {
+	policies, err := listSteadybitQosPolicies(ctx, runner)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list QoS policies: %w", err)
+		return nil, err
 	}
-	var policies []string
-	blocks := strings.SplitSeq(result, separator)
-	for block := range blocks {
-		if block != "" {
-			policies = append(policies, block)
-		}
+	var names []string
+	for _, policy := range policies {
+		names = append(names, policy.Name)
 	}
-	return policies, nil
+	return names, nil
 }
 
-func removeSteadybitQosPolicies(ctx context.Context) error {
-	policies, err := listSteadybitQosPolicyNames(ctx)
+func removeSteadybitQosPolicies(ctx context.Context, runner utils.CommandRunner) error {
+	policies, err := listSteadybitQosPolicyNames(ctx, runner)
 	if err != nil {
 		return err
 	}
@@ -59,14 +146,13 @@ func removeSteadybitQosPolicies(ctx context.Context) error {
 		return nil
 	}
 	log.Error().Strs("policies", policies).Msg("Found leftover QoS policies, removing them")
-	return removeQoSPolicies(ctx, policies)
+	return removeQoSPolicies(ctx, runner, policies)
 }
 
-func removeQoSPolicies(ctx context.Context, policies []string) error {
+func removeQoSPolicies(ctx context.Context, runner utils.CommandRunner, policies []string) error {
 	var errs error
 	for _, policy := range policies {
-		removeCommand := fmt.Sprintf("Remove-NetQosPolicy -Name %s -Confirm:$false", policy)
-		if _, err := utils.ExecutePowershellCommand(ctx, utils.BuildSystemCommandFor(removeCommand), utils.PSRun); err != nil {
+		if err := runQosInvocation(ctx, runner, utils.PowershellInvocation{Cmdlet: "Remove-NetQosPolicy", Params: map[string]any{"Name": policy, "Confirm": false}}); err != nil {
 			errs = errors.Join(errs, err)
 		}
 	}
@@ -4,44 +4,124 @@
 package network
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
 	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils/utilstest"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/exp/rand"
-	"strconv"
-	"testing"
 )
 
 func TestListSteadybitQosPolicyNames(t *testing.T) {
-	err := removeSteadybitQosPolicies(t.Context())
+	err := removeSteadybitQosPolicies(t.Context(), execRunner)
 	require.NoError(t, err)
 
 	testQosPolicyName := qosPolicyPrefix + "test_" + strconv.Itoa(rand.Intn(100000))
 	createQosPolicy(t, testQosPolicyName)
 	defer func() {
-		err := removeQoSPolicies(t.Context(), []string{testQosPolicyName})
+		err := removeQoSPolicies(t.Context(), execRunner, []string{testQosPolicyName})
 		require.NoError(t, err)
 	}()
 
-	qosPolicyNames, err := listSteadybitQosPolicyNames(t.Context())
+	qosPolicyNames, err := listSteadybitQosPolicyNames(t.Context(), execRunner)
 	require.NoError(t, err)
 	require.Contains(t, qosPolicyNames, testQosPolicyName)
 }
 
 func TestListSteadybitQosPolicies(t *testing.T) {
-	err := removeSteadybitQosPolicies(t.Context())
+	err := removeSteadybitQosPolicies(t.Context(), execRunner)
 	require.NoError(t, err)
 
 	testQosPolicyName := qosPolicyPrefix + "test_" + strconv.Itoa(rand.Intn(100000))
 	createQosPolicy(t, testQosPolicyName)
 	defer func() {
-		err := removeQoSPolicies(t.Context(), []string{testQosPolicyName})
+		err := removeQoSPolicies(t.Context(), execRunner, []string{testQosPolicyName})
 		require.NoError(t, err)
 	}()
 
-	qosPolicyNames, err := listSteadybitQosPolicies(t.Context())
+	policies, err := listSteadybitQosPolicies(t.Context(), execRunner)
+	require.NoError(t, err)
+	require.Contains(t, policies, qosPolicy{Name: testQosPolicyName, Precedence: 127, ThrottleRateAction: 100 * 1024 * 1024})
+}
+
+func TestListSteadybitQosPolicies_Fixture(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/get-netqospolicy.json")
+	require.NoError(t, err)
+	runner := utilstest.NewRecordingRunner().Respond("", string(fixture), nil)
+
+	policies, err := listSteadybitQosPolicies(t.Context(), runner)
+	require.NoError(t, err)
+	require.Equal(t, []qosPolicy{
+		{Name: "STEADYBIT_QOS_1mbit_eb1893d5_0", Precedence: 255, IPDstPrefixMatchCondition: "10.0.0.0/24", IPDstPortStartMatchCondition: 5432, IPDstPortEndMatchCondition: 5432, ThrottleRateAction: 1000000},
+		{Name: "STEADYBIT_QOS_1mbit_eb1893d5_1", Precedence: 255, IPDstPrefixMatchCondition: "10.0.1.0/24", ThrottleRateAction: 1000000},
+	}, policies)
+	command := "ConvertTo-Json -Compress -Depth 4 -InputObject @(Get-NetQosPolicy | Select-Object -Property " + strings.Join(qosPolicyProperties, ",") + ")"
+	require.Equal(t, []string{command}, runner.Commands())
+
+	script, err := os.ReadFile("../../scripts/capture-powershell-testdata.ps1")
+	require.NoError(t, err)
+	require.Contains(t, string(script), "'"+command+"'", "fixture not captured with the command")
+}
+
+func TestApply_QosErrors(t *testing.T) {
+	opts := &LimitBandwidthOpts{Bandwidth: "1MB", IncludeCidrs: []net.IPNet{mustParseCIDR(t, "10.0.0.0/24")}}
+	policyName := opts.policyName(0)
+
+	tests := []struct {
+		name       string
+		runner     *utilstest.RecordingRunner
+		wantResult utils.PrivilegedResult
+		wantErr    string
+	}{
+		{
+			name: "cmdlet fails",
+			runner: utilstest.NewRecordingRunner().
+				Respond("New-NetQosPolicy", "", errors.New("Access denied")),
+			wantResult: utils.PrivilegedResult{ExitCode: 1, Error: "Access denied"},
+			wantErr:    "New-NetQosPolicy " + policyName + " failed: exit code 1, output: , error: Access denied",
+		},
+		{
+			name: "policy is missing",
+			runner: utilstest.NewRecordingRunner().
+				Respond("Get-NetQosPolicy", qosPoliciesJson(t), nil),
+			wantErr: "New-NetQosPolicy " + policyName + " failed: policy does not exist after it was created",
+		},
+		{
+			name: "policy has another rate",
+			runner: utilstest.NewRecordingRunner().
+				Respond("Get-NetQosPolicy", qosPoliciesJson(t, qosPolicy{Name: policyName, ThrottleRateAction: 1000}), nil),
+			wantErr: "New-NetQosPolicy " + policyName + " failed: policy throttles to 1000 bits per second instead of 1048576",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			initTestJournal(t)
+			executionId := uuid.New()
+			defer func() { require.NoError(t, Revert(t.Context(), utilstest.NewRecordingRunner(), executionId, opts)) }()
+
+			err := Apply(t.Context(), tt.runner, executionId, opts)
+			qosErrs := QosErrors(err)
+			require.Len(t, qosErrs, 1)
+			assert.Equal(t, policyName, qosErrs[0].Policy)
+			assert.Equal(t, tt.wantResult, qosErrs[0].Result)
+			assert.EqualError(t, qosErrs[0], tt.wantErr)
+		})
+	}
+}
+
+func qosPoliciesJson(t *testing.T, policies ...qosPolicy) string {
+	out, err := json.Marshal(append([]qosPolicy{}, policies...))
 	require.NoError(t, err)
-	require.NotEmpty(t, qosPolicyNames)
+	return string(out)
 }
 
 func createQosPolicy(t *testing.T, name string) {
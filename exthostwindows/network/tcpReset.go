@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package network
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+)
+
+type TcpResetOpts struct {
+	Filter
+	// Interval between the resets of the matching connections. Without interval, the connections are reset once at start.
+	Interval time.Duration
+	Duration time.Duration
+	WinDivertInstance
+}
+
+func (o *TcpResetOpts) QoSCommands(_ Mode) ([]utils.PowershellInvocation, error) {
+	return nil, nil
+}
+
+func (o *TcpResetOpts) WinDivertCommands(mode Mode) ([]string, error) {
+	var cmds []string
+
+	if mode == ModeAdd {
+		if !slices.Equal(o.Filter.protocols(), []Protocol{ProtocolTCP}) {
+			return nil, errors.New("only tcp connections can be reset")
+		}
+		filterFile, err := buildWinDivertFilterFile(o.Filter)
+		if err != nil {
+			return nil, err
+		}
+		o.FilterFile = filterFile
+		// an interval of 0 makes wdna reset the connections only once
+		start, err := o.startCommand("--mode=reset", o.durationArg(o.Duration), fmt.Sprintf("--interval=%d", o.Interval.Milliseconds()))
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, start)
+
+	} else {
+		cmds = append(cmds, o.stopCommands()...)
+		o.removeFilterFile()
+	}
+
+	return cmds, nil
+}
+
+func (o *TcpResetOpts) String() string {
+	var sb strings.Builder
+	sb.WriteString("resetting tcp connections ")
+	if o.Interval > 0 {
+		sb.WriteString("every ")
+		sb.WriteString(o.Interval.String())
+	} else {
+		sb.WriteString("once")
+	}
+	o.Filter.writeStringForFilters(&sb)
+	return sb.String()
+}
@@ -4,10 +4,13 @@
 package network
 
 import (
+	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 
 	akn "github.com/steadybit/action-kit/go/action_kit_commons/network"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
 )
 
 type Mode string
@@ -25,7 +28,8 @@ const (
 )
 
 type WinOpts interface {
-	QoSCommands(mode Mode) ([]string, error)
+	// QoSCommands returns the QoS policy changes, they are run with SYSTEM privileges.
+	QoSCommands(mode Mode) ([]utils.PowershellInvocation, error)
 	WinDivertCommands(mode Mode) ([]string, error)
 	String() string
 }
@@ -38,11 +42,61 @@ var (
 	DirectionAll      Direction = "All"
 )
 
+type Protocol string
+
+const (
+	ProtocolTCP  Protocol = "tcp"
+	ProtocolUDP  Protocol = "udp"
+	ProtocolICMP Protocol = "icmp"
+)
+
+// defaultProtocols are affected if no protocol is selected.
+var defaultProtocols = []Protocol{ProtocolTCP, ProtocolUDP}
+
+func ParseProtocols(values []string) ([]Protocol, error) {
+	var protocols []Protocol
+	for _, value := range values {
+		protocol := Protocol(strings.ToLower(strings.TrimSpace(value)))
+		switch protocol {
+		case "":
+			continue
+		case ProtocolTCP, ProtocolUDP, ProtocolICMP:
+			if !slices.Contains(protocols, protocol) {
+				protocols = append(protocols, protocol)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported protocol %q", value)
+		}
+	}
+	return protocols, nil
+}
+
 type Filter struct {
 	Include          []akn.NetWithPortRange
 	Exclude          []akn.NetWithPortRange
 	InterfaceIndexes []int
 	Direction        Direction
+	Protocols        []Protocol
+	// Process restricts the attack to the traffic of a process, given by pid or executable name. The traffic is
+	// matched by the LocalPorts of the process, which are refreshed while the attack is running.
+	Process    string
+	LocalPorts []uint16
+}
+
+// filteredAttack is implemented by attacks matching the affected packets with a Filter.
+type filteredAttack interface {
+	networkFilter() *Filter
+}
+
+func (filter *Filter) networkFilter() *Filter {
+	return filter
+}
+
+func (filter *Filter) protocols() []Protocol {
+	if len(filter.Protocols) == 0 {
+		return defaultProtocols
+	}
+	return filter.Protocols
 }
 
 func (filter *Filter) writeStringForFilters(sb *strings.Builder) {
@@ -50,6 +104,19 @@ func (filter *Filter) writeStringForFilters(sb *strings.Builder) {
 		sb.WriteString("\ndirection: ")
 		sb.WriteString(string(filter.Direction))
 	}
+	if len(filter.Protocols) > 0 {
+		sb.WriteString("\nprotocols: ")
+		for i, protocol := range filter.Protocols {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(string(protocol))
+		}
+	}
+	if filter.Process != "" {
+		sb.WriteString("\nof process: ")
+		sb.WriteString(filter.Process)
+	}
 	sb.WriteString("\nto/from:\n")
 	for _, inc := range filter.Include {
 		sb.WriteString(" ")
@@ -73,3 +140,65 @@ func (filter *Filter) writeStringForFilters(sb *strings.Builder) {
 		}
 	}
 }
+
+// trafficSelector is implemented by attacks which know the traffic they are affecting.
+type trafficSelector interface {
+	trafficFilter() Filter
+}
+
+func (filter *Filter) trafficFilter() Filter {
+	return *filter
+}
+
+// overlaps reports whether both filters may match the same packets. Excludes are not taken into account.
+func (filter *Filter) overlaps(other Filter) bool {
+	if !directionsOverlap(filter.Direction, other.Direction) {
+		return false
+	}
+	if !slices.ContainsFunc(filter.protocols(), func(protocol Protocol) bool {
+		return slices.Contains(other.protocols(), protocol)
+	}) {
+		return false
+	}
+	if filter.Process != "" && other.Process != "" && !slices.ContainsFunc(filter.LocalPorts, func(port uint16) bool {
+		return slices.Contains(other.LocalPorts, port)
+	}) {
+		return false
+	}
+	if len(filter.InterfaceIndexes) > 0 && len(other.InterfaceIndexes) > 0 && !slices.ContainsFunc(filter.InterfaceIndexes, func(ifIdx int) bool {
+		return slices.Contains(other.InterfaceIndexes, ifIdx)
+	}) {
+		return false
+	}
+	for _, include := range filter.Include {
+		for _, otherInclude := range other.Include {
+			if include.Overlap(otherInclude) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func directionsOverlap(a, b Direction) bool {
+	// the WinDivert filter treats an unset direction as outgoing
+	if a == "" {
+		a = DirectionOutgoing
+	}
+	if b == "" {
+		b = DirectionOutgoing
+	}
+	return a == b || a == DirectionAll || b == DirectionAll
+}
+
+// attacksOverlap reports whether both attacks may affect the same traffic. Attacks which don't tell which traffic
+// they affect are considered to overlap with any other attack.
+func attacksOverlap(a, b WinOpts) bool {
+	selectorA, okA := a.(trafficSelector)
+	selectorB, okB := b.(trafficSelector)
+	if !okA || !okB {
+		return true
+	}
+	filterA := selectorA.trafficFilter()
+	return filterA.overlaps(selectorB.trafficFilter())
+}
@@ -4,18 +4,101 @@
 package network
 
 import (
+	"encoding/base64"
 	"fmt"
 	"net"
 	"os"
-	"strings"
-	"text/template"
+	"slices"
+	"strconv"
 	"time"
 
-	"github.com/rs/zerolog/log"
-	"golang.org/x/sys/windows/svc"
-	"golang.org/x/sys/windows/svc/mgr"
+	akn "github.com/steadybit/action-kit/go/action_kit_commons/network"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
 )
 
+// stopWinDivertServiceCommand stops the WinDivert driver once no wdna instance is running anymore.
+const stopWinDivertServiceCommand = "cmd /c \"sc stop windivert || exit /b 0\"" // don't fail on error
+
+// WinDivertInstance holds the state of the wdna process running a single network attack.
+type WinDivertInstance struct {
+	FilterFile string
+	Pid        int
+	StartedAt  time.Time
+	// start is the invocation of the last start command, it is only used to describe the command.
+	start utils.PowershellInvocation
+}
+
+// winDivertAttack is implemented by network attacks running their own wdna instance.
+type winDivertAttack interface {
+	winDivertInstance() *WinDivertInstance
+}
+
+func (i *WinDivertInstance) winDivertInstance() *WinDivertInstance {
+	return i
+}
+
+// startCommand returns the script starting a detached wdna instance with the filter file and the given arguments. The
+// arguments are passed as parameters of the invocation, so powershell never interprets them. The script prints the
+// process id of the started instance, see parseWdnaPid.
+func (i *WinDivertInstance) startCommand(args ...string) (string, error) {
+	i.start = utils.PowershellInvocation{
+		Cmdlet: "Start-Process",
+		Params: map[string]any{
+			"FilePath":     "wdna.exe",
+			"ArgumentList": append([]string{i.fileArg()}, args...),
+			"WindowStyle":  "Hidden",
+			"PassThru":     true,
+		},
+		Select: []string{"Id"},
+	}
+	return i.start.Script()
+}
+
+// durationArg returns the wdna duration argument. When the instance is restarted, only the remaining duration is used.
+func (i *WinDivertInstance) durationArg(duration time.Duration) string {
+	if !i.StartedAt.IsZero() {
+		duration = max(duration-time.Since(i.StartedAt), time.Second)
+	}
+	return fmt.Sprintf("--duration=%d", int(duration.Seconds()))
+}
+
+// fileArg returns the wdna argument passing the filter file. Start-Process joins the arguments with spaces, so the path
+// is quoted.
+func (i *WinDivertInstance) fileArg() string {
+	return fmt.Sprintf("--file=%q", i.FilterFile)
+}
+
+// stopCommands returns the commands stopping this wdna instance only. If the process id is unknown, e.g. because the
+// extension crashed right after starting the instance, the instance is found by the argument passing its filter file,
+// which is unique per instance. The instances of other attacks keep running.
+func (i *WinDivertInstance) stopCommands() []string {
+	if i.Pid > 0 {
+		// make sure not to kill an unrelated process which got the process id of an already terminated instance
+		return []string{fmt.Sprintf("Get-Process -Id %d -ErrorAction SilentlyContinue | Where-Object { $_.ProcessName -eq 'wdna' } | Stop-Process -Force", i.Pid)}
+	}
+	if i.FilterFile == "" {
+		// the instance was never started
+		return nil
+	}
+	// the argument is passed base64 encoded, so powershell never interprets the path
+	return []string{fmt.Sprintf("$fileArg = [Text.Encoding]::UTF8.GetString([Convert]::FromBase64String('%s')); "+
+		"Get-CimInstance Win32_Process -Filter \"Name = 'wdna.exe'\" | Where-Object { $_.CommandLine -and $_.CommandLine.Contains($fileArg) } | ForEach-Object { Stop-Process -Id $_.ProcessId -Force }",
+		base64.StdEncoding.EncodeToString([]byte(i.fileArg())))}
+}
+
+func (i *WinDivertInstance) removeFilterFile() {
+	_ = os.Remove(i.FilterFile)
+}
+
+// parseWdnaPid returns the process id printed by the script of startCommand.
+func parseWdnaPid(out string) (int, error) {
+	processes, err := utils.DecodePowershellJson[struct{ Id int }](out)
+	if err != nil || len(processes) != 1 || processes[0].Id <= 0 {
+		return 0, fmt.Errorf("failed to determine process id of wdna instance from output %q", out)
+	}
+	return processes[0].Id, nil
+}
+
 func getFamily(net net.IPNet) (Family, error) {
 	switch {
 	case net.IP.To4() != nil:
@@ -75,262 +158,166 @@ func getStartEndIP(ipNet net.IPNet) (net.IP, net.IP, error) {
 	return nil, nil, fmt.Errorf("not implemented")
 }
 
-func setCorrectReplacements(replacements *map[string]string, family Family) {
-	if family == FamilyV4 {
-		(*replacements)["ipDstAddr"] = "ip.DstAddr"
-		(*replacements)["ipSrcAddr"] = "ip.SrcAddr"
-	} else {
-		(*replacements)["ipDstAddr"] = "ipv6.DstAddr"
-		(*replacements)["ipSrcAddr"] = "ipv6.SrcAddr"
+func buildWinDivertFilter(f Filter) (string, error) {
+	expr, err := buildWinDivertFilterExpression(f)
+	if err != nil {
+		return "", err
 	}
+	expr = simplifyFilter(expr)
+	if err := expr.validate(); err != nil {
+		return "", err
+	}
+	return renderFilter(expr), nil
 }
 
-const openGroup string = " and ("
-const closeGroup string = ")"
-
-func buildWinDivertFilter(f Filter) (string, error) {
-	var sb strings.Builder
-
-	sb.WriteString("(tcp or udp)")
+func buildWinDivertFilterExpression(f Filter) (filterExpression, error) {
+	protocols := orExpr{}
+	for _, protocol := range f.protocols() {
+		switch protocol {
+		case ProtocolTCP:
+			protocols = append(protocols, flagExpr("tcp"))
+		case ProtocolUDP:
+			protocols = append(protocols, flagExpr("udp"))
+		case ProtocolICMP:
+			protocols = append(protocols, flagExpr("icmp"), flagExpr("icmpv6"))
+		default:
+			return nil, fmt.Errorf("unsupported protocol %q", protocol)
+		}
+	}
+	expr := andExpr{protocols}
 
-	if f.Direction != DirectionAll {
-		writeDirectionFilter(&sb, f.Direction)
+	switch f.Direction {
+	case DirectionAll:
+	case DirectionIncoming:
+		expr = append(expr, flagExpr("inbound"))
+	default:
+		expr = append(expr, flagExpr("outbound"))
 	}
 
 	if len(f.InterfaceIndexes) > 0 {
-		writeInterfaceFilter(&sb, f.InterfaceIndexes)
+		interfaces := orExpr{}
+		for _, ifIdx := range f.InterfaceIndexes {
+			interfaces = append(interfaces, comparisonExpr{Field: "ifIdx", Op: opEqual, Value: strconv.Itoa(ifIdx)})
+		}
+		expr = append(expr, interfaces)
 	}
 
 	if len(f.Include) > 0 {
-		err := writeIncludeFilter(&sb, f, f.Direction)
-		if err != nil {
-			return "", err
+		includes := orExpr{}
+		for _, include := range f.Include {
+			if f.Direction != DirectionIncoming {
+				dst, err := matchExpr(include, "Dst", f.protocols())
+				if err != nil {
+					return nil, err
+				}
+				includes = append(includes, dst)
+			}
+			if f.Direction == DirectionIncoming || f.Direction == DirectionAll {
+				src, err := matchExpr(include, "Src", f.protocols())
+				if err != nil {
+					return nil, err
+				}
+				includes = append(includes, src)
+			}
 		}
+		expr = append(expr, includes)
 	}
 
-	if len(f.Exclude) > 0 {
-		err := writeExcludeFilter(&sb, f)
-		if err != nil {
-			return "", err
+	if f.Process != "" {
+		expr = append(expr, localPortsExpr(f.LocalPorts, f.Direction, f.protocols()))
+	}
+
+	// excluded traffic is exempted in both directions
+	for _, exclude := range f.Exclude {
+		for _, side := range []string{"Dst", "Src"} {
+			exemption, err := exemptionExpr(exclude, side, f.protocols())
+			if err != nil {
+				return nil, err
+			}
+			expr = append(expr, exemption)
 		}
 	}
 
-	return sb.String(), nil
+	return expr, nil
 }
 
-func writeDirectionFilter(sb *strings.Builder, direction Direction) {
-	sb.WriteString(" and ")
-	if direction == DirectionIncoming {
-		sb.WriteString("inbound")
-	} else {
-		sb.WriteString("outbound")
+// matchExpr matches packets with the destination or source ("Dst" or "Src") in the network and port range.
+func matchExpr(nwp akn.NetWithPortRange, side string, protocols []Protocol) (filterExpression, error) {
+	addr, err := ipRangeExpr(side+"Addr", nwp.Net)
+	if err != nil {
+		return nil, err
 	}
+	return andExpr{addr, portMatchExpr(nwp.PortRange, side, protocols)}, nil
 }
 
-func writeInterfaceFilter(sb *strings.Builder, ifIdxs []int) {
-	sb.WriteString(openGroup)
-	ifIdxStatements := make([]string, len(ifIdxs))
-	for i, ifIdx := range ifIdxs {
-		ifIdxStatements[i] = fmt.Sprintf("ifIdx == %d", ifIdx)
+// portMatchExpr matches the port range for the protocols. ICMP has no ports, so it's matched regardless of the range.
+func portMatchExpr(portRange akn.PortRange, side string, protocols []Protocol) filterExpression {
+	expr := orExpr{}
+	for _, protocol := range protocols {
+		switch protocol {
+		case ProtocolTCP, ProtocolUDP:
+			expr = append(expr, portRangeExpr(string(protocol)+"."+side+"Port", portRange))
+		case ProtocolICMP:
+			expr = append(expr, flagExpr("icmp"), flagExpr("icmpv6"))
+		}
 	}
-	sb.WriteString(strings.Join(ifIdxStatements, " or "))
-	sb.WriteString(closeGroup)
+	return expr
 }
 
-func writeIncludeFilter(sb *strings.Builder, filter Filter, direction Direction) error {
-	replaceMap := map[string]string{
-		"tcpDstPort": "tcp.DstPort",
-		"udpDstPort": "udp.DstPort",
-		"tcpSrcPort": "tcp.SrcPort",
-		"udpSrcPort": "udp.SrcPort",
+// localPortsExpr matches packets of the local ports, which are the source ports of outgoing and the destination ports
+// of incoming packets. ICMP has no ports, so it's never matched.
+func localPortsExpr(ports []uint16, direction Direction, protocols []Protocol) filterExpression {
+	outgoing := portsMatchExpr(ports, "Src", protocols)
+	incoming := portsMatchExpr(ports, "Dst", protocols)
+	switch direction {
+	case DirectionIncoming:
+		return incoming
+	case DirectionAll:
+		return ternaryExpr{Condition: flagExpr("outbound"), Then: outgoing, Else: incoming}
+	default:
+		return outgoing
 	}
+}
 
-	sb.WriteString(openGroup)
-	for i, ran := range filter.Include {
-		family, err := getFamily(ran.Net)
-		if err != nil {
-			return err
-		}
-
-		setCorrectReplacements(&replaceMap, family)
-		if direction != DirectionIncoming {
-			var portFilter string
-
-			if ran.PortRange.From == ran.PortRange.To {
-				portFilter = fmt.Sprintf("(( {{.tcpDstPort}} == %d ) or ( {{.udpDstPort}} == %d ))",
-					ran.PortRange.From, ran.PortRange.From)
-			} else {
-				portFilter = fmt.Sprintf("(( {{.tcpDstPort}} >= %d and {{.tcpDstPort}} <= %d ) or ( {{.udpDstPort}} >= %d and {{.udpDstPort}} <= %d ))",
-					ran.PortRange.From, ran.PortRange.To, ran.PortRange.From, ran.PortRange.To)
-			}
-
-			startIp, endIp, err := getStartEndIP(ran.Net)
-			if err != nil {
-				return err
-			}
-
-			var config string
-
-			if startIp.String() == endIp.String() {
-				config = fmt.Sprintf("( {{.ipDstAddr}} == %s and %s)",
-					startIp.String(), portFilter)
-			} else {
-				config = fmt.Sprintf("( {{.ipDstAddr}} >= %s and {{.ipDstAddr}} <= %s and %s)",
-					startIp.String(), endIp.String(), portFilter)
-			}
-
-			tmpl, err := template.New("filter").Parse(config)
-			if err != nil {
-				return err
-			}
-
-			err = tmpl.Execute(sb, replaceMap)
-			if err != nil {
-				return err
-			}
-		}
-
-		if direction == DirectionAll {
-			sb.WriteString(" or ")
-		}
-
-		if direction != DirectionOutgoing {
-			var portFilter string
-
-			if ran.PortRange.From == ran.PortRange.To {
-				portFilter = fmt.Sprintf("(( {{.tcpSrcPort}} == %d ) or ( {{.udpSrcPort}} == %d ))",
-					ran.PortRange.From, ran.PortRange.From)
-			} else {
-				portFilter = fmt.Sprintf("(( {{.tcpSrcPort}} >= %d and {{.tcpSrcPort}} <= %d ) or ( {{.udpSrcPort}} >= %d and {{.udpSrcPort}} <= %d ))",
-					ran.PortRange.From, ran.PortRange.To, ran.PortRange.From, ran.PortRange.To)
-			}
-
-			startIp, endIp, err := getStartEndIP(ran.Net)
-			if err != nil {
-				return err
-			}
-
-			var config string
-
-			if startIp.String() == endIp.String() {
-				config = fmt.Sprintf("( {{.ipSrcAddr}} == %s and %s)",
-					startIp.String(), portFilter)
-			} else {
-				config = fmt.Sprintf("( {{.ipSrcAddr}} >= %s and {{.ipSrcAddr}} <= %s and %s)",
-					startIp.String(), endIp.String(), portFilter)
-			}
-
-			tmpl, err := template.New("filter").Parse(config)
-			if err != nil {
-				return err
-			}
-
-			err = tmpl.Execute(sb, replaceMap)
-			if err != nil {
-				return err
+func portsMatchExpr(ports []uint16, side string, protocols []Protocol) filterExpression {
+	expr := orExpr{}
+	for _, portRange := range toPortRanges(ports) {
+		for _, protocol := range protocols {
+			if protocol == ProtocolTCP || protocol == ProtocolUDP {
+				expr = append(expr, portRangeExpr(string(protocol)+"."+side+"Port", portRange))
 			}
 		}
-
-		if i < len(filter.Include)-1 {
-			sb.WriteString(" or ")
-		}
 	}
-	sb.WriteString(closeGroup)
-	return nil
+	return expr
 }
 
-func writeExcludeFilter(sb *strings.Builder, filter Filter) error {
-	replaceMap := map[string]string{
-		"tcpDstPort": "tcp.DstPort",
-		"udpDstPort": "udp.DstPort",
-		"tcpSrcPort": "tcp.SrcPort",
-		"udpSrcPort": "udp.SrcPort",
-	}
-
-	sb.WriteString(openGroup)
-	for i, ran := range filter.Exclude {
-		family, err := getFamily(ran.Net)
-		if err != nil {
-			return err
-		}
-
-		setCorrectReplacements(&replaceMap, family)
-
-		var portFilter string
-
-		if ran.PortRange.From == ran.PortRange.To {
-			portFilter = fmt.Sprintf("(( {{.tcpDstPort}} != %d ) or ( {{.udpDstPort}} != %d ))",
-				ran.PortRange.From, ran.PortRange.To)
-		} else {
-			portFilter = fmt.Sprintf("(( {{.tcpDstPort}} < %d or {{.tcpDstPort}} > %d ) or ( {{.udpDstPort}} < %d or {{.udpDstPort}} > %d ))",
-				ran.PortRange.From, ran.PortRange.To, ran.PortRange.From, ran.PortRange.To)
-		}
-
-		startIp, endIp, err := getStartEndIP(ran.Net)
-		if err != nil {
-			return err
-		}
-
-		var config string
+// toPortRanges merges consecutive ports into ranges.
+func toPortRanges(ports []uint16) []akn.PortRange {
+	sorted := slices.Clone(ports)
+	slices.Sort(sorted)
 
-		if startIp.String() == endIp.String() {
-			config = fmt.Sprintf("(( {{.ipDstAddr}} == %s )? %s: true)",
-				startIp.String(), portFilter)
+	var ranges []akn.PortRange
+	for _, port := range slices.Compact(sorted) {
+		if len(ranges) > 0 && ranges[len(ranges)-1].To+1 == port {
+			ranges[len(ranges)-1].To = port
 		} else {
-			config = fmt.Sprintf("(( {{.ipDstAddr}} >= %s and {{.ipDstAddr}} <= %s )? %s: true)",
-				startIp.String(), endIp.String(), portFilter)
-		}
-
-		tmpl, err := template.New("filter").Parse(config)
-		if err != nil {
-			return err
-		}
-
-		err = tmpl.Execute(sb, replaceMap)
-		if err != nil {
-			return err
-		}
-
-		sb.WriteString(" and ")
-
-		if ran.PortRange.From == ran.PortRange.To {
-			portFilter = fmt.Sprintf("(( {{.tcpSrcPort}} != %d ) or ( {{.udpSrcPort}} != %d ))",
-				ran.PortRange.From, ran.PortRange.To)
-		} else {
-			portFilter = fmt.Sprintf("(( {{.tcpSrcPort}} < %d or {{.tcpSrcPort}} > %d ) or ( {{.udpSrcPort}} < %d or {{.udpSrcPort}} > %d ))",
-				ran.PortRange.From, ran.PortRange.To, ran.PortRange.From, ran.PortRange.To)
-		}
-
-		startIp, endIp, err = getStartEndIP(ran.Net)
-		if err != nil {
-			return err
-		}
-
-		if startIp.String() == endIp.String() {
-			config = fmt.Sprintf("(( {{.ipSrcAddr}} == %s )? %s: true)",
-				startIp.String(), portFilter)
-		} else {
-			config = fmt.Sprintf("(( {{.ipSrcAddr}} >= %s and {{.ipSrcAddr}} <= %s )? %s: true)",
-				startIp.String(), endIp.String(), portFilter)
-		}
-
-		tmpl, err = template.New("filter").Parse(config)
-		if err != nil {
-			return err
-		}
-
-		err = tmpl.Execute(sb, replaceMap)
-		if err != nil {
-			return err
-		}
-
-		if i < len(filter.Exclude)-1 {
-			sb.WriteString(" and ")
+			ranges = append(ranges, akn.PortRange{From: port, To: port})
 		}
 	}
+	return ranges
+}
 
-	sb.WriteString(closeGroup)
-	return nil
+// exemptionExpr matches all packets except the ones with the destination or source in the network and port range.
+func exemptionExpr(nwp akn.NetWithPortRange, side string, protocols []Protocol) (filterExpression, error) {
+	addr, err := ipRangeExpr(side+"Addr", nwp.Net)
+	if err != nil {
+		return nil, err
+	}
+	return ternaryExpr{
+		Condition: addr,
+		Then:      notExpr{Operand: portMatchExpr(nwp.PortRange, side, protocols)},
+		Else:      boolLiteral(true),
+	}, nil
 }
 
 func buildWinDivertFilterFile(f Filter) (string, error) {
@@ -353,43 +340,3 @@ func buildWinDivertFilterFile(f Filter) (string, error) {
 	}
 	return tempFile.Name(), nil
 }
-
-func awaitWinDivertServiceStatus(state svc.State, timeout time.Duration) error {
-	// wait until the windivert service reports successful startup or an error occurred
-	m, err := mgr.Connect()
-	if err != nil {
-		return err
-	}
-	defer func(m *mgr.Mgr) {
-		_ = m.Disconnect()
-	}(m)
-
-	end := time.Now().Add(timeout)
-	for time.Now().Before(end) {
-		s, err := m.OpenService("windivert")
-		if err != nil {
-			time.Sleep(500 * time.Millisecond)
-			log.Debug().Msgf("failed opening the windivert service with error (retrying in 500ms): %v", err)
-			continue
-		}
-
-		log.Info().Msgf("successfully opened the windivert service.")
-		// deferred function is only created once
-		//goland:noinspection GoDeferInLoop
-		defer func(s *mgr.Service) {
-			_ = s.Close()
-		}(s)
-
-		for time.Now().Before(end) {
-			status, err := s.Query()
-			if err == nil && status.State == state {
-				log.Debug().Int("state", int(status.State)).Msgf("windivert service reached state %d", state)
-				return nil
-			}
-			//goland:noinspection GoDfaErrorMayBeNotNil
-			log.Debug().Int("state", int(status.State)).Msgf("windivert service not yet in state %d", state)
-			time.Sleep(100 * time.Millisecond)
-		}
-	}
-	return fmt.Errorf("windivert service did not reach state %d in time", state)
-}
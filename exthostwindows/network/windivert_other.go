@@ -0,0 +1,20 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+//go:build !windows
+
+package network
+
+import (
+	"errors"
+	"time"
+)
+
+var errWinDivertUnsupported = errors.New("the WinDivert service is only available on Windows")
+
+func awaitWinDivertServiceRunning(_ time.Duration) error {
+	return errWinDivertUnsupported
+}
+
+func awaitWinDivertServiceStopped(_ time.Duration) error {
+	return errWinDivertUnsupported
+}
@@ -4,7 +4,12 @@
 package network
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	akn "github.com/steadybit/action-kit/go/action_kit_commons/network"
 
@@ -51,7 +56,7 @@ func TestWinDivertBuildFilterInclude(t *testing.T) {
 	filter, err := buildWinDivertFilter(f)
 	assert.NoError(t, err)
 
-	assert.Equal(t, "(tcp or udp) and outbound and (( ip.DstAddr >= 1.1.1.0 and ip.DstAddr <= 1.1.1.255 and (( tcp.DstPort >= 8000 and tcp.DstPort <= 8002 ) or ( udp.DstPort >= 8000 and udp.DstPort <= 8002 ))))", filter)
+	assert.Equal(t, "(tcp or udp) and outbound and ip.DstAddr >= 1.1.1.0 and ip.DstAddr <= 1.1.1.255 and ((tcp.DstPort >= 8000 and tcp.DstPort <= 8002) or (udp.DstPort >= 8000 and udp.DstPort <= 8002))", filter)
 }
 
 func TestWinDivertBuildFilterIncludeInbound(t *testing.T) {
@@ -71,7 +76,7 @@ func TestWinDivertBuildFilterIncludeInbound(t *testing.T) {
 	filter, err := buildWinDivertFilter(f)
 	assert.NoError(t, err)
 
-	assert.Equal(t, "(tcp or udp) and inbound and (( ip.SrcAddr >= 1.1.1.0 and ip.SrcAddr <= 1.1.1.255 and (( tcp.SrcPort >= 8000 and tcp.SrcPort <= 8002 ) or ( udp.SrcPort >= 8000 and udp.SrcPort <= 8002 ))))", filter)
+	assert.Equal(t, "(tcp or udp) and inbound and ip.SrcAddr >= 1.1.1.0 and ip.SrcAddr <= 1.1.1.255 and ((tcp.SrcPort >= 8000 and tcp.SrcPort <= 8002) or (udp.SrcPort >= 8000 and udp.SrcPort <= 8002))", filter)
 }
 
 func TestWinDivertBuildFilterIncludeInOut(t *testing.T) {
@@ -91,7 +96,7 @@ func TestWinDivertBuildFilterIncludeInOut(t *testing.T) {
 	filter, err := buildWinDivertFilter(f)
 	assert.NoError(t, err)
 
-	assert.Equal(t, "(tcp or udp) and (( ip.DstAddr >= 1.1.1.0 and ip.DstAddr <= 1.1.1.255 and (( tcp.DstPort >= 8000 and tcp.DstPort <= 8002 ) or ( udp.DstPort >= 8000 and udp.DstPort <= 8002 ))) or ( ip.SrcAddr >= 1.1.1.0 and ip.SrcAddr <= 1.1.1.255 and (( tcp.SrcPort >= 8000 and tcp.SrcPort <= 8002 ) or ( udp.SrcPort >= 8000 and udp.SrcPort <= 8002 ))))", filter)
+	assert.Equal(t, "(tcp or udp) and ((ip.DstAddr >= 1.1.1.0 and ip.DstAddr <= 1.1.1.255 and ((tcp.DstPort >= 8000 and tcp.DstPort <= 8002) or (udp.DstPort >= 8000 and udp.DstPort <= 8002))) or (ip.SrcAddr >= 1.1.1.0 and ip.SrcAddr <= 1.1.1.255 and ((tcp.SrcPort >= 8000 and tcp.SrcPort <= 8002) or (udp.SrcPort >= 8000 and udp.SrcPort <= 8002))))", filter)
 }
 
 func TestWinDivertBuildFilterIncludeIpv6(t *testing.T) {
@@ -111,7 +116,7 @@ func TestWinDivertBuildFilterIncludeIpv6(t *testing.T) {
 	filter, err := buildWinDivertFilter(f)
 	assert.NoError(t, err)
 
-	assert.Equal(t, "(tcp or udp) and outbound and (( ipv6.DstAddr >= :: and ipv6.DstAddr <= ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff and (( tcp.DstPort >= 8000 and tcp.DstPort <= 8002 ) or ( udp.DstPort >= 8000 and udp.DstPort <= 8002 ))))", filter)
+	assert.Equal(t, "(tcp or udp) and outbound and ipv6 and ((tcp.DstPort >= 8000 and tcp.DstPort <= 8002) or (udp.DstPort >= 8000 and udp.DstPort <= 8002))", filter)
 }
 
 func TestWinDivertBuildFilterExclude(t *testing.T) {
@@ -140,7 +145,7 @@ func TestWinDivertBuildFilterExclude(t *testing.T) {
 	filter, err := buildWinDivertFilter(f)
 	assert.NoError(t, err)
 
-	assert.Equal(t, "(tcp or udp) and outbound and (( ip.DstAddr >= 1.1.1.0 and ip.DstAddr <= 1.1.1.255 and (( tcp.DstPort >= 8000 and tcp.DstPort <= 8002 ) or ( udp.DstPort >= 8000 and udp.DstPort <= 8002 )))) and ((( ip.DstAddr == 1.1.1.0 )? (( tcp.DstPort < 8000 or tcp.DstPort > 8002 ) or ( udp.DstPort < 8000 or udp.DstPort > 8002 )): true) and (( ip.SrcAddr == 1.1.1.0 )? (( tcp.SrcPort < 8000 or tcp.SrcPort > 8002 ) or ( udp.SrcPort < 8000 or udp.SrcPort > 8002 )): true))", filter)
+	assert.Equal(t, "(tcp or udp) and outbound and ip.DstAddr >= 1.1.1.0 and ip.DstAddr <= 1.1.1.255 and ((tcp.DstPort >= 8000 and tcp.DstPort <= 8002) or (udp.DstPort >= 8000 and udp.DstPort <= 8002)) and (ip.DstAddr == 1.1.1.0 ? not ((tcp.DstPort >= 8000 and tcp.DstPort <= 8002) or (udp.DstPort >= 8000 and udp.DstPort <= 8002)) : true) and (ip.SrcAddr == 1.1.1.0 ? not ((tcp.SrcPort >= 8000 and tcp.SrcPort <= 8002) or (udp.SrcPort >= 8000 and udp.SrcPort <= 8002)) : true)", filter)
 }
 
 func TestWinDivertBuildFilterMultipleExcludes(t *testing.T) {
@@ -176,7 +181,7 @@ func TestWinDivertBuildFilterMultipleExcludes(t *testing.T) {
 	filter, err := buildWinDivertFilter(f)
 	assert.NoError(t, err)
 
-	assert.Equal(t, "(tcp or udp) and outbound and (( ip.DstAddr >= 1.1.1.0 and ip.DstAddr <= 1.1.1.255 and (( tcp.DstPort >= 8000 and tcp.DstPort <= 8002 ) or ( udp.DstPort >= 8000 and udp.DstPort <= 8002 )))) and ((( ip.DstAddr == 1.1.1.0 )? (( tcp.DstPort < 8000 or tcp.DstPort > 8002 ) or ( udp.DstPort < 8000 or udp.DstPort > 8002 )): true) and (( ip.SrcAddr == 1.1.1.0 )? (( tcp.SrcPort < 8000 or tcp.SrcPort > 8002 ) or ( udp.SrcPort < 8000 or udp.SrcPort > 8002 )): true) and (( ip.DstAddr == 1.1.1.1 )? (( tcp.DstPort < 8000 or tcp.DstPort > 8002 ) or ( udp.DstPort < 8000 or udp.DstPort > 8002 )): true) and (( ip.SrcAddr == 1.1.1.1 )? (( tcp.SrcPort < 8000 or tcp.SrcPort > 8002 ) or ( udp.SrcPort < 8000 or udp.SrcPort > 8002 )): true))", filter)
+	assert.Equal(t, "(tcp or udp) and outbound and ip.DstAddr >= 1.1.1.0 and ip.DstAddr <= 1.1.1.255 and ((tcp.DstPort >= 8000 and tcp.DstPort <= 8002) or (udp.DstPort >= 8000 and udp.DstPort <= 8002)) and (ip.DstAddr == 1.1.1.0 ? not ((tcp.DstPort >= 8000 and tcp.DstPort <= 8002) or (udp.DstPort >= 8000 and udp.DstPort <= 8002)) : true) and (ip.SrcAddr == 1.1.1.0 ? not ((tcp.SrcPort >= 8000 and tcp.SrcPort <= 8002) or (udp.SrcPort >= 8000 and udp.SrcPort <= 8002)) : true) and (ip.DstAddr == 1.1.1.1 ? not ((tcp.DstPort >= 8000 and tcp.DstPort <= 8002) or (udp.DstPort >= 8000 and udp.DstPort <= 8002)) : true) and (ip.SrcAddr == 1.1.1.1 ? not ((tcp.SrcPort >= 8000 and tcp.SrcPort <= 8002) or (udp.SrcPort >= 8000 and udp.SrcPort <= 8002)) : true)", filter)
 }
 
 func TestWinDivertBuildFilterMultipleIncludes(t *testing.T) {
@@ -212,7 +217,7 @@ func TestWinDivertBuildFilterMultipleIncludes(t *testing.T) {
 	filter, err := buildWinDivertFilter(f)
 	assert.NoError(t, err)
 
-	assert.Equal(t, "(tcp or udp) and outbound and (( ip.DstAddr >= 1.1.1.0 and ip.DstAddr <= 1.1.1.255 and (( tcp.DstPort >= 8000 and tcp.DstPort <= 8002 ) or ( udp.DstPort >= 8000 and udp.DstPort <= 8002 ))) or ( ip.DstAddr >= 1.1.2.0 and ip.DstAddr <= 1.1.2.255 and (( tcp.DstPort >= 8000 and tcp.DstPort <= 8002 ) or ( udp.DstPort >= 8000 and udp.DstPort <= 8002 )))) and ((( ip.DstAddr == 1.1.1.0 )? (( tcp.DstPort < 8000 or tcp.DstPort > 8002 ) or ( udp.DstPort < 8000 or udp.DstPort > 8002 )): true) and (( ip.SrcAddr == 1.1.1.0 )? (( tcp.SrcPort < 8000 or tcp.SrcPort > 8002 ) or ( udp.SrcPort < 8000 or udp.SrcPort > 8002 )): true))", filter)
+	assert.Equal(t, "(tcp or udp) and outbound and ((ip.DstAddr >= 1.1.1.0 and ip.DstAddr <= 1.1.1.255 and ((tcp.DstPort >= 8000 and tcp.DstPort <= 8002) or (udp.DstPort >= 8000 and udp.DstPort <= 8002))) or (ip.DstAddr >= 1.1.2.0 and ip.DstAddr <= 1.1.2.255 and ((tcp.DstPort >= 8000 and tcp.DstPort <= 8002) or (udp.DstPort >= 8000 and udp.DstPort <= 8002)))) and (ip.DstAddr == 1.1.1.0 ? not ((tcp.DstPort >= 8000 and tcp.DstPort <= 8002) or (udp.DstPort >= 8000 and udp.DstPort <= 8002)) : true) and (ip.SrcAddr == 1.1.1.0 ? not ((tcp.SrcPort >= 8000 and tcp.SrcPort <= 8002) or (udp.SrcPort >= 8000 and udp.SrcPort <= 8002)) : true)", filter)
 }
 
 func TestWinDivertBuildFilterOnlyExclude(t *testing.T) {
@@ -232,7 +237,7 @@ func TestWinDivertBuildFilterOnlyExclude(t *testing.T) {
 	filter, err := buildWinDivertFilter(f)
 	assert.NoError(t, err)
 
-	assert.Equal(t, "(tcp or udp) and outbound and ((( ip.DstAddr == 1.1.1.14 )? (( tcp.DstPort < 8000 or tcp.DstPort > 8002 ) or ( udp.DstPort < 8000 or udp.DstPort > 8002 )): true) and (( ip.SrcAddr == 1.1.1.14 )? (( tcp.SrcPort < 8000 or tcp.SrcPort > 8002 ) or ( udp.SrcPort < 8000 or udp.SrcPort > 8002 )): true))", filter)
+	assert.Equal(t, "(tcp or udp) and outbound and (ip.DstAddr == 1.1.1.14 ? not ((tcp.DstPort >= 8000 and tcp.DstPort <= 8002) or (udp.DstPort >= 8000 and udp.DstPort <= 8002)) : true) and (ip.SrcAddr == 1.1.1.14 ? not ((tcp.SrcPort >= 8000 and tcp.SrcPort <= 8002) or (udp.SrcPort >= 8000 and udp.SrcPort <= 8002)) : true)", filter)
 }
 
 func TestWinDivertBuildFilterInterfaces(t *testing.T) {
@@ -262,7 +267,7 @@ func TestWinDivertBuildFilterInterfacesAndIncludeOutgoing(t *testing.T) {
 	filter, err := buildWinDivertFilter(f)
 	assert.NoError(t, err)
 
-	assert.Equal(t, "(tcp or udp) and outbound and (ifIdx == 1 or ifIdx == 2 or ifIdx == 3) and ((( ip.DstAddr == 1.1.1.14 )? (( tcp.DstPort < 8000 or tcp.DstPort > 8002 ) or ( udp.DstPort < 8000 or udp.DstPort > 8002 )): true) and (( ip.SrcAddr == 1.1.1.14 )? (( tcp.SrcPort < 8000 or tcp.SrcPort > 8002 ) or ( udp.SrcPort < 8000 or udp.SrcPort > 8002 )): true))", filter)
+	assert.Equal(t, "(tcp or udp) and outbound and (ifIdx == 1 or ifIdx == 2 or ifIdx == 3) and (ip.DstAddr == 1.1.1.14 ? not ((tcp.DstPort >= 8000 and tcp.DstPort <= 8002) or (udp.DstPort >= 8000 and udp.DstPort <= 8002)) : true) and (ip.SrcAddr == 1.1.1.14 ? not ((tcp.SrcPort >= 8000 and tcp.SrcPort <= 8002) or (udp.SrcPort >= 8000 and udp.SrcPort <= 8002)) : true)", filter)
 }
 
 func TestWinDivertBuildFilterInterfacesAndIncludeInbound(t *testing.T) {
@@ -283,7 +288,7 @@ func TestWinDivertBuildFilterInterfacesAndIncludeInbound(t *testing.T) {
 	filter, err := buildWinDivertFilter(f)
 	assert.NoError(t, err)
 
-	assert.Equal(t, "(tcp or udp) and inbound and (ifIdx == 1 or ifIdx == 2 or ifIdx == 3) and ((( ip.DstAddr == 1.1.1.14 )? (( tcp.DstPort < 8000 or tcp.DstPort > 8002 ) or ( udp.DstPort < 8000 or udp.DstPort > 8002 )): true) and (( ip.SrcAddr == 1.1.1.14 )? (( tcp.SrcPort < 8000 or tcp.SrcPort > 8002 ) or ( udp.SrcPort < 8000 or udp.SrcPort > 8002 )): true))", filter)
+	assert.Equal(t, "(tcp or udp) and inbound and (ifIdx == 1 or ifIdx == 2 or ifIdx == 3) and (ip.DstAddr == 1.1.1.14 ? not ((tcp.DstPort >= 8000 and tcp.DstPort <= 8002) or (udp.DstPort >= 8000 and udp.DstPort <= 8002)) : true) and (ip.SrcAddr == 1.1.1.14 ? not ((tcp.SrcPort >= 8000 and tcp.SrcPort <= 8002) or (udp.SrcPort >= 8000 and udp.SrcPort <= 8002)) : true)", filter)
 }
 
 func TestWinDivertBuildFilterDirection(t *testing.T) {
@@ -310,6 +315,277 @@ func TestWinDivertBuildFilterDirection(t *testing.T) {
 		filter, _ := buildWinDivertFilter(Filter{
 			Direction: DirectionAll,
 		})
-		assert.Equal(t, "(tcp or udp)", filter)
+		assert.Equal(t, "tcp or udp", filter)
 	})
 }
+
+func TestWinDivertBuildFilterRoundTrip(t *testing.T) {
+	include, err := akn.ParseCIDR("1.1.1.1/24")
+	require.NoError(t, err)
+	includeV6, err := akn.ParseCIDR("fd00::1/64")
+	require.NoError(t, err)
+	exclude, err := akn.ParseCIDR("1.1.1.14")
+	require.NoError(t, err)
+
+	for _, direction := range []Direction{"", DirectionOutgoing, DirectionIncoming, DirectionAll} {
+		t.Run(string(direction), func(t *testing.T) {
+			filter, err := buildWinDivertFilter(Filter{
+				Direction:        direction,
+				InterfaceIndexes: []int{1, 2},
+				Include: []akn.NetWithPortRange{
+					{Net: *include, PortRange: akn.PortRange{From: 8000, To: 8002}},
+					{Net: *includeV6, PortRange: akn.PortRange{From: 443, To: 443}},
+				},
+				Exclude: []akn.NetWithPortRange{
+					{Net: *exclude, PortRange: akn.PortRange{From: 8000, To: 8000}},
+				},
+			})
+			require.NoError(t, err)
+
+			parsed, err := parseFilter(filter)
+			require.NoError(t, err)
+			assert.Equal(t, filter, renderFilter(parsed))
+		})
+	}
+}
+
+func TestWinDivertBuildFilterProtocols(t *testing.T) {
+	net1, err := akn.ParseCIDR("1.1.1.1/24")
+	require.NoError(t, err)
+	exemptNet, err := akn.ParseCIDR("1.1.1.14")
+	require.NoError(t, err)
+	include := []akn.NetWithPortRange{{Net: *net1, PortRange: akn.PortRange{From: 53, To: 53}}}
+	exclude := []akn.NetWithPortRange{{Net: *exemptNet, PortRange: akn.PortRange{From: 8000, To: 8002}}}
+
+	t.Run("udp", func(t *testing.T) {
+		filter, err := buildWinDivertFilter(Filter{Direction: DirectionOutgoing, Include: include, Protocols: []Protocol{ProtocolUDP}})
+		assert.NoError(t, err)
+		assert.Equal(t, "udp and outbound and ip.DstAddr >= 1.1.1.0 and ip.DstAddr <= 1.1.1.255 and udp.DstPort == 53", filter)
+	})
+
+	t.Run("tcp incoming", func(t *testing.T) {
+		filter, err := buildWinDivertFilter(Filter{Direction: DirectionIncoming, Include: include, Protocols: []Protocol{ProtocolTCP}})
+		assert.NoError(t, err)
+		assert.Equal(t, "tcp and inbound and ip.SrcAddr >= 1.1.1.0 and ip.SrcAddr <= 1.1.1.255 and tcp.SrcPort == 53", filter)
+	})
+
+	t.Run("icmp ignores ports", func(t *testing.T) {
+		filter, err := buildWinDivertFilter(Filter{Direction: DirectionOutgoing, Include: include, Protocols: []Protocol{ProtocolICMP}})
+		assert.NoError(t, err)
+		assert.Equal(t, "(icmp or icmpv6) and outbound and ip.DstAddr >= 1.1.1.0 and ip.DstAddr <= 1.1.1.255", filter)
+	})
+
+	t.Run("icmp and udp", func(t *testing.T) {
+		filter, err := buildWinDivertFilter(Filter{Direction: DirectionOutgoing, Include: include, Protocols: []Protocol{ProtocolUDP, ProtocolICMP}})
+		assert.NoError(t, err)
+		assert.Equal(t, "(udp or icmp or icmpv6) and outbound and ip.DstAddr >= 1.1.1.0 and ip.DstAddr <= 1.1.1.255 and (udp.DstPort == 53 or icmp or icmpv6)", filter)
+	})
+
+	t.Run("icmp to excluded address is exempted", func(t *testing.T) {
+		filter, err := buildWinDivertFilter(Filter{Direction: DirectionOutgoing, Exclude: exclude, Protocols: []Protocol{ProtocolICMP}})
+		assert.NoError(t, err)
+		assert.Equal(t, "(icmp or icmpv6) and outbound and (ip.DstAddr == 1.1.1.14 ? not (icmp or icmpv6) : true) and (ip.SrcAddr == 1.1.1.14 ? not (icmp or icmpv6) : true)", filter)
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		filter, err := buildWinDivertFilter(Filter{Direction: DirectionAll, Include: include, Exclude: exclude, Protocols: []Protocol{ProtocolTCP, ProtocolICMP}})
+		require.NoError(t, err)
+		parsed, err := parseFilter(filter)
+		require.NoError(t, err)
+		assert.Equal(t, filter, renderFilter(parsed))
+	})
+}
+
+func TestParseProtocols(t *testing.T) {
+	protocols, err := ParseProtocols([]string{"UDP", " icmp", "", "udp"})
+	require.NoError(t, err)
+	assert.Equal(t, []Protocol{ProtocolUDP, ProtocolICMP}, protocols)
+
+	protocols, err = ParseProtocols(nil)
+	require.NoError(t, err)
+	assert.Empty(t, protocols)
+
+	_, err = ParseProtocols([]string{"sctp"})
+	assert.ErrorContains(t, err, "unsupported protocol \"sctp\"")
+}
+
+func TestWinDivertBuildFilterProcess(t *testing.T) {
+	t.Run("outgoing", func(t *testing.T) {
+		filter, err := buildWinDivertFilter(Filter{Direction: DirectionOutgoing, Process: "sqlservr.exe", LocalPorts: []uint16{1433, 50001, 50002, 50003}})
+		assert.NoError(t, err)
+		assert.Equal(t, "(tcp or udp) and outbound and (tcp.SrcPort == 1433 or udp.SrcPort == 1433 or (tcp.SrcPort >= 50001 and tcp.SrcPort <= 50003) or (udp.SrcPort >= 50001 and udp.SrcPort <= 50003))", filter)
+	})
+
+	t.Run("incoming tcp", func(t *testing.T) {
+		filter, err := buildWinDivertFilter(Filter{Direction: DirectionIncoming, Protocols: []Protocol{ProtocolTCP, ProtocolICMP}, Process: "sqlservr.exe", LocalPorts: []uint16{1433}})
+		assert.NoError(t, err)
+		assert.Equal(t, "(tcp or icmp or icmpv6) and inbound and tcp.DstPort == 1433", filter)
+	})
+
+	t.Run("all directions", func(t *testing.T) {
+		filter, err := buildWinDivertFilter(Filter{Direction: DirectionAll, Protocols: []Protocol{ProtocolUDP}, Process: "1234", LocalPorts: []uint16{53}})
+		assert.NoError(t, err)
+		assert.Equal(t, "udp and (outbound ? udp.SrcPort == 53 : udp.DstPort == 53)", filter)
+
+		parsed, err := parseFilter(filter)
+		require.NoError(t, err)
+		assert.Equal(t, filter, renderFilter(parsed))
+	})
+
+	t.Run("without ports", func(t *testing.T) {
+		filter, err := buildWinDivertFilter(Filter{Direction: DirectionOutgoing, Process: "sqlservr.exe"})
+		assert.NoError(t, err)
+		assert.Equal(t, "false", filter)
+	})
+}
+
+func TestToPortRanges(t *testing.T) {
+	assert.Equal(t, []akn.PortRange{{From: 80, To: 81}, {From: 443, To: 443}, {From: 65535, To: 65535}}, toPortRanges([]uint16{443, 81, 80, 65535, 80}))
+	assert.Empty(t, toPortRanges(nil))
+}
+
+func TestWinDivertCommandsDuplicateAndReorder(t *testing.T) {
+	filter := Filter{Include: akn.NewNetWithPortRanges(akn.NetAny, akn.PortRangeAny), Direction: DirectionOutgoing}
+
+	tests := []struct {
+		name string
+		opts interface {
+			WinOpts
+			winDivertAttack
+		}
+		wantArgs string
+	}{
+		{
+			name:     "duplicate",
+			opts:     &DuplicateOpts{Filter: filter, Duplication: 20, Correlation: 25, Duration: 30 * time.Second},
+			wantArgs: "--mode=duplicate --duration=30 --percentage=20 --correlation=25",
+		},
+		{
+			name:     "reorder",
+			opts:     &ReorderOpts{Filter: filter, Reorder: 10, Correlation: 0, Duration: 30 * time.Second},
+			wantArgs: "--mode=reorder --duration=30 --percentage=10 --correlation=0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmds, err := tt.opts.WinDivertCommands(ModeAdd)
+			require.NoError(t, err)
+			instance := tt.opts.winDivertInstance()
+			defer instance.removeFilterFile()
+			require.Len(t, cmds, 1)
+			assert.Contains(t, wdnaArgs(t, cmds[0]), tt.wantArgs)
+			assert.Contains(t, wdnaArgs(t, cmds[0]), instance.FilterFile)
+
+			instance.Pid = 4711
+			cmds, err = tt.opts.WinDivertCommands(ModeDelete)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"Get-Process -Id 4711 -ErrorAction SilentlyContinue | Where-Object { $_.ProcessName -eq 'wdna' } | Stop-Process -Force"}, cmds)
+		})
+	}
+}
+
+func TestWinDivertCommandsTcpReset(t *testing.T) {
+	filter := Filter{Include: akn.NewNetWithPortRanges(akn.NetAny, akn.PortRangeAny), Direction: DirectionOutgoing, Protocols: []Protocol{ProtocolTCP}}
+
+	t.Run("once", func(t *testing.T) {
+		opts := &TcpResetOpts{Filter: filter, Duration: 30 * time.Second}
+		cmds, err := opts.WinDivertCommands(ModeAdd)
+		require.NoError(t, err)
+		defer opts.removeFilterFile()
+		require.Len(t, cmds, 1)
+		assert.Contains(t, wdnaArgs(t, cmds[0]), "--mode=reset --duration=30 --interval=0")
+	})
+
+	t.Run("interval", func(t *testing.T) {
+		opts := &TcpResetOpts{Filter: filter, Interval: 5 * time.Second, Duration: 30 * time.Second}
+		cmds, err := opts.WinDivertCommands(ModeAdd)
+		require.NoError(t, err)
+		defer opts.removeFilterFile()
+		require.Len(t, cmds, 1)
+		assert.Contains(t, wdnaArgs(t, cmds[0]), "--mode=reset --duration=30 --interval=5000")
+	})
+
+	t.Run("only tcp", func(t *testing.T) {
+		opts := &TcpResetOpts{Filter: Filter{Include: filter.Include, Protocols: []Protocol{ProtocolTCP, ProtocolUDP}}, Duration: 30 * time.Second}
+		_, err := opts.WinDivertCommands(ModeAdd)
+		assert.EqualError(t, err, "only tcp connections can be reset")
+	})
+}
+
+func TestWinDivertCommandsLimitBandwidth(t *testing.T) {
+	filter := Filter{Include: akn.NewNetWithPortRanges(akn.NetAny, akn.PortRangeAny), Direction: DirectionIncoming}
+
+	opts := &LimitBandwidthWinDivertOpts{Filter: filter, BitsPerSecond: 8_000_000, Duration: 30 * time.Second}
+	cmds, err := opts.WinDivertCommands(ModeAdd)
+	require.NoError(t, err)
+	defer opts.removeFilterFile()
+	require.Len(t, cmds, 1)
+	assert.Contains(t, wdnaArgs(t, cmds[0]), "--mode=bandwidth --duration=30 --rate=8000000 --burst=100000")
+
+	// the bucket holds at least one full-sized packet
+	opts = &LimitBandwidthWinDivertOpts{Filter: filter, BitsPerSecond: 8_000, Duration: 30 * time.Second}
+	assert.Equal(t, uint64(minBandwidthBurst), opts.burst())
+
+	opts = &LimitBandwidthWinDivertOpts{Filter: filter, Duration: 30 * time.Second}
+	_, err = opts.WinDivertCommands(ModeAdd)
+	assert.EqualError(t, err, "bandwidth must be greater than 0")
+}
+
+// wdnaArgs returns the arguments the start command passes to wdna.
+func wdnaArgs(t *testing.T, command string) string {
+	encoded := regexp.MustCompile(`FromBase64String\('([^']*)'\)`).FindStringSubmatch(command)
+	require.NotNil(t, encoded, "not a start command: %s", command)
+	params, err := base64.StdEncoding.DecodeString(encoded[1])
+	require.NoError(t, err)
+	var start struct {
+		FilePath     string
+		ArgumentList []string
+	}
+	require.NoError(t, json.Unmarshal(params, &start))
+	assert.Equal(t, "wdna.exe", start.FilePath)
+	return strings.Join(start.ArgumentList, " ")
+}
+
+func TestWinDivertStartCommand(t *testing.T) {
+	instance := &WinDivertInstance{FilterFile: "C:\\Temp\\x'; Stop-Computer; '.txt"}
+
+	cmd, err := instance.startCommand("--mode=drop", "--percentage=100")
+	require.NoError(t, err)
+	assert.NotContains(t, cmd, "Stop-Computer")
+	assert.Contains(t, cmd, "Start-Process @params | Select-Object -Property Id")
+	assert.Equal(t, `--file="C:\\Temp\\x'; Stop-Computer; '.txt" --mode=drop --percentage=100`, wdnaArgs(t, cmd))
+}
+
+func TestParseWdnaPid(t *testing.T) {
+	pid, err := parseWdnaPid(`[{"Id":4711}]`)
+	require.NoError(t, err)
+	assert.Equal(t, 4711, pid)
+
+	pid, err = parseWdnaPid(`{"Id":4711}`)
+	require.NoError(t, err)
+	assert.Equal(t, 4711, pid)
+
+	_, err = parseWdnaPid("")
+	assert.ErrorContains(t, err, "failed to determine process id of wdna instance")
+	_, err = parseWdnaPid("Start-Process : This command cannot be run")
+	assert.ErrorContains(t, err, "failed to determine process id of wdna instance")
+}
+
+func TestWinDivertStopCommands(t *testing.T) {
+	started := &WinDivertInstance{FilterFile: "C:\\Temp\\x'; Stop-Computer; '.txt", Pid: 4711}
+	assert.Equal(t, []string{"Get-Process -Id 4711 -ErrorAction SilentlyContinue | Where-Object { $_.ProcessName -eq 'wdna' } | Stop-Process -Force"}, started.stopCommands())
+
+	// without the process id only the instance started with the filter file is stopped
+	unknownPid := &WinDivertInstance{FilterFile: started.FilterFile}
+	cmds := unknownPid.stopCommands()
+	require.Len(t, cmds, 1)
+	assert.NotContains(t, cmds[0], "Stop-Computer")
+	assert.Contains(t, cmds[0], "$_.CommandLine.Contains($fileArg)")
+	encoded := regexp.MustCompile(`FromBase64String\('([^']*)'\)`).FindStringSubmatch(cmds[0])
+	require.NotNil(t, encoded)
+	fileArg, err := base64.StdEncoding.DecodeString(encoded[1])
+	require.NoError(t, err)
+	assert.Equal(t, started.fileArg(), string(fileArg))
+
+	assert.Empty(t, (&WinDivertInstance{}).stopCommands())
+}
@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2025 Steadybit GmbH
+
+package network
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+func awaitWinDivertServiceRunning(timeout time.Duration) error {
+	return awaitWinDivertServiceStatus(svc.Running, timeout)
+}
+
+func awaitWinDivertServiceStopped(timeout time.Duration) error {
+	return awaitWinDivertServiceStatus(svc.Stopped, timeout)
+}
+
+func awaitWinDivertServiceStatus(state svc.State, timeout time.Duration) error {
+	// wait until the windivert service reports successful startup or an error occurred
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer func(m *mgr.Mgr) {
+		_ = m.Disconnect()
+	}(m)
+
+	end := time.Now().Add(timeout)
+	for time.Now().Before(end) {
+		s, err := m.OpenService("windivert")
+		if err != nil {
+			time.Sleep(500 * time.Millisecond)
+			log.Debug().Msgf("failed opening the windivert service with error (retrying in 500ms): %v", err)
+			continue
+		}
+
+		log.Info().Msgf("successfully opened the windivert service.")
+		// deferred function is only created once
+		//goland:noinspection GoDeferInLoop
+		defer func(s *mgr.Service) {
+			_ = s.Close()
+		}(s)
+
+		for time.Now().Before(end) {
+			status, err := s.Query()
+			if err == nil && status.State == state {
+				log.Debug().Int("state", int(status.State)).Msgf("windivert service reached state %d", state)
+				return nil
+			}
+			//goland:noinspection GoDfaErrorMayBeNotNil
+			log.Debug().Int("state", int(status.State)).Msgf("windivert service not yet in state %d", state)
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+	return fmt.Errorf("windivert service did not reach state %d in time", state)
+}
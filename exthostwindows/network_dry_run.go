@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package exthostwindows
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/action-kit/go/action_kit_sdk"
+	"github.com/steadybit/extension-host-windows/exthostwindows/network"
+	extension_kit "github.com/steadybit/extension-kit"
+	"github.com/steadybit/extension-kit/exthttp"
+)
+
+const networkDryRunPath = "/network/dry-run"
+
+// NetworkDryRunRequest is a prepare request for the network action with the given id.
+type NetworkDryRunRequest struct {
+	ActionId string `json:"actionId"`
+	action_kit_api.PrepareActionRequestBody
+}
+
+type NetworkDryRunResponse struct {
+	*network.DryRunResult
+	Messages action_kit_api.Messages `json:"messages,omitempty"`
+}
+
+// RegisterNetworkDryRunHandler registers an endpoint returning the WinDivert filter and the commands a network action
+// would run for the given config. Nothing is applied.
+func RegisterNetworkDryRunHandler(actions ...action_kit_sdk.Action[NetworkActionState]) {
+	exthttp.RegisterHttpHandler(networkDryRunPath, networkDryRunHandler(actions))
+}
+
+func networkDryRunHandler(actions []action_kit_sdk.Action[NetworkActionState]) exthttp.Handler {
+	return func(w http.ResponseWriter, r *http.Request, body []byte) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		var request NetworkDryRunRequest
+		if err := json.Unmarshal(body, &request); err != nil {
+			exthttp.WriteError(w, extension_kit.ToError("Failed to parse request body.", err))
+			return
+		}
+
+		response, err := networkDryRun(r, actions, request)
+		if err != nil {
+			exthttp.WriteError(w, *extension_kit.WrapError(err))
+			return
+		}
+		exthttp.WriteBody(w, response)
+	}
+}
+
+func networkDryRun(r *http.Request, actions []action_kit_sdk.Action[NetworkActionState], request NetworkDryRunRequest) (*NetworkDryRunResponse, error) {
+	var action *networkAction
+	for _, candidate := range actions {
+		if a, ok := candidate.(*networkAction); ok && a.description.Id == request.ActionId {
+			action = a
+		}
+	}
+	if action == nil {
+		return nil, fmt.Errorf("unknown network action %q", request.ActionId)
+	}
+
+	// the attack is computed for this host, so the target may be omitted
+	if request.Target == nil {
+		hostname, err := osHostname()
+		if err != nil {
+			return nil, new(extension_kit.ToError("Failed to get hostname.", err))
+		}
+		request.Target = &action_kit_api.Target{Attributes: map[string][]string{hostNameAttribute: {hostname}}}
+	}
+
+	opts, messages, err := action.optsProvider(r.Context(), request.PrepareActionRequestBody)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := network.DryRun(r.Context(), action.runner, opts)
+	if err != nil {
+		return nil, new(extension_kit.ToError("Failed to generate network commands.", err))
+	}
+	return &NetworkDryRunResponse{DryRunResult: result, Messages: messages}, nil
+}
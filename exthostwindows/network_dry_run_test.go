@@ -0,0 +1,46 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package exthostwindows
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/steadybit/action-kit/go/action_kit_sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkDryRun(t *testing.T) {
+	osHostname = func() (string, error) {
+		return "myhostname", nil
+	}
+	handler := networkDryRunHandler([]action_kit_sdk.Action[NetworkActionState]{NewNetworkPackageLossContainerAction()})
+
+	t.Run("returns filter and commands", func(t *testing.T) {
+		body := []byte(`{"actionId": "` + BaseActionID + `.network_package_loss", "config": {"duration": 10000, "percentage": 30, "ip": ["10.0.0.1"], "port": ["443"]}}`)
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest(http.MethodPost, networkDryRunPath, nil), body)
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var response NetworkDryRunResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Contains(t, response.WinDivertFilter, "ip.DstAddr == 10.0.0.1 and (tcp.DstPort == 443 or udp.DstPort == 443)")
+		require.Len(t, response.WinDivertCommands, 1)
+		assert.Contains(t, response.WinDivertCommands[0], "Start-Process @params")
+		assert.Equal(t, "wdna.exe", response.WinDivertParameters["FilePath"])
+		assert.Subset(t, response.WinDivertParameters["ArgumentList"], []any{"--mode=drop", "--duration=10", "--percentage=30"})
+		assert.NotEmpty(t, response.Excludes)
+		assert.Empty(t, response.QoSCommands)
+	})
+
+	t.Run("unknown action", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest(http.MethodPost, networkDryRunPath, nil), []byte(`{"actionId": "unknown", "config": {}}`))
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `unknown network action \"unknown\"`)
+	})
+}
@@ -4,6 +4,7 @@
 package stopprocess
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os/exec"
@@ -11,10 +12,11 @@ import (
 
 	"github.com/mitchellh/go-ps"
 	"github.com/rs/zerolog/log"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
 	"github.com/steadybit/extension-kit/extutil"
 )
 
-func StopProcesses(pids []int, force bool) error {
+func StopProcesses(runner utils.CommandRunner, pids []int, force bool) error {
 	if len(pids) == 0 {
 		return nil
 	}
@@ -23,7 +25,7 @@ func StopProcesses(pids []int, force bool) error {
 	for _, pid := range pids {
 		if process, err := ps.FindProcess(pid); err == nil && process != nil {
 			log.Info().Int("pid", pid).Msg("Stopping process")
-			err := stopProcessWindows(pid, force)
+			err := stopProcessWindows(runner, pid, force)
 			if err != nil {
 				errs = append(errs, err.Error())
 			}
@@ -37,14 +39,14 @@ func StopProcesses(pids []int, force bool) error {
 	return nil
 }
 
-func stopProcessWindows(pid int, force bool) error {
+func stopProcessWindows(runner utils.CommandRunner, pid int, force bool) error {
 	// use absolute path to resolve untrusted search path issue reported  by Sonar
 	taskkill, err := exec.LookPath("taskkill.exe")
 	if err != nil {
 		return fmt.Errorf("fail to find taskkill.exe: %w", err)
 	}
 	if force {
-		err := exec.Command(taskkill, "/F", "/pid", fmt.Sprintf("%d", pid)).Run()
+		_, err := runner.Run(context.Background(), taskkill, "/F", "/pid", fmt.Sprintf("%d", pid))
 		if err != nil {
 			if isProcessNotFound(err) {
 				log.Debug().Int("pid", pid).Msg("process already exited")
@@ -55,7 +57,7 @@ func stopProcessWindows(pid int, force bool) error {
 		return nil
 	}
 
-	err = exec.Command(taskkill, "/pid", fmt.Sprintf("%d", pid)).Run()
+	_, err = runner.Run(context.Background(), taskkill, "/pid", fmt.Sprintf("%d", pid))
 	if err != nil {
 		if isProcessNotFound(err) {
 			log.Debug().Int("pid", pid).Msg("Process already exited")
@@ -72,6 +74,20 @@ func isProcessNotFound(err error) bool {
 }
 
 func FindProcessIds(processOrPid string) []int {
+	return findProcessIds(processOrPid, func(executable string) bool {
+		return strings.Contains(executable, processOrPid)
+	})
+}
+
+// FindProcessIdsByImageName returns the pid or the ids of the processes with the image name. Unlike FindProcessIds
+// parts of names don't match, the name is compared case-insensitively with or without the .exe extension.
+func FindProcessIdsByImageName(processOrPid string) []int {
+	return findProcessIds(processOrPid, func(executable string) bool {
+		return matchesImageName(executable, processOrPid)
+	})
+}
+
+func findProcessIds(processOrPid string, matches func(executable string) bool) []int {
 	pid := extutil.ToInt(processOrPid)
 	if pid > 0 {
 		return []int{pid}
@@ -84,9 +100,20 @@ func FindProcessIds(processOrPid string) []int {
 		return nil
 	}
 	for _, process := range processes {
-		if strings.Contains(strings.TrimSpace(process.Executable()), processOrPid) {
+		if matches(strings.TrimSpace(process.Executable())) {
 			pids = append(pids, process.Pid())
 		}
 	}
 	return pids
 }
+
+func matchesImageName(executable string, name string) bool {
+	return strings.EqualFold(trimExeExtension(executable), trimExeExtension(strings.TrimSpace(name)))
+}
+
+func trimExeExtension(name string) string {
+	if len(name) > len(".exe") && strings.EqualFold(name[len(name)-len(".exe"):], ".exe") {
+		return name[:len(name)-len(".exe")]
+	}
+	return name
+}
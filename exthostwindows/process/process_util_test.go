@@ -5,6 +5,8 @@ package stopprocess
 
 import (
 	"github.com/mitchellh/go-ps"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"os/exec"
 	"testing"
@@ -19,10 +21,20 @@ func TestStopProcesses(t *testing.T) {
 	require.Len(t, ids, 1)
 	require.Equal(t, cmd.Process.Pid, ids[0])
 
-	err = StopProcesses(ids, true)
+	err = StopProcesses(utils.NewCommandRunner(), ids, true)
 	require.NoError(t, err)
 
 	p, err := ps.FindProcess(cmd.Process.Pid)
 	require.NoError(t, err)
 	require.Nil(t, p)
 }
+
+func TestMatchesImageName(t *testing.T) {
+	assert.True(t, matchesImageName("sqlservr.exe", "sqlservr.exe"))
+	assert.True(t, matchesImageName("sqlservr.exe", "SQLSERVR"))
+	assert.True(t, matchesImageName("SQLSERVR.EXE", "sqlservr.exe"))
+	assert.True(t, matchesImageName("sqlservr", "sqlservr.exe"))
+	assert.False(t, matchesImageName("sqlservr.exe", "sql"))
+	assert.False(t, matchesImageName("sqlwriter.exe", "sqlservr.exe"))
+	assert.False(t, matchesImageName(".exe", ""))
+}
@@ -4,8 +4,11 @@
 package shutdown
 
 import (
-	"github.com/rs/zerolog/log"
+	"context"
 	"os/exec"
+
+	"github.com/rs/zerolog/log"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
 )
 
 type Command interface {
@@ -16,10 +19,12 @@ type Command interface {
 
 const shutdownExecutableName = "shutdown.exe"
 
-type CommandImpl struct{}
+type CommandImpl struct {
+	runner utils.CommandRunner
+}
 
 func NewCommand() Command {
-	return &CommandImpl{}
+	return &CommandImpl{runner: utils.NewCommandRunner()}
 }
 
 func (c *CommandImpl) IsShutdownCommandExecutable() bool {
@@ -43,7 +48,7 @@ func (c *CommandImpl) getShutdownCommand() []string {
 
 func (c *CommandImpl) Shutdown() error {
 	cmd := c.getShutdownCommand()
-	err := exec.Command(cmd[0], cmd[1:]...).Run()
+	_, err := c.runner.Run(context.Background(), cmd[0], cmd[1:]...)
 	if err != nil {
 		log.Err(err).Msg("Failed to shutdown")
 		return err
@@ -62,7 +67,7 @@ func (c *CommandImpl) getRebootCommand() []string {
 
 func (c *CommandImpl) Reboot() error {
 	cmd := c.getRebootCommand()
-	err := exec.Command(cmd[0], cmd[1:]...).Run()
+	_, err := c.runner.Run(context.Background(), cmd[0], cmd[1:]...)
 	if err != nil {
 		log.Err(err).Msg("Failed to reboot")
 		return err
@@ -0,0 +1,263 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package exthostwindows
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+	"github.com/steadybit/extension-kit/extutil"
+)
+
+// stressProcess is a process started by an attack. The actions keep them by execution id, so attacks running at the
+// same time on a host don't see or stop each other's processes.
+type stressProcess struct {
+	name    string
+	process utils.Process
+	pid     int
+	output  lockedBuffer
+
+	// done is closed once the process exited, err and exitedAt are set before.
+	done     chan struct{}
+	err      error
+	exitedAt time.Time
+}
+
+// startStressProcess starts the command and waits for it in the background. The output of the process is captured,
+// unless the command redirects it.
+func startStressProcess(runner utils.CommandRunner, cmd utils.Command) (*stressProcess, error) {
+	p := &stressProcess{
+		name: strings.TrimSuffix(filepath.Base(cmd.Name), ".exe"),
+		done: make(chan struct{}),
+	}
+	if cmd.Stdout == nil {
+		cmd.Stdout = &p.output
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = &p.output
+	}
+
+	process, err := runner.Start(cmd)
+	if err != nil {
+		return nil, err
+	}
+	p.process = process
+	p.pid = process.Pid()
+	log.Info().Str("name", p.name).Int("pid", p.pid).Msg("started stress process")
+
+	go p.wait()
+	return p, nil
+}
+
+func (p *stressProcess) wait() {
+	_, err := p.process.Wait()
+	if err != nil {
+		log.Warn().Str("name", p.name).Int("pid", p.pid).Err(err).Msg("stress process failed")
+	} else {
+		log.Info().Str("name", p.name).Int("pid", p.pid).Msg("stress process exited")
+	}
+	p.err = err
+	p.exitedAt = time.Now()
+	close(p.done)
+}
+
+func (p *stressProcess) exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// awaitExit waits up to the timeout for the process to exit on its own and reports whether it exited.
+func (p *stressProcess) awaitExit(ctx context.Context, timeout time.Duration) bool {
+	select {
+	case <-p.done:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// status reports whether the process exited. Once exited, the exit code and the output are returned as messages and a
+// failed process as error. The stoppedMessage is added if the process exited successfully.
+func (p *stressProcess) status(stoppedMessage string) *action_kit_api.StatusResult {
+	if !p.exited() {
+		return &action_kit_api.StatusResult{Completed: false}
+	}
+
+	messages := p.exitMessages()
+	if p.err != nil {
+		return &action_kit_api.StatusResult{
+			Completed: true,
+			Messages:  &messages,
+			Error: &action_kit_api.ActionKitError{
+				Title:  fmt.Sprintf("%s failed: %s", p.name, p.err),
+				Detail: extutil.Ptr(p.output.String()),
+				Status: extutil.Ptr(action_kit_api.Errored),
+			},
+		}
+	}
+
+	messages = append(messages, action_kit_api.Message{
+		Level:   extutil.Ptr(action_kit_api.Info),
+		Message: stoppedMessage,
+	})
+	return &action_kit_api.StatusResult{
+		Completed: true,
+		Messages:  &messages,
+	}
+}
+
+// stop kills the process, if it is still running, and waits for it to exit. The output is returned as message.
+func (p *stressProcess) stop(ctx context.Context, canceledMessage string) (*action_kit_api.StopResult, error) {
+	killed := false
+	if !p.exited() {
+		if err := p.process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			return nil, fmt.Errorf("failed to kill %s (pid %d): %w", p.name, p.pid, err)
+		}
+		killed = true
+	}
+
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%s (pid %d) did not exit: %w", p.name, p.pid, ctx.Err())
+	}
+
+	var messages []action_kit_api.Message
+	if killed {
+		// the exit code of a killed process carries no information
+		messages = p.outputMessages()
+	} else {
+		messages = p.exitMessages()
+	}
+	messages = append(messages, action_kit_api.Message{
+		Level:   extutil.Ptr(action_kit_api.Info),
+		Message: canceledMessage,
+	})
+	return &action_kit_api.StopResult{
+		Messages: &messages,
+	}, nil
+}
+
+func (p *stressProcess) exitMessages() []action_kit_api.Message {
+	level := action_kit_api.Info
+	if p.err != nil {
+		level = action_kit_api.Error
+	}
+	messages := []action_kit_api.Message{{
+		Level:   extutil.Ptr(level),
+		Message: fmt.Sprintf("%s (pid %d) exited with code %d.", p.name, p.pid, exitCode(p.err)),
+	}}
+	return append(messages, p.outputMessages()...)
+}
+
+func (p *stressProcess) outputMessages() []action_kit_api.Message {
+	output := strings.TrimSpace(p.output.String())
+	if output == "" {
+		return []action_kit_api.Message{}
+	}
+	return []action_kit_api.Message{{
+		Level:   extutil.Ptr(action_kit_api.Info),
+		Message: fmt.Sprintf("Output of %s (pid %d):\n%s", p.name, p.pid, output),
+	}}
+}
+
+// exitCode returns the exit code of a process that exited with err, or -1 if the process didn't exit normally.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// lockedBuffer is a bytes.Buffer that can be written by a process while being read.
+type lockedBuffer struct {
+	lock   sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buffer.String()
+}
+
+// maxStressReports limits the reports kept until they are taken.
+const maxStressReports = 600
+
+// stressReports reads the reports a stress process writes to stdout as one line of JSON each. Other lines are logged.
+type stressReports[T any] struct {
+	lock    sync.Mutex
+	partial []byte
+	pending []T
+	latest  *T
+}
+
+func (r *stressReports[T]) Write(b []byte) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.partial = append(r.partial, b...)
+	for {
+		line, rest, found := bytes.Cut(r.partial, []byte("\n"))
+		if !found {
+			break
+		}
+		r.partial = rest
+		var report T
+		if err := json.Unmarshal(line, &report); err != nil {
+			log.Info().Msgf("%s", line)
+			continue
+		}
+		r.latest = &report
+		r.pending = append(r.pending, report)
+		if len(r.pending) > maxStressReports {
+			r.pending = r.pending[len(r.pending)-maxStressReports:]
+		}
+	}
+	return len(b), nil
+}
+
+// last returns the latest report, or nil if none was written yet.
+func (r *stressReports[T]) last() *T {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.latest
+}
+
+// take returns the reports written since the previous call.
+func (r *stressReports[T]) take() []T {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	pending := r.pending
+	r.pending = nil
+	return pending
+}
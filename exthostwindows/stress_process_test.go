@@ -0,0 +1,165 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package exthostwindows
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/steadybit/action-kit/go/action_kit_api/v2"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils/utilstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProcess runs until it is killed or exit is called.
+type fakeProcess struct {
+	pid    int
+	exited chan error
+	once   sync.Once
+	killed bool
+}
+
+func newFakeProcess(pid int) *fakeProcess {
+	return &fakeProcess{pid: pid, exited: make(chan error, 1)}
+}
+
+func (p *fakeProcess) exit(err error) {
+	p.once.Do(func() { p.exited <- err })
+}
+
+func (p *fakeProcess) Wait() ([]byte, error) {
+	return nil, <-p.exited
+}
+
+func (p *fakeProcess) Kill() error {
+	p.killed = true
+	p.exit(errors.New("exit status 1"))
+	return nil
+}
+
+func (p *fakeProcess) Pid() int {
+	return p.pid
+}
+
+func newTestStressProcess(process *fakeProcess, output string) *stressProcess {
+	p := &stressProcess{name: "steadybit-stress-cpu", process: process, pid: process.pid, done: make(chan struct{})}
+	_, _ = p.output.Write([]byte(output))
+	go p.wait()
+	return p
+}
+
+func TestStressProcess_StatusOfRunningProcess(t *testing.T) {
+	process := newFakeProcess(42)
+	p := newTestStressProcess(process, "")
+	defer process.exit(nil)
+
+	assert.Equal(t, &action_kit_api.StatusResult{Completed: false}, p.status("Stress host stopped"))
+}
+
+func TestStressProcess_StatusReportsExitCodeAndOutput(t *testing.T) {
+	process := newFakeProcess(42)
+	p := newTestStressProcess(process, "done\n")
+	process.exit(nil)
+	<-p.done
+
+	result := p.status("Stress host stopped")
+	assert.True(t, result.Completed)
+	assert.Nil(t, result.Error)
+	require.NotNil(t, result.Messages)
+	assert.Equal(t, []string{
+		"steadybit-stress-cpu (pid 42) exited with code 0.",
+		"Output of steadybit-stress-cpu (pid 42):\ndone",
+		"Stress host stopped",
+	}, messageTexts(*result.Messages))
+}
+
+func TestStressProcess_StatusReportsFailure(t *testing.T) {
+	process := newFakeProcess(42)
+	p := newTestStressProcess(process, "invalid argument")
+	process.exit(errors.New("exit status 2"))
+	<-p.done
+
+	result := p.status("Stress host stopped")
+	assert.True(t, result.Completed)
+	require.NotNil(t, result.Error)
+	assert.Equal(t, "steadybit-stress-cpu failed: exit status 2", result.Error.Title)
+	assert.Equal(t, "invalid argument", *result.Error.Detail)
+	assert.Equal(t, action_kit_api.Errored, *result.Error.Status)
+}
+
+func TestStressProcess_StopKillsOnlyItsProcess(t *testing.T) {
+	action := &cpuStressAction{runner: utilstest.NewRecordingRunner()}
+	first, second := newFakeProcess(1), newFakeProcess(2)
+	firstState := &CPUStressActionState{ExecutionId: uuid.New()}
+	secondState := &CPUStressActionState{ExecutionId: uuid.New()}
+	action.processes.Store(firstState.ExecutionId, &cpuStressExecution{process: newTestStressProcess(first, ""), reports: &stressReports[cpuStressReport]{}})
+	action.processes.Store(secondState.ExecutionId, &cpuStressExecution{process: newTestStressProcess(second, ""), reports: &stressReports[cpuStressReport]{}})
+	defer second.exit(nil)
+
+	result, err := action.Stop(t.Context(), firstState)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Canceled stress host"}, messageTexts(*result.Messages))
+	assert.True(t, first.killed)
+	assert.False(t, second.killed)
+
+	status, err := action.Status(t.Context(), secondState)
+	require.NoError(t, err)
+	assert.False(t, status.Completed)
+
+	result, err = action.Stop(t.Context(), firstState)
+	require.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestStressProcess_StopAfterExit(t *testing.T) {
+	process := newFakeProcess(42)
+	p := newTestStressProcess(process, "")
+	process.exit(nil)
+	<-p.done
+
+	result, err := p.stop(t.Context(), "Canceled stress host")
+	require.NoError(t, err)
+	assert.False(t, process.killed)
+	assert.Equal(t, []string{"steadybit-stress-cpu (pid 42) exited with code 0.", "Canceled stress host"}, messageTexts(*result.Messages))
+}
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, 0, exitCode(nil))
+	assert.Equal(t, -1, exitCode(os.ErrProcessDone))
+}
+
+func TestStressProcess_StopTimesOut(t *testing.T) {
+	process := &unkillableProcess{fakeProcess: newFakeProcess(42)}
+	p := &stressProcess{name: "diskspd", process: process, pid: 42, done: make(chan struct{})}
+	go p.wait()
+	defer process.exit(nil)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	_, err := p.stop(ctx, "Canceled stress host")
+	assert.ErrorContains(t, err, "diskspd (pid 42) did not exit")
+}
+
+// unkillableProcess ignores Kill.
+type unkillableProcess struct {
+	*fakeProcess
+}
+
+func (p *unkillableProcess) Kill() error {
+	return nil
+}
+
+func messageTexts(messages []action_kit_api.Message) []string {
+	texts := make([]string, 0, len(messages))
+	for _, message := range messages {
+		texts = append(texts, message.Message)
+	}
+	return texts
+}
@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package utils
+
+// DiskCounterObject is the performance counter object a disk is sampled from.
+type DiskCounterObject string
+
+const (
+	// PhysicalDiskCounters has an instance per disk, named by the disk number and its drive letters, e.g. "0 C: D:".
+	PhysicalDiskCounters DiskCounterObject = "PhysicalDisk"
+	// LogicalDiskCounters has an instance per partition, named by its drive letter, e.g. "C:".
+	LogicalDiskCounters DiskCounterObject = "LogicalDisk"
+)
+
+// DiskIO is the IO of a disk since the previous sample.
+type DiskIO struct {
+	BytesPerSecond     float64
+	TransfersPerSecond float64
+	// AvgLatencySeconds is the average time of a transfer.
+	AvgLatencySeconds float64
+}
+
+// diskCounterInstance is the IO of an instance of a counter object.
+type diskCounterInstance struct {
+	name string
+	io   DiskIO
+}
+
+// sumDiskIO sums up the IO of the matching instances, the latency is weighted by the transfers. It returns false if no
+// instance matches.
+func sumDiskIO(instances []diskCounterInstance, match func(instance string) bool) (DiskIO, bool) {
+	var sum DiskIO
+	var latency float64
+	found := false
+	for _, instance := range instances {
+		if !match(instance.name) {
+			continue
+		}
+		found = true
+		sum.BytesPerSecond += instance.io.BytesPerSecond
+		sum.TransfersPerSecond += instance.io.TransfersPerSecond
+		latency += instance.io.AvgLatencySeconds * instance.io.TransfersPerSecond
+	}
+	if sum.TransfersPerSecond > 0 {
+		sum.AvgLatencySeconds = latency / sum.TransfersPerSecond
+	}
+	return sum, found
+}
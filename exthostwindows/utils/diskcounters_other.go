@@ -0,0 +1,23 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+//go:build !windows
+
+package utils
+
+import "errors"
+
+// DiskCounters samples the IO of disks from the performance counters, which only exist on Windows.
+type DiskCounters struct{}
+
+func OpenDiskCounters(_ DiskCounterObject, _ func(instance string) bool) (*DiskCounters, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func (c *DiskCounters) Sample() (DiskIO, error) {
+	return DiskIO{}, errors.ErrUnsupported
+}
+
+func (c *DiskCounters) Close() error {
+	return nil
+}
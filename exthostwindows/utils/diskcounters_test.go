@@ -0,0 +1,37 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiskCounters_SumDiskIO(t *testing.T) {
+	instances := []diskCounterInstance{
+		{name: "0 C:", io: DiskIO{BytesPerSecond: 100, TransfersPerSecond: 10, AvgLatencySeconds: 0.001}},
+		{name: "1 D: E:", io: DiskIO{BytesPerSecond: 300, TransfersPerSecond: 30, AvgLatencySeconds: 0.005}},
+		{name: "_Total", io: DiskIO{BytesPerSecond: 400, TransfersPerSecond: 40, AvgLatencySeconds: 0.004}},
+	}
+
+	io, found := sumDiskIO(instances, func(instance string) bool { return instance != "_Total" })
+	assert.True(t, found)
+	assert.Equal(t, 400.0, io.BytesPerSecond)
+	assert.Equal(t, 40.0, io.TransfersPerSecond)
+	assert.InDelta(t, 0.004, io.AvgLatencySeconds, 1e-9)
+
+	io, found = sumDiskIO(instances, func(instance string) bool { return strings.HasPrefix(instance, "1 ") })
+	assert.True(t, found)
+	assert.Equal(t, DiskIO{BytesPerSecond: 300, TransfersPerSecond: 30, AvgLatencySeconds: 0.005}, io)
+
+	_, found = sumDiskIO(instances, func(instance string) bool { return instance == "Z:" })
+	assert.False(t, found)
+
+	// idle disks have no latency
+	io, found = sumDiskIO([]diskCounterInstance{{name: "C:"}}, func(string) bool { return true })
+	assert.True(t, found)
+	assert.Equal(t, DiskIO{}, io)
+}
@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	pdh                             = windows.NewLazySystemDLL("pdh.dll")
+	procPdhOpenQuery                = pdh.NewProc("PdhOpenQueryW")
+	procPdhAddEnglishCounter        = pdh.NewProc("PdhAddEnglishCounterW")
+	procPdhCollectQueryData         = pdh.NewProc("PdhCollectQueryData")
+	procPdhGetFormattedCounterArray = pdh.NewProc("PdhGetFormattedCounterArrayW")
+	procPdhCloseQuery               = pdh.NewProc("PdhCloseQuery")
+)
+
+const (
+	pdhFmtDouble = 0x00000200
+	pdhMoreData  = 0x800007D2
+)
+
+// pdhCounterValueItem is PDH_FMT_COUNTERVALUE_ITEM_W with a double value, laid out like on amd64.
+type pdhCounterValueItem struct {
+	name    *uint16
+	cStatus uint32
+	_       uint32
+	value   float64
+}
+
+// DiskCounters samples the IO of disks from the performance counters. The counters are rates, each sample covers the
+// time since the previous one.
+type DiskCounters struct {
+	query       uintptr
+	bytes       uintptr
+	transfers   uintptr
+	latency     uintptr
+	match       func(instance string) bool
+	description string
+}
+
+// OpenDiskCounters opens the counters of all instances of the object, the IO of the instances matching is summed up.
+func OpenDiskCounters(object DiskCounterObject, match func(instance string) bool) (*DiskCounters, error) {
+	c := &DiskCounters{match: match, description: string(object)}
+	if err := pdhCall(procPdhOpenQuery, 0, 0, uintptr(unsafe.Pointer(&c.query))); err != nil {
+		return nil, fmt.Errorf("failed to open %s performance counters: %w", object, err)
+	}
+	for counter, handle := range map[string]*uintptr{
+		"Disk Bytes/sec":         &c.bytes,
+		"Disk Transfers/sec":     &c.transfers,
+		"Avg. Disk sec/Transfer": &c.latency,
+	} {
+		path, err := windows.UTF16PtrFromString(fmt.Sprintf(`\%s(*)\%s`, object, counter))
+		if err != nil {
+			return nil, errors.Join(err, c.Close())
+		}
+		if err := pdhCall(procPdhAddEnglishCounter, c.query, uintptr(unsafe.Pointer(path)), 0, uintptr(unsafe.Pointer(handle))); err != nil {
+			return nil, errors.Join(fmt.Errorf("failed to add performance counter %s: %w", counter, err), c.Close())
+		}
+	}
+	// rates need two collections, the first sample is relative to this one
+	if err := pdhCall(procPdhCollectQueryData, c.query); err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to collect %s performance counters: %w", object, err), c.Close())
+	}
+	return c, nil
+}
+
+// Sample returns the IO of the matching instances since the previous sample.
+func (c *DiskCounters) Sample() (DiskIO, error) {
+	if err := pdhCall(procPdhCollectQueryData, c.query); err != nil {
+		return DiskIO{}, fmt.Errorf("failed to collect %s performance counters: %w", c.description, err)
+	}
+	instances := map[string]*DiskIO{}
+	var names []string
+	for _, counter := range []struct {
+		handle uintptr
+		set    func(io *DiskIO, value float64)
+	}{
+		{c.bytes, func(io *DiskIO, value float64) { io.BytesPerSecond = value }},
+		{c.transfers, func(io *DiskIO, value float64) { io.TransfersPerSecond = value }},
+		{c.latency, func(io *DiskIO, value float64) { io.AvgLatencySeconds = value }},
+	} {
+		items, err := formattedCounterArray(counter.handle)
+		if err != nil {
+			return DiskIO{}, err
+		}
+		for name, value := range items {
+			io, ok := instances[name]
+			if !ok {
+				io = &DiskIO{}
+				instances[name] = io
+				names = append(names, name)
+			}
+			counter.set(io, value)
+		}
+	}
+
+	sampled := make([]diskCounterInstance, 0, len(names))
+	for _, name := range names {
+		sampled = append(sampled, diskCounterInstance{name: name, io: *instances[name]})
+	}
+	io, found := sumDiskIO(sampled, c.match)
+	if !found {
+		return DiskIO{}, fmt.Errorf("no %s performance counters of the disk found", c.description)
+	}
+	return io, nil
+}
+
+// Close closes the counters.
+func (c *DiskCounters) Close() error {
+	if c.query == 0 {
+		return nil
+	}
+	err := pdhCall(procPdhCloseQuery, c.query)
+	c.query = 0
+	return err
+}
+
+// formattedCounterArray returns the values of all instances of a counter.
+func formattedCounterArray(counter uintptr) (map[string]float64, error) {
+	var size, count uint32
+	status, _, _ := procPdhGetFormattedCounterArray.Call(counter, pdhFmtDouble, uintptr(unsafe.Pointer(&size)), uintptr(unsafe.Pointer(&count)), 0)
+	if uint32(status) != pdhMoreData {
+		return nil, fmt.Errorf("failed to get size of performance counter values: PDH status 0x%X", uint32(status))
+	}
+	buffer := make([]byte, size)
+	if err := pdhCall(procPdhGetFormattedCounterArray, counter, pdhFmtDouble, uintptr(unsafe.Pointer(&size)), uintptr(unsafe.Pointer(&count)), uintptr(unsafe.Pointer(&buffer[0]))); err != nil {
+		return nil, fmt.Errorf("failed to get performance counter values: %w", err)
+	}
+
+	values := make(map[string]float64, count)
+	for _, item := range unsafe.Slice((*pdhCounterValueItem)(unsafe.Pointer(&buffer[0])), count) {
+		// counters of instances which were just added have no valid value yet
+		if item.cStatus != 0 {
+			continue
+		}
+		values[windows.UTF16PtrToString(item.name)] = item.value
+	}
+	return values, nil
+}
+
+func pdhCall(proc *windows.LazyProc, args ...uintptr) error {
+	status, _, _ := proc.Call(args...)
+	if status != 0 {
+		return fmt.Errorf("PDH status 0x%X", uint32(status))
+	}
+	return nil
+}
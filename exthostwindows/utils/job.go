@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package utils
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// processJob keeps the processes started by the extension from outliving it. On Windows the processes are assigned to
+// a job object, which kills them once its last handle is closed. Only the extension holds a handle, so the attacks end
+// when the extension does, even if it is killed. Processes started by assigned processes, e.g. wdna started by a
+// powershell host, are part of the job as well. The processes start suspended and are resumed once assigned, so that
+// they can't start processes outside the job.
+type processJob interface {
+	// suspend makes the command start suspended.
+	suspend(cmd *exec.Cmd)
+	assign(process *os.Process) error
+	// resume resumes the process started suspended.
+	resume(process *os.Process) error
+}
+
+// defaultProcessJob is shared by all processes started by the extension.
+var defaultProcessJob = sync.OnceValue(func() processJob {
+	job, err := newProcessJob()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to create job object, started processes may outlive the extension")
+		return noProcessJob{}
+	}
+	return job
+})
+
+// noProcessJob doesn't track processes.
+type noProcessJob struct{}
+
+func (noProcessJob) suspend(*exec.Cmd) {}
+
+func (noProcessJob) assign(*os.Process) error {
+	return nil
+}
+
+func (noProcessJob) resume(*os.Process) error {
+	return nil
+}
+
+// startInJob starts the command suspended, assigns the process to the job and resumes it. The process is killed if it
+// can't be assigned or resumed.
+func startInJob(job processJob, cmd *exec.Cmd) error {
+	job.suspend(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if err := job.assign(cmd.Process); err != nil {
+		killStarted(cmd)
+		return fmt.Errorf("failed to assign %s (pid %d) to job object: %w", cmd.Path, cmd.Process.Pid, err)
+	}
+	if err := job.resume(cmd.Process); err != nil {
+		killStarted(cmd)
+		return fmt.Errorf("failed to resume %s (pid %d): %w", cmd.Path, cmd.Process.Pid, err)
+	}
+	return nil
+}
+
+func killStarted(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+	_ = cmd.Wait()
+}
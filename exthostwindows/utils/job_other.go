@@ -0,0 +1,10 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+//go:build !windows
+
+package utils
+
+// newProcessJob doesn't track processes on other platforms, they are only supported to run unit tests.
+func newProcessJob() (processJob, error) {
+	return noProcessJob{}, nil
+}
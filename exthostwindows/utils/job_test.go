@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package utils
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingJob records the process ids of the assigned processes and the steps of starting them.
+type recordingJob struct {
+	lock      sync.Mutex
+	pids      []int
+	steps     []string
+	err       error
+	resumeErr error
+}
+
+func (j *recordingJob) suspend(cmd *exec.Cmd) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if cmd.Process == nil {
+		j.steps = append(j.steps, "suspend")
+	}
+}
+
+func (j *recordingJob) assign(process *os.Process) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if j.err != nil {
+		return j.err
+	}
+	j.pids = append(j.pids, process.Pid)
+	j.steps = append(j.steps, "assign")
+	return nil
+}
+
+func (j *recordingJob) resume(*os.Process) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if j.resumeErr != nil {
+		return j.resumeErr
+	}
+	j.steps = append(j.steps, "resume")
+	return nil
+}
+
+func (j *recordingJob) assigned() []int {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	return j.pids
+}
+
+func TestStartInJob_AssignsProcess(t *testing.T) {
+	job := &recordingJob{}
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+
+	require.NoError(t, startInJob(job, cmd))
+	require.NoError(t, cmd.Wait())
+	assert.Equal(t, []int{cmd.Process.Pid}, job.assigned())
+	assert.Equal(t, []string{"suspend", "assign", "resume"}, job.steps)
+}
+
+func TestStartInJob_KillsProcessNotAssigned(t *testing.T) {
+	job := &recordingJob{err: errors.New("access denied")}
+	cmd := fakePowershellHostCommand()
+	stdin, err := cmd.StdinPipe()
+	require.NoError(t, err)
+	defer func() { _ = stdin.Close() }()
+
+	err = startInJob(job, cmd)
+	assert.ErrorContains(t, err, "to job object: access denied")
+	// the process waiting for requests was killed and waited for
+	require.NotNil(t, cmd.ProcessState)
+	assert.False(t, cmd.ProcessState.Success())
+}
+
+func TestStartInJob_KillsProcessNotResumed(t *testing.T) {
+	job := &recordingJob{resumeErr: errors.New("access denied")}
+	cmd := fakePowershellHostCommand()
+	stdin, err := cmd.StdinPipe()
+	require.NoError(t, err)
+	defer func() { _ = stdin.Close() }()
+
+	err = startInJob(job, cmd)
+	assert.ErrorContains(t, err, "failed to resume")
+	require.NotNil(t, cmd.ProcessState)
+	assert.False(t, cmd.ProcessState.Success())
+}
+
+func TestExecRunner_AssignsProcessesToJob(t *testing.T) {
+	job := &recordingJob{}
+	runner := &execRunner{job: job}
+
+	_, err := runner.Run(t.Context(), os.Args[0], "-test.run=^$")
+	require.NoError(t, err)
+
+	process, err := runner.Start(Command{Name: os.Args[0], Args: []string{"-test.run=^$"}})
+	require.NoError(t, err)
+	_, err = process.Wait()
+	require.NoError(t, err)
+
+	assert.Len(t, job.assigned(), 2)
+	assert.Equal(t, process.Pid(), job.assigned()[1])
+}
+
+func TestPowershellPool_AssignsHostsToJob(t *testing.T) {
+	job := &recordingJob{}
+	pool := newPowershellPool(1, time.Minute, fakePowershellHostCommand, job)
+	defer pool.Close()
+
+	pid, err := pool.Run(t.Context(), []string{"pid"})
+	require.NoError(t, err)
+	assert.Len(t, job.assigned(), 1)
+	assert.Equal(t, pid, strconv.Itoa(job.assigned()[0]))
+}
@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// jobObject is a job object killing its processes once the extension exits. The handle is never closed explicitly.
+type jobObject struct {
+	handle windows.Handle
+}
+
+func newProcessJob() (processJob, error) {
+	handle, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job object: %w", err)
+	}
+	info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{
+		BasicLimitInformation: windows.JOBOBJECT_BASIC_LIMIT_INFORMATION{
+			LimitFlags: windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE,
+		},
+	}
+	if _, err := windows.SetInformationJobObject(handle, windows.JobObjectExtendedLimitInformation, uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info))); err != nil {
+		_ = windows.CloseHandle(handle)
+		return nil, fmt.Errorf("failed to set kill on close for job object: %w", err)
+	}
+	return &jobObject{handle: handle}, nil
+}
+
+func (j *jobObject) suspend(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= windows.CREATE_SUSPENDED
+}
+
+func (j *jobObject) assign(process *os.Process) error {
+	handle, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(process.Pid))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = windows.CloseHandle(handle) }()
+	return windows.AssignProcessToJobObject(j.handle, handle)
+}
+
+// resume resumes the main thread of the process. os.StartProcess closes the thread handle returned by CreateProcess,
+// so the thread is looked up in a snapshot. A process started suspended has no other threads.
+func (j *jobObject) resume(process *os.Process) error {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPTHREAD, 0)
+	if err != nil {
+		return fmt.Errorf("failed to snapshot threads: %w", err)
+	}
+	defer func() { _ = windows.CloseHandle(snapshot) }()
+
+	resumed := false
+	entry := windows.ThreadEntry32{Size: uint32(unsafe.Sizeof(windows.ThreadEntry32{}))}
+	for err = windows.Thread32First(snapshot, &entry); err == nil; err = windows.Thread32Next(snapshot, &entry) {
+		if entry.OwnerProcessID != uint32(process.Pid) {
+			continue
+		}
+		if err := resumeThread(entry.ThreadID); err != nil {
+			return err
+		}
+		resumed = true
+	}
+	if !errors.Is(err, windows.ERROR_NO_MORE_FILES) {
+		return fmt.Errorf("failed to list threads: %w", err)
+	}
+	if !resumed {
+		return errors.New("no thread to resume")
+	}
+	return nil
+}
+
+func resumeThread(threadId uint32) error {
+	handle, err := windows.OpenThread(windows.THREAD_SUSPEND_RESUME, false, threadId)
+	if err != nil {
+		return fmt.Errorf("failed to open thread %d: %w", threadId, err)
+	}
+	defer func() { _ = windows.CloseHandle(handle) }()
+	if _, err := windows.ResumeThread(handle); err != nil {
+		return fmt.Errorf("failed to resume thread %d: %w", threadId, err)
+	}
+	return nil
+}
@@ -3,41 +3,39 @@ package utils
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"os"
 	"os/exec"
-	"strconv"
 	"strings"
 
 	"github.com/rs/zerolog/log"
 )
 
-func IsProcessRunning(processName string) (bool, error) {
-	cmd := PowershellCommand("Get-Process", "-Name", processName)
-	output, err := cmd.CombinedOutput()
+func IsProcessRunning(r CommandRunner, processName string) (bool, error) {
+	output, err := r.RunPowershell(context.Background(), []string{"Get-Process -Name " + SanitizePowershellArg(processName)}, PSRun)
 	if err != nil {
-		if strings.Contains(string(output), "Cannot find a process with the name") {
+		if strings.Contains(err.Error(), "Cannot find a process with the name") {
 			return false, nil
 		}
 
 		return false, err
 	}
 
-	return len(strings.TrimSpace(string(output))) > 0, nil
+	return len(strings.TrimSpace(output)) > 0, nil
 }
 
-func StopProcess(processName string) error {
-	isRunning, err := IsProcessRunning(processName)
+func StopProcess(r CommandRunner, processName string) error {
+	isRunning, err := IsProcessRunning(r, processName)
 
 	if err != nil {
 		return err
 	}
 
 	if isRunning {
-		cmd := PowershellCommand("Stop-Process", "-Name", processName, "-Force")
-		out, err := cmd.CombinedOutput()
+		out, err := r.RunPowershell(context.Background(), []string{"Stop-Process -Name " + SanitizePowershellArg(processName) + " -Force"}, PSRun)
 		if err != nil {
-			if strings.Contains(string(out), "Cannot find a process with the name") {
+			if strings.Contains(err.Error(), "Cannot find a process with the name") {
 				log.Err(err).Msg("Stop-Process failed")
 				return err
 			}
@@ -49,20 +47,15 @@ func StopProcess(processName string) error {
 	return nil
 }
 
-func IsExecutableOperational(executableName string, args ...string) error {
-	cmd := exec.Command(executableName, args...)
-	cmd.Dir = os.TempDir()
-	var outputBuffer bytes.Buffer
-	cmd.Stdout = &outputBuffer
-	cmd.Stderr = &outputBuffer
-	err := cmd.Run()
+func IsExecutableOperational(r CommandRunner, executableName string, args ...string) error {
+	output, err := r.Run(context.Background(), executableName, args...)
 	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return fmt.Errorf("%s is not operational: '%s' returned: %s", executableName, executableName, output)
+		}
 		return fmt.Errorf("failed to start '%s': %s \n'%s' is not installed or not present in %%PATH%%", executableName, err, executableName)
 	}
-	success := cmd.ProcessState.Success()
-	if !success {
-		return fmt.Errorf("%s is not operational: '%s' in %v returned: %v", executableName, executableName, os.TempDir(), outputBuffer.Bytes())
-	}
 
 	return nil
 }
@@ -72,21 +65,27 @@ func PowershellCommand(args ...string) *exec.Cmd {
 	return exec.Command("powershell", args...)
 }
 
-func GetAvailableDriveLetters() ([]string, error) {
-	cmd := PowershellCommand("Get-Volume | ForEach-Object { $_.DriveLetter }")
-	output, err := cmd.Output()
+// Volume is a volume as returned by Get-Volume.
+type Volume struct {
+	// DriveLetter is empty for volumes without drive letter.
+	DriveLetter   string
+	Size          uint64
+	SizeRemaining uint64
+}
+
+var volumeProperties = []string{"DriveLetter", "Size", "SizeRemaining"}
+
+func GetAvailableDriveLetters(r CommandRunner) ([]string, error) {
+	volumes, err := RunPowershellJson[Volume](context.Background(), r, PowershellInvocation{Cmdlet: "Get-Volume", Select: volumeProperties})
 	if err != nil {
 		return []string{}, err
 	}
 
-	reader := strings.NewReader(string(output))
-	scanner := bufio.NewScanner(reader)
 	driveLetters := make([]string, 0)
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		letter := strings.TrimSpace(line)
-		driveLetters = append(driveLetters, letter)
+	for _, volume := range volumes {
+		if volume.DriveLetter != "" {
+			driveLetters = append(driveLetters, volume.DriveLetter)
+		}
 	}
 	return driveLetters, nil
 }
@@ -98,28 +97,50 @@ const (
 	Total     DriveSpace = "Size"
 )
 
-func GetDriveSpace(driveLetter string, kind DriveSpace) (uint64, error) {
-	cmd := PowershellCommand(fmt.Sprintf("(Get-Volume -DriveLetter %s).%s", driveLetter, kind))
-	output, err := cmd.Output()
+// GetDriveSpace returns the space of the volume with the drive letter as reported by Get-Volume. Use GetVolumeSpace
+// to poll the space.
+func GetDriveSpace(r CommandRunner, driveLetter string, kind DriveSpace) (uint64, error) {
+	volumes, err := RunPowershellJson[Volume](context.Background(), r, PowershellInvocation{
+		Cmdlet: "Get-Volume",
+		Params: map[string]any{"DriveLetter": driveLetter},
+		Select: volumeProperties,
+	})
 
 	if err != nil {
 		return 0, err
 	}
 
-	availableSpace, err := strconv.ParseUint(strings.TrimSpace(string(output)), 10, 0)
+	if len(volumes) != 1 {
+		return 0, fmt.Errorf("volume with drive letter %s not found", driveLetter)
+	}
 
-	if err != nil {
-		return 0, err
+	if kind == Total {
+		return volumes[0].Size, nil
 	}
+	return volumes[0].SizeRemaining, nil
+}
+
+// PhysicalDisk is a disk as returned by Get-PhysicalDisk.
+type PhysicalDisk struct {
+	DeviceId     string
+	FriendlyName string
+	Size         uint64
+}
 
-	return availableSpace, nil
+func GetPhysicalDisks(r CommandRunner) ([]PhysicalDisk, error) {
+	return RunPowershellJson[PhysicalDisk](context.Background(), r, PowershellInvocation{
+		Cmdlet: "Get-PhysicalDisk",
+		Select: []string{"DeviceId", "FriendlyName", "Size"},
+	})
 }
 
 type CmdOutputProvider func() ([]byte, error)
 
-func IsTestSigningEnabled() (bool, error) {
-	cmd := PowershellCommand("bcdedit")
-	isEnabled, err := CheckTestSigningAttribute(cmd.Output)
+func IsTestSigningEnabled(r CommandRunner) (bool, error) {
+	isEnabled, err := CheckTestSigningAttribute(func() ([]byte, error) {
+		output, err := r.RunPowershell(context.Background(), []string{"bcdedit"}, PSRun)
+		return []byte(output), err
+	})
 
 	if err != nil {
 		return false, err
@@ -0,0 +1,261 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package utils
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"github.com/rs/zerolog/log"
+)
+
+// powershellHostScript runs the scripts sent to the host. It reads one JSON request per line from stdin and writes one
+// JSON response per line to stdout. Scripts run in their own scope, so variables don't leak into later requests. Like
+// powershell -Command, a script fails if its last statement failed or if it raised a terminating error.
+const powershellHostScript = `$ProgressPreference = 'SilentlyContinue'
+[Console]::OutputEncoding = New-Object Text.UTF8Encoding $false
+while ($true) {
+  $line = [Console]::In.ReadLine()
+  if ($null -eq $line) { break }
+  $request = ConvertFrom-Json $line
+  $response = @{ id = $request.id; output = ''; error = ''; failed = $false }
+  try {
+    $global:SteadybitSucceeded = $true
+    $script = [Text.Encoding]::UTF8.GetString([Convert]::FromBase64String($request.script)) + "` + "`n" + `" + '$global:SteadybitSucceeded = $?'
+    $records = @(& ([ScriptBlock]::Create($script)) 2>&1 6>&1)
+    $response.output = $records | Where-Object { $_ -isnot [Management.Automation.ErrorRecord] } | Out-String -Width 4096
+    $response.error = $records | Where-Object { $_ -is [Management.Automation.ErrorRecord] } | Out-String -Width 4096
+    $response.failed = -not $global:SteadybitSucceeded
+  } catch {
+    $response.error = $_ | Out-String -Width 4096
+    $response.failed = $true
+  }
+  [Console]::Out.WriteLine((ConvertTo-Json -Compress -InputObject $response))
+  [Console]::Out.Flush()
+}`
+
+// maxPowershellResponseSize limits the size of a single response line.
+const maxPowershellResponseSize = 16 * 1024 * 1024
+
+type powershellRequest struct {
+	Id uint64 `json:"id"`
+	// Script is base64 encoded, so requests are plain ASCII regardless of the console encoding.
+	Script string `json:"script"`
+}
+
+type powershellResponse struct {
+	Id     uint64 `json:"id"`
+	Output string `json:"output"`
+	Error  string `json:"error"`
+	Failed bool   `json:"failed"`
+}
+
+// PowershellPool runs scripts in long-lived powershell processes, which saves starting a new process per call.
+// Each host runs one script at a time. Hosts are started on first use and restarted after they crashed, timed out
+// or the request was cancelled.
+type PowershellPool struct {
+	hosts   chan *powershellHost
+	timeout time.Duration
+}
+
+// NewPowershellPool creates a pool of size hosts. Scripts running longer than the timeout are cancelled.
+func NewPowershellPool(size int, timeout time.Duration) *PowershellPool {
+	return newPowershellPool(size, timeout, powershellHostCommand, defaultProcessJob())
+}
+
+func newPowershellPool(size int, timeout time.Duration, command func() *exec.Cmd, job processJob) *PowershellPool {
+	p := &PowershellPool{hosts: make(chan *powershellHost, size), timeout: timeout}
+	for range size {
+		p.hosts <- &powershellHost{command: command, job: job}
+	}
+	return p
+}
+
+func powershellHostCommand() *exec.Cmd {
+	// the script is passed encoded, so it doesn't need to be quoted
+	encoded := utf16.Encode([]rune(powershellHostScript))
+	script := make([]byte, 2*len(encoded))
+	for i, c := range encoded {
+		binary.LittleEndian.PutUint16(script[2*i:], c)
+	}
+	cmd := exec.Command("powershell", "-NoLogo", "-NoProfile", "-NonInteractive", "-EncodedCommand", base64.StdEncoding.EncodeToString(script))
+	hideWindow(cmd)
+	return cmd
+}
+
+// Run runs the commands in one of the hosts and returns the trimmed output, see ExecutePowershellCommand.
+// Callers must make sure that passed in commands are properly sanitized.
+func (p *PowershellPool) Run(ctx context.Context, cmds []string) (string, error) {
+	log.Debug().Strs("cmds", cmds).Msg("running commands")
+
+	var host *powershellHost
+	select {
+	case host = <-p.hosts:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	defer func() { p.hosts <- host }()
+
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	response, err := host.run(ctx, strings.Join(cmds, ";"))
+	if err != nil {
+		return "", err
+	}
+	out := strings.TrimSpace(response.Output)
+	if response.Failed {
+		return "", fmt.Errorf("execution failed: script failed, output: %s, error: %s", out, strings.TrimSpace(response.Error))
+	}
+	return out, nil
+}
+
+// Close stops all hosts. Hosts which are running a script are stopped once it is finished. Stopped hosts are
+// restarted if the pool is used again.
+func (p *PowershellPool) Close() {
+	hosts := make([]*powershellHost, 0, cap(p.hosts))
+	for range cap(p.hosts) {
+		host := <-p.hosts
+		host.stop()
+		hosts = append(hosts, host)
+	}
+	for _, host := range hosts {
+		p.hosts <- host
+	}
+}
+
+type powershellHost struct {
+	command func() *exec.Cmd
+	// job also contains the processes started by the scripts
+	job processJob
+
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	responses chan powershellResponse
+	exited    chan struct{}
+	lastId    uint64
+}
+
+func (h *powershellHost) start() error {
+	cmd := h.command()
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := startInJob(h.job, cmd); err != nil {
+		return fmt.Errorf("failed to start powershell host: %w", err)
+	}
+	log.Debug().Int("pid", cmd.Process.Pid).Msg("started powershell host")
+
+	responses := make(chan powershellResponse, 1)
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64*1024), maxPowershellResponseSize)
+		for scanner.Scan() {
+			var response powershellResponse
+			// anything else written to stdout, e.g. by native commands writing to the console directly, is ignored
+			if err := json.Unmarshal(scanner.Bytes(), &response); err != nil || response.Id == 0 {
+				log.Trace().Str("line", scanner.Text()).Msg("ignoring output of powershell host")
+				continue
+			}
+			select {
+			case responses <- response:
+			default:
+				log.Warn().Uint64("id", response.Id).Msg("dropping unexpected response of powershell host")
+			}
+		}
+		err := cmd.Wait()
+		log.Debug().Err(err).Int("pid", cmd.Process.Pid).Msg("powershell host exited")
+	}()
+
+	h.cmd, h.stdin, h.responses, h.exited = cmd, stdin, responses, exited
+	return nil
+}
+
+func (h *powershellHost) run(ctx context.Context, script string) (powershellResponse, error) {
+	if h.cmd == nil {
+		if err := h.start(); err != nil {
+			return powershellResponse{}, err
+		}
+	}
+
+	h.lastId++
+	request, err := json.Marshal(powershellRequest{Id: h.lastId, Script: base64.StdEncoding.EncodeToString([]byte(script))})
+	if err != nil {
+		return powershellResponse{}, err
+	}
+	if _, err := h.stdin.Write(append(request, '\n')); err != nil {
+		h.stop()
+		return powershellResponse{}, fmt.Errorf("failed to send script to powershell host: %w", err)
+	}
+
+	for {
+		select {
+		case response := <-h.responses:
+			if response.Id == h.lastId {
+				return response, nil
+			}
+		case <-h.exited:
+			h.stop()
+			return powershellResponse{}, errors.New("powershell host exited while running the script")
+		case <-ctx.Done():
+			// a running script can't be interrupted, the host is restarted for the next request
+			h.stop()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return powershellResponse{}, fmt.Errorf("powershell script timed out: %w", ctx.Err())
+			}
+			return powershellResponse{}, ctx.Err()
+		}
+	}
+}
+
+func (h *powershellHost) stop() {
+	if h.cmd == nil {
+		return
+	}
+	_ = h.stdin.Close()
+	if err := h.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		log.Warn().Err(err).Int("pid", h.cmd.Process.Pid).Msg("failed to stop powershell host")
+	}
+	<-h.exited
+	h.cmd = nil
+}
+
+// defaultPowershellPool is shared by all runners created with NewCommandRunner.
+var defaultPowershellPool = sync.OnceValue(func() *PowershellPool {
+	return NewPowershellPool(2, 5*time.Minute)
+})
+
+// networkPowershellPool is shared by all runners created with NewNetworkCommandRunner. Network attacks get their own
+// hosts, so that reverting them isn't blocked by long-running scripts of other actions.
+var networkPowershellPool = sync.OnceValue(func() *PowershellPool {
+	return NewPowershellPool(2, 5*time.Minute)
+})
+
+// ClosePowershellHosts stops the hosts of the default and the network pool.
+func ClosePowershellHosts() {
+	defaultPowershellPool().Close()
+	networkPowershellPool().Close()
+}
@@ -0,0 +1,224 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package utils
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fakePowershellHostEnv = "STEADYBIT_FAKE_POWERSHELL_HOST"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakePowershellHostEnv) == "1" {
+		runFakePowershellHost()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runFakePowershellHost speaks the protocol of powershellHostScript. The script selects the behavior, e.g. "echo hello".
+func runFakePowershellHost() {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var request powershellRequest
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			os.Exit(2)
+		}
+		script, _ := base64.StdEncoding.DecodeString(request.Script)
+		verb, arg, _ := strings.Cut(string(script), " ")
+
+		response := powershellResponse{Id: request.Id}
+		switch verb {
+		case "echo":
+			response.Output = arg + "\r\n"
+		case "pid":
+			response.Output = strconv.Itoa(os.Getpid())
+		case "fail":
+			response.Error = arg
+			response.Failed = true
+		case "noise":
+			fmt.Println("written to the console directly")
+			fmt.Println(`{"id":0}`)
+			response.Output = arg
+		case "sleep":
+			time.Sleep(time.Hour)
+		case "crash":
+			os.Exit(3)
+		}
+		out, _ := json.Marshal(response)
+		fmt.Println(string(out))
+	}
+}
+
+func newFakePowershellPool(size int, timeout time.Duration) *PowershellPool {
+	return newPowershellPool(size, timeout, fakePowershellHostCommand, noProcessJob{})
+}
+
+func fakePowershellHostCommand() *exec.Cmd {
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), fakePowershellHostEnv+"=1")
+	return cmd
+}
+
+func TestPowershellPool_Run(t *testing.T) {
+	pool := newFakePowershellPool(1, time.Minute)
+	defer pool.Close()
+
+	tests := []struct {
+		name    string
+		cmds    []string
+		want    string
+		wantErr string
+	}{
+		{name: "trims output", cmds: []string{"echo hello"}, want: "hello"},
+		{name: "joins commands", cmds: []string{"echo a", "b"}, want: "a;b"},
+		{name: "ignores unrelated output", cmds: []string{"noise hello"}, want: "hello"},
+		{name: "returns error of failed script", cmds: []string{"fail Cannot find a process with the name"}, wantErr: "execution failed: script failed, output: , error: Cannot find a process with the name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pool.Run(context.Background(), tt.cmds)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPowershellPool_ReusesHost(t *testing.T) {
+	pool := newFakePowershellPool(1, time.Minute)
+	defer pool.Close()
+
+	first, err := pool.Run(context.Background(), []string{"pid"})
+	require.NoError(t, err)
+	second, err := pool.Run(context.Background(), []string{"pid"})
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+}
+
+func TestPowershellPool_RestartsHost(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		ctx     func() (context.Context, context.CancelFunc)
+		cmd     string
+		wantErr string
+	}{
+		{
+			name:    "after crash",
+			timeout: time.Minute,
+			ctx:     func() (context.Context, context.CancelFunc) { return context.WithCancel(context.Background()) },
+			cmd:     "crash",
+			wantErr: "powershell host exited while running the script",
+		},
+		{
+			name:    "after timeout",
+			timeout: 200 * time.Millisecond,
+			ctx:     func() (context.Context, context.CancelFunc) { return context.WithCancel(context.Background()) },
+			cmd:     "sleep",
+			wantErr: "powershell script timed out: context deadline exceeded",
+		},
+		{
+			name:    "after cancellation",
+			timeout: time.Minute,
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(200*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			cmd:     "sleep",
+			wantErr: "context canceled",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := newFakePowershellPool(1, tt.timeout)
+			defer pool.Close()
+
+			before, err := pool.Run(context.Background(), []string{"pid"})
+			require.NoError(t, err)
+
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			_, err = pool.Run(ctx, []string{tt.cmd})
+			assert.EqualError(t, err, tt.wantErr)
+
+			after, err := pool.Run(context.Background(), []string{"pid"})
+			require.NoError(t, err)
+			assert.NotEqual(t, before, after)
+		})
+	}
+}
+
+func TestPowershellPool_WaitsForHost(t *testing.T) {
+	pool := newFakePowershellPool(1, time.Minute)
+	defer pool.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := pool.Run(ctx, []string{"sleep"})
+		done <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer waitCancel()
+	_, err := pool.Run(waitCtx, []string{"echo hello"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	out, err := pool.Run(context.Background(), []string{"echo hello"})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", out)
+}
+
+func TestPowershellPool_NetworkRevertNotBlockedByOtherCallers(t *testing.T) {
+	assert.NotSame(t, NewCommandRunner().(*execRunner).pool, NewNetworkCommandRunner().(*execRunner).pool)
+
+	pool := newFakePowershellPool(2, time.Minute)
+	defer pool.Close()
+	networkPool := newFakePowershellPool(2, time.Minute)
+	defer networkPool.Close()
+	runner := &execRunner{pool: pool, job: noProcessJob{}}
+	networkRunner := &execRunner{pool: networkPool, job: noProcessJob{}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	for range 2 {
+		go func() {
+			_, err := runner.RunPowershell(ctx, []string{"sleep"}, PSRun)
+			done <- err
+		}()
+	}
+	require.Eventually(t, func() bool { return len(pool.hosts) == 0 }, 5*time.Second, 10*time.Millisecond)
+
+	revertCtx, revertCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer revertCancel()
+	out, err := networkRunner.RunPowershell(revertCtx, []string{"echo reverted"}, PSRun)
+	require.NoError(t, err)
+	assert.Equal(t, "reverted", out)
+
+	cancel()
+	for range 2 {
+		assert.ErrorIs(t, <-done, context.Canceled)
+	}
+}
@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	cmdletPattern     = regexp.MustCompile(`^[A-Za-z]+-[A-Za-z0-9]+$`)
+	identifierPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+)
+
+// PowershellInvocation is a single cmdlet call. Parameters are passed as JSON encoded splat and never become part of
+// the script, so they don't need to be sanitized.
+type PowershellInvocation struct {
+	Cmdlet string
+	// Params are passed to the cmdlet by name. Objects are passed as hashtables, e.g. for the headers of Invoke-RestMethod.
+	Params map[string]any
+	// Select limits the properties of the returned objects, all properties are returned if empty.
+	Select []string
+}
+
+// Script returns the powershell script running the cmdlet and converting its output to a JSON array.
+func (i PowershellInvocation) Script() (string, error) {
+	if !cmdletPattern.MatchString(i.Cmdlet) {
+		return "", fmt.Errorf("invalid cmdlet %q", i.Cmdlet)
+	}
+	for _, property := range i.Select {
+		if !identifierPattern.MatchString(property) {
+			return "", fmt.Errorf("invalid property %q", property)
+		}
+	}
+
+	pipeline := i.Cmdlet
+	var script []string
+	if len(i.Params) > 0 {
+		params, err := json.Marshal(i.Params)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode parameters of %s: %w", i.Cmdlet, err)
+		}
+		script = append(script,
+			"$params = @{}",
+			fmt.Sprintf("$json = [Text.Encoding]::UTF8.GetString([Convert]::FromBase64String('%s'))", base64.StdEncoding.EncodeToString(params)),
+			"foreach ($p in (ConvertFrom-Json $json).PSObject.Properties) { $v = $p.Value; if ($v -is [Management.Automation.PSCustomObject]) { $h = @{}; foreach ($q in $v.PSObject.Properties) { $h[$q.Name] = $q.Value }; $v = $h }; $params[$p.Name] = $v }",
+		)
+		pipeline += " @params"
+	}
+	if len(i.Select) > 0 {
+		pipeline += " | Select-Object -Property " + strings.Join(i.Select, ",")
+	}
+	script = append(script, fmt.Sprintf("ConvertTo-Json -Compress -Depth 4 -InputObject @(%s)", pipeline))
+	return strings.Join(script, ";"), nil
+}
+
+// String describes the invocation like a cmdlet call in a script, e.g. to log it. Parameters are sorted by name.
+func (i PowershellInvocation) String() string {
+	var sb strings.Builder
+	sb.WriteString(i.Cmdlet)
+	for _, name := range slices.Sorted(maps.Keys(i.Params)) {
+		switch value := i.Params[name].(type) {
+		case bool:
+			fmt.Fprintf(&sb, " -%s:$%t", name, value)
+		case string:
+			fmt.Fprintf(&sb, " -%s '%s'", name, strings.ReplaceAll(value, "'", "''"))
+		default:
+			fmt.Fprintf(&sb, " -%s %v", name, value)
+		}
+	}
+	return sb.String()
+}
+
+// RunPowershellJson runs the cmdlet and decodes the returned objects.
+func RunPowershellJson[T any](ctx context.Context, runner CommandRunner, invocation PowershellInvocation) ([]T, error) {
+	script, err := invocation.Script()
+	if err != nil {
+		return nil, err
+	}
+	out, err := runner.RunPowershell(ctx, []string{script}, PSRun)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", invocation.Cmdlet, err)
+	}
+	result, err := DecodePowershellJson[T](out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode output of %s: %w", invocation.Cmdlet, err)
+	}
+	return result, nil
+}
+
+// DecodePowershellJson decodes the output of ConvertTo-Json. A single object is decoded as well as an array, as
+// powershell unwraps arrays with a single element in some places.
+func DecodePowershellJson[T any](out string) ([]T, error) {
+	data := bytes.TrimSpace([]byte(out))
+	// Windows PowerShell may prefix the output with a byte order mark
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	if data[0] != '[' {
+		var single T
+		if err := json.Unmarshal(data, &single); err != nil {
+			return nil, err
+		}
+		return []T{single}, nil
+	}
+
+	var result []T
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,157 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package utils_test
+
+import (
+	"encoding/base64"
+	"os"
+	"testing"
+
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils/utilstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPowershellInvocation_Script(t *testing.T) {
+	tests := []struct {
+		name       string
+		invocation utils.PowershellInvocation
+		wantScript string
+		wantErr    string
+	}{
+		{
+			name:       "without parameters",
+			invocation: utils.PowershellInvocation{Cmdlet: "Get-Volume", Select: []string{"DriveLetter", "Size"}},
+			wantScript: "ConvertTo-Json -Compress -Depth 4 -InputObject @(Get-Volume | Select-Object -Property DriveLetter,Size)",
+		},
+		{
+			name:       "with parameters",
+			invocation: utils.PowershellInvocation{Cmdlet: "Get-Volume", Params: map[string]any{"DriveLetter": "C"}},
+			wantScript: "$params = @{};" +
+				"$json = [Text.Encoding]::UTF8.GetString([Convert]::FromBase64String('" + base64.StdEncoding.EncodeToString([]byte(`{"DriveLetter":"C"}`)) + "'));" +
+				"foreach ($p in (ConvertFrom-Json $json).PSObject.Properties) { $v = $p.Value; if ($v -is [Management.Automation.PSCustomObject]) { $h = @{}; foreach ($q in $v.PSObject.Properties) { $h[$q.Name] = $q.Value }; $v = $h }; $params[$p.Name] = $v };" +
+				"ConvertTo-Json -Compress -Depth 4 -InputObject @(Get-Volume @params)",
+		},
+		{
+			name:       "invalid cmdlet",
+			invocation: utils.PowershellInvocation{Cmdlet: "Get-Volume; Remove-Item C:\\"},
+			wantErr:    "invalid cmdlet \"Get-Volume; Remove-Item C:\\\\\"",
+		},
+		{
+			name:       "invalid property",
+			invocation: utils.PowershellInvocation{Cmdlet: "Get-Volume", Select: []string{"Size)"}},
+			wantErr:    "invalid property \"Size)\"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := tt.invocation.Script()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantScript, script)
+		})
+	}
+}
+
+func TestPowershellInvocation_ScriptDoesNotContainParameters(t *testing.T) {
+	script, err := utils.PowershellInvocation{Cmdlet: "Get-Process", Params: map[string]any{"Name": "x'; Stop-Computer; '"}}.Script()
+	require.NoError(t, err)
+	assert.NotContains(t, script, "Stop-Computer")
+}
+
+func TestPowershellInvocation_String(t *testing.T) {
+	invocation := utils.PowershellInvocation{Cmdlet: "New-NetQosPolicy", Params: map[string]any{
+		"Name":       "STEADYBIT_QOS_1MB",
+		"Precedence": 255,
+		"Confirm":    false,
+		"Prefix":     "it's",
+	}}
+	assert.Equal(t, "New-NetQosPolicy -Confirm:$false -Name 'STEADYBIT_QOS_1MB' -Precedence 255 -Prefix 'it''s'", invocation.String())
+}
+
+func TestDecodePowershellJson(t *testing.T) {
+	type item struct {
+		Name string
+	}
+	tests := []struct {
+		name    string
+		out     string
+		want    []item
+		wantErr bool
+	}{
+		{name: "empty", out: "", want: nil},
+		{name: "empty array", out: "[]", want: []item{}},
+		{name: "array", out: `[{"Name":"a"},{"Name":"b"}]`, want: []item{{Name: "a"}, {Name: "b"}}},
+		{name: "single object", out: `{"Name":"a"}`, want: []item{{Name: "a"}}},
+		{name: "byte order mark", out: "\ufeff[{\"Name\":\"a\"}]\r\n", want: []item{{Name: "a"}}},
+		{name: "invalid", out: "Get-Volume : Access denied", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := utils.DecodePowershellJson[item](tt.out)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGetAvailableDriveLetters_Fixture(t *testing.T) {
+	runner := utilstest.NewRecordingRunner().Respond("", readFixture(t, "testdata/get-volume.json"), nil)
+
+	driveLetters, err := utils.GetAvailableDriveLetters(runner)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"C", "E"}, driveLetters)
+	assertCapturedWith(t, runner.Commands()...)
+}
+
+func TestGetDriveSpace_Fixture(t *testing.T) {
+	runner := utilstest.NewRecordingRunner().Respond("", `{"DriveLetter":"C","Size":135838822400,"SizeRemaining":98765432832}`, nil)
+
+	available, err := utils.GetDriveSpace(runner, "C", utils.Available)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(98765432832), available)
+
+	total, err := utils.GetDriveSpace(runner, "C", utils.Total)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(135838822400), total)
+
+	_, err = utils.GetDriveSpace(utilstest.NewRecordingRunner().Respond("", "[]", nil), "X", utils.Total)
+	assert.EqualError(t, err, "volume with drive letter X not found")
+}
+
+func TestGetPhysicalDisks_Fixture(t *testing.T) {
+	runner := utilstest.NewRecordingRunner().Respond("", readFixture(t, "testdata/get-physicaldisk.json"), nil)
+
+	disks, err := utils.GetPhysicalDisks(runner)
+	require.NoError(t, err)
+	assert.Equal(t, []utils.PhysicalDisk{
+		{DeviceId: "0", FriendlyName: "Msft Virtual Disk", Size: 137438953472},
+		{DeviceId: "1", FriendlyName: "NVMe Amazon Elastic B", Size: 34359738368},
+	}, disks)
+	assertCapturedWith(t, runner.Commands()...)
+}
+
+// assertCapturedWith asserts that the fixtures are captured by scripts/capture-powershell-testdata.ps1 with the
+// commands the test ran.
+func assertCapturedWith(t *testing.T, commands ...string) {
+	script := readFixture(t, "../../scripts/capture-powershell-testdata.ps1")
+	require.NotEmpty(t, commands)
+	for _, command := range commands {
+		assert.Contains(t, script, "'"+command+"'", "fixture not captured with the command")
+	}
+}
+
+func readFixture(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
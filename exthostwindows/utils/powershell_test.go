@@ -1,76 +1,78 @@
-package utils
+package utils_test
 
 import (
 	"errors"
 	"testing"
 
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils/utilstest"
 	"github.com/stretchr/testify/require"
 )
 
 func Test_PSPowershellCommand(t *testing.T) {
 	arg0 := "test"
 	arg1 := "--arg"
-	cmd := PowershellCommand(arg0, arg1)
+	cmd := utils.PowershellCommand(arg0, arg1)
 	require.Len(t, cmd.Args, 4)
 	require.Contains(t, cmd.Args, arg0)
 	require.Contains(t, cmd.Args, arg1)
 }
 
 func Test_PSIsProcessRunning_ProcessExists(t *testing.T) {
-	isRunning, err := IsProcessRunning("explorer")
+	isRunning, err := utils.IsProcessRunning(utils.NewCommandRunner(), "explorer")
 	require.NoError(t, err)
 	require.True(t, isRunning)
 }
 
 func Test_PSIsProcessRunning_NoProcess(t *testing.T) {
-	isRunning, err := IsProcessRunning("explorer-wsad")
+	isRunning, err := utils.IsProcessRunning(utils.NewCommandRunner(), "explorer-wsad")
 	require.NoError(t, err)
 	require.False(t, isRunning)
 }
 
 func Test_PSStopProcess_NoProcess(t *testing.T) {
-	err := StopProcess("explorer-wsad")
+	err := utils.StopProcess(utils.NewCommandRunner(), "explorer-wsad")
 	require.NoError(t, err)
 }
 
 func Test_PSStopProcess(t *testing.T) {
-	err := StopProcess("explorer")
+	err := utils.StopProcess(utils.NewCommandRunner(), "explorer")
 	require.NoError(t, err)
 }
 
 func Test_PSIsExecutableOperational_Yes(t *testing.T) {
-	err := IsExecutableOperational("powershell", "-h")
+	err := utils.IsExecutableOperational(utils.NewCommandRunner(), "powershell", "-h")
 	require.NoError(t, err)
 }
 
 func Test_PSIsExecutableOperational_No(t *testing.T) {
-	err := IsExecutableOperational("powershell", "test")
+	err := utils.IsExecutableOperational(utils.NewCommandRunner(), "powershell", "test")
 	require.Error(t, err)
 }
 
 func Test_PSGetAvailableDriveLetters_AtLeastOne(t *testing.T) {
-	driveLetters, err := GetAvailableDriveLetters()
+	driveLetters, err := utils.GetAvailableDriveLetters(utils.NewCommandRunner())
 	require.NoError(t, err)
 	require.GreaterOrEqual(t, len(driveLetters), 1)
 }
 
 func Test_PSGetDriveSpace_Available(t *testing.T) {
-	driveLetters, err := GetAvailableDriveLetters()
+	driveLetters, err := utils.GetAvailableDriveLetters(utils.NewCommandRunner())
 	require.NoError(t, err)
 	require.GreaterOrEqual(t, len(driveLetters), 1)
-	space, err := GetDriveSpace(driveLetters[0], Available)
+	space, err := utils.GetDriveSpace(utils.NewCommandRunner(), driveLetters[0], utils.Available)
 	require.NoError(t, err)
 	require.Greater(t, space, uint64(0))
 }
 
 func Test_PSGetDriveSpace_Total(t *testing.T) {
-	driveLetters, err := GetAvailableDriveLetters()
+	driveLetters, err := utils.GetAvailableDriveLetters(utils.NewCommandRunner())
 	require.NoError(t, err)
 	require.GreaterOrEqual(t, len(driveLetters), 1)
-	space, err := GetDriveSpace(driveLetters[0], Available)
+	space, err := utils.GetDriveSpace(utils.NewCommandRunner(), driveLetters[0], utils.Available)
 	require.NoError(t, err)
 	require.Greater(t, space, uint64(0))
-	totalSpace, err := GetDriveSpace(driveLetters[0], Total)
+	totalSpace, err := utils.GetDriveSpace(utils.NewCommandRunner(), driveLetters[0], utils.Total)
 	require.NoError(t, err)
 	require.Greater(t, totalSpace, space)
 }
@@ -84,7 +86,7 @@ func Test_IsTestSigningEnabled_Enabled(t *testing.T) {
     testsigning             Yes
     `)
 
-	result, err := CheckTestSigningAttribute(func() ([]byte, error) {
+	result, err := utils.CheckTestSigningAttribute(func() ([]byte, error) {
 		return mockOutput, nil
 	})
 
@@ -105,7 +107,7 @@ func Test_IsTestSigningEnabled_Disabled(t *testing.T) {
     testsigning             No
     `)
 
-	result, err := CheckTestSigningAttribute(func() ([]byte, error) {
+	result, err := utils.CheckTestSigningAttribute(func() ([]byte, error) {
 		return mockOutput, nil
 	})
 
@@ -119,7 +121,7 @@ func Test_IsTestSigningEnabled_Disabled(t *testing.T) {
 
 func Test_IsTestSigningEnabled_Error(t *testing.T) {
 	expectedErr := errors.New("command failed")
-	_, err := CheckTestSigningAttribute(func() ([]byte, error) {
+	_, err := utils.CheckTestSigningAttribute(func() ([]byte, error) {
 		return nil, expectedErr
 	})
 
@@ -130,3 +132,13 @@ func Test_IsTestSigningEnabled_Error(t *testing.T) {
 		t.Fatalf("expected %v, got %v", expectedErr, err)
 	}
 }
+
+func Test_StopProcess_Commands(t *testing.T) {
+	runner := utilstest.NewRecordingRunner().Respond("Get-Process", "memfill", nil)
+	require.NoError(t, utils.StopProcess(runner, "memfill"))
+	require.Equal(t, []string{"Get-Process -Name memfill", "Stop-Process -Name memfill -Force"}, runner.Commands())
+
+	runner = utilstest.NewRecordingRunner().Respond("Get-Process", "", errors.New("Cannot find a process with the name \"memfill\""))
+	require.NoError(t, utils.StopProcess(runner, "memfill"))
+	require.Equal(t, []string{"Get-Process -Name memfill"}, runner.Commands())
+}
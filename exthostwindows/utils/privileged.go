@@ -0,0 +1,171 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// PrivilegedHelperCommand is the first argument of the extension binary starting the privileged helper.
+const PrivilegedHelperCommand = "privileged-helper"
+
+// privilegedCmdlets are the only cmdlets the privileged helper runs.
+var privilegedCmdlets = []string{"New-NetQosPolicy", "Remove-NetQosPolicy"}
+
+// PrivilegedResult is the result of an invocation run with SYSTEM privileges.
+type PrivilegedResult struct {
+	ExitCode int    `json:"exitCode"`
+	Output   string `json:"output"`
+	Error    string `json:"error"`
+}
+
+// Err returns an error if the invocation failed.
+func (r PrivilegedResult) Err() error {
+	if r.ExitCode == 0 {
+		return nil
+	}
+	return fmt.Errorf("exit code %d, output: %s, error: %s", r.ExitCode, r.Output, r.Error)
+}
+
+type privilegedRequest struct {
+	Id     uint64         `json:"id"`
+	Cmdlet string         `json:"cmdlet"`
+	Params map[string]any `json:"params,omitempty"`
+}
+
+type privilegedResponse struct {
+	Id uint64 `json:"id"`
+	PrivilegedResult
+}
+
+// runPrivileged runs the invocation in the current process, which is expected to run as SYSTEM.
+func runPrivileged(ctx context.Context, runner CommandRunner, invocation PowershellInvocation) PrivilegedResult {
+	if !slices.Contains(privilegedCmdlets, invocation.Cmdlet) {
+		return PrivilegedResult{ExitCode: 1, Error: fmt.Sprintf("cmdlet %s may not run privileged", invocation.Cmdlet)}
+	}
+	script, err := invocation.Script()
+	if err != nil {
+		return PrivilegedResult{ExitCode: 1, Error: err.Error()}
+	}
+	out, err := runner.RunPowershell(ctx, []string{script}, PSRun)
+	if err != nil {
+		return PrivilegedResult{ExitCode: 1, Error: err.Error()}
+	}
+	return PrivilegedResult{Output: out}
+}
+
+// servePrivileged answers the requests of a client of the privileged helper until the connection is closed. Each
+// request and response is a single line of JSON.
+func servePrivileged(ctx context.Context, conn io.ReadWriter, runner CommandRunner) error {
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), maxPowershellResponseSize)
+	for scanner.Scan() {
+		var request privilegedRequest
+		// numbers are kept as they are, so large values pass without loss of precision
+		decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		decoder.UseNumber()
+		if err := decoder.Decode(&request); err != nil {
+			return fmt.Errorf("invalid request: %w", err)
+		}
+		invocation := PowershellInvocation{Cmdlet: request.Cmdlet, Params: request.Params}
+		log.Info().Stringer("invocation", invocation).Msg("running privileged invocation")
+		result := runPrivileged(ctx, runner, invocation)
+		response, err := json.Marshal(privilegedResponse{Id: request.Id, PrivilegedResult: result})
+		if err != nil {
+			return err
+		}
+		if _, err := conn.Write(append(response, '\n')); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// privilegedClient sends invocations to the privileged helper, one at a time. It reconnects after the connection
+// broke or a request was cancelled.
+type privilegedClient struct {
+	connect func(ctx context.Context) (io.ReadWriteCloser, error)
+
+	lock    sync.Mutex
+	conn    io.ReadWriteCloser
+	scanner *bufio.Scanner
+	lastId  uint64
+}
+
+func (c *privilegedClient) run(ctx context.Context, invocation PowershellInvocation) (PrivilegedResult, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.conn == nil {
+		conn, err := c.connect(ctx)
+		if err != nil {
+			return PrivilegedResult{}, fmt.Errorf("failed to connect to privileged helper: %w", err)
+		}
+		c.conn = conn
+		c.scanner = bufio.NewScanner(conn)
+		c.scanner.Buffer(make([]byte, 64*1024), maxPowershellResponseSize)
+	}
+
+	c.lastId++
+	request, err := json.Marshal(privilegedRequest{Id: c.lastId, Cmdlet: invocation.Cmdlet, Params: invocation.Params})
+	if err != nil {
+		return PrivilegedResult{}, err
+	}
+	if _, err := c.conn.Write(append(request, '\n')); err != nil {
+		c.disconnect()
+		return PrivilegedResult{}, fmt.Errorf("failed to send request to privileged helper: %w", err)
+	}
+
+	responses := make(chan privilegedResponse, 1)
+	failed := make(chan error, 1)
+	go func(scanner *bufio.Scanner, id uint64) {
+		for scanner.Scan() {
+			var response privilegedResponse
+			if err := json.Unmarshal(scanner.Bytes(), &response); err != nil {
+				failed <- fmt.Errorf("invalid response of privileged helper: %w", err)
+				return
+			}
+			if response.Id == id {
+				responses <- response
+				return
+			}
+		}
+		failed <- errors.Join(errors.New("privileged helper closed the connection"), scanner.Err())
+	}(c.scanner, c.lastId)
+
+	select {
+	case response := <-responses:
+		return response.PrivilegedResult, nil
+	case err := <-failed:
+		c.disconnect()
+		return PrivilegedResult{}, err
+	case <-ctx.Done():
+		// the pending response is discarded together with the connection
+		c.disconnect()
+		return PrivilegedResult{}, ctx.Err()
+	}
+}
+
+func (c *privilegedClient) disconnect() {
+	if c.conn == nil {
+		return
+	}
+	conn := c.conn
+	c.conn, c.scanner = nil, nil
+	go func() { _ = conn.Close() }()
+}
+
+var defaultPrivilegedClient = sync.OnceValue(func() *privilegedClient {
+	return &privilegedClient{connect: connectPrivilegedHelper}
+})
@@ -0,0 +1,16 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+//go:build !windows
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// connectPrivilegedHelper fails on other platforms, they are only supported to run unit tests.
+func connectPrivilegedHelper(_ context.Context) (io.ReadWriteCloser, error) {
+	return nil, errors.New("the privileged helper is only supported on windows")
+}
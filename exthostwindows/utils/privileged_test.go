@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptRunner records the scripts of privileged invocations, which the helper runs with RunPowershell. The scripts
+// of the failing cmdlets fail with the error. Other commands aren't run by the helper.
+type scriptRunner struct {
+	CommandRunner
+	lock    sync.Mutex
+	scripts []string
+	failing map[string]error
+}
+
+func (r *scriptRunner) RunPowershell(_ context.Context, cmds []string, _ Shell) (string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	script := strings.Join(cmds, ";")
+	r.scripts = append(r.scripts, script)
+	for cmdlet, err := range r.failing {
+		if strings.Contains(script, cmdlet) {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+func (r *scriptRunner) recorded() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return slices.Clone(r.scripts)
+}
+
+// newPrivilegedTestClient connects a client with a helper serving the requests with the runner.
+func newPrivilegedTestClient(t *testing.T, runner CommandRunner) *privilegedClient {
+	return &privilegedClient{connect: func(ctx context.Context) (io.ReadWriteCloser, error) {
+		client, server := net.Pipe()
+		go func() {
+			defer func() { _ = server.Close() }()
+			_ = servePrivileged(ctx, server, runner)
+		}()
+		t.Cleanup(func() { _ = client.Close() })
+		return client, nil
+	}}
+}
+
+func TestPrivilegedClient_Run(t *testing.T) {
+	runner := &scriptRunner{failing: map[string]error{"Remove-NetQosPolicy": errors.New("No MSFT_NetQosPolicySettingData objects found")}}
+	client := newPrivilegedTestClient(t, runner)
+
+	tests := []struct {
+		name       string
+		invocation PowershellInvocation
+		want       PrivilegedResult
+	}{
+		{
+			name:       "succeeds",
+			invocation: PowershellInvocation{Cmdlet: "New-NetQosPolicy", Params: map[string]any{"Name": "STEADYBIT_QOS_1MB", "ThrottleRateActionBitsPerSecond": uint64(1 << 40)}},
+			want:       PrivilegedResult{},
+		},
+		{
+			name:       "fails",
+			invocation: PowershellInvocation{Cmdlet: "Remove-NetQosPolicy", Params: map[string]any{"Name": "STEADYBIT_QOS_1MB"}},
+			want:       PrivilegedResult{ExitCode: 1, Error: "No MSFT_NetQosPolicySettingData objects found"},
+		},
+		{
+			name:       "refuses other cmdlets",
+			invocation: PowershellInvocation{Cmdlet: "Stop-Computer"},
+			want:       PrivilegedResult{ExitCode: 1, Error: "cmdlet Stop-Computer may not run privileged"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := client.run(t.Context(), tt.invocation)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+		})
+	}
+
+	// parameters are passed without loss of precision
+	script, err := tests[0].invocation.Script()
+	require.NoError(t, err)
+	commands := runner.recorded()
+	require.Len(t, commands, 2)
+	assert.Equal(t, script, commands[0])
+}
+
+func TestPrivilegedClient_Reconnects(t *testing.T) {
+	connections := 0
+	client := &privilegedClient{connect: func(_ context.Context) (io.ReadWriteCloser, error) {
+		connections++
+		client, server := net.Pipe()
+		if connections == 1 {
+			// the helper exits without answering
+			go func() {
+				_, _ = server.Read(make([]byte, 1024))
+				_ = server.Close()
+			}()
+		} else {
+			go func() { _ = servePrivileged(context.Background(), server, &scriptRunner{}) }()
+		}
+		return client, nil
+	}}
+	invocation := PowershellInvocation{Cmdlet: "Remove-NetQosPolicy"}
+
+	_, err := client.run(t.Context(), invocation)
+	assert.ErrorContains(t, err, "privileged helper closed the connection")
+
+	result, err := client.run(t.Context(), invocation)
+	require.NoError(t, err)
+	assert.NoError(t, result.Err())
+	assert.Equal(t, 2, connections)
+}
+
+func TestPrivilegedClient_Cancel(t *testing.T) {
+	client := &privilegedClient{connect: func(_ context.Context) (io.ReadWriteCloser, error) {
+		client, server := net.Pipe()
+		// the helper never answers
+		go func() { _, _ = io.Copy(io.Discard, server) }()
+		return client, nil
+	}}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, err := client.run(ctx, PowershellInvocation{Cmdlet: "Remove-NetQosPolicy"})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, client.conn)
+}
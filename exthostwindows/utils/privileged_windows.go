@@ -0,0 +1,215 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package utils
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sys/windows"
+)
+
+const privilegedHelperTask = "SteadybitPrivilegedHelper"
+
+// privilegedHelperStarted is set once the scheduled task of the privileged helper was registered.
+var privilegedHelperStarted atomic.Bool
+
+// privilegedPipeName is unique per extension process, so a helper left over from a previous run doesn't conflict.
+func privilegedPipeName(parentPid int) string {
+	return fmt.Sprintf(`\\.\pipe\steadybit-extension-host-windows-privileged-%d`, parentPid)
+}
+
+// RunPrivilegedHelper runs the privileged helper until the extension process exits. The helper is started as SYSTEM by
+// a scheduled task and serves requests on a named pipe, which only SYSTEM and the user of the extension may access.
+func RunPrivilegedHelper(args []string) error {
+	flags := flag.NewFlagSet(PrivilegedHelperCommand, flag.ContinueOnError)
+	parentPid := flags.Int("parent-pid", 0, "process id of the extension")
+	clientSid := flags.String("client-sid", "", "security identifier of the user running the extension")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *parentPid == 0 || *clientSid == "" {
+		return errors.New("parent-pid and client-sid are required")
+	}
+	if _, err := windows.StringToSid(*clientSid); err != nil {
+		return fmt.Errorf("invalid client-sid %s: %w", *clientSid, err)
+	}
+
+	parent, err := windows.OpenProcess(windows.SYNCHRONIZE, false, uint32(*parentPid))
+	if err != nil {
+		return fmt.Errorf("failed to open extension process %d: %w", *parentPid, err)
+	}
+	go func() {
+		_, _ = windows.WaitForSingleObject(parent, windows.INFINITE)
+		log.Info().Int("pid", *parentPid).Msg("extension exited, stopping privileged helper")
+		os.Exit(0)
+	}()
+
+	sd, err := windows.SecurityDescriptorFromString(fmt.Sprintf("D:P(A;;GA;;;SY)(A;;GRGW;;;%s)", *clientSid))
+	if err != nil {
+		return fmt.Errorf("failed to create security descriptor: %w", err)
+	}
+	sa := &windows.SecurityAttributes{Length: uint32(unsafe.Sizeof(windows.SecurityAttributes{})), SecurityDescriptor: sd}
+	pipe := privilegedPipeName(*parentPid)
+	name, err := windows.UTF16PtrFromString(pipe)
+	if err != nil {
+		return err
+	}
+
+	runner := NewCommandRunner()
+	// the first instance makes sure no other process created the pipe before
+	openMode := uint32(windows.PIPE_ACCESS_DUPLEX | windows.FILE_FLAG_FIRST_PIPE_INSTANCE)
+	log.Info().Str("pipe", pipe).Msg("privileged helper listening")
+	for {
+		handle, err := windows.CreateNamedPipe(name, openMode, windows.PIPE_TYPE_BYTE|windows.PIPE_READMODE_BYTE|windows.PIPE_WAIT|windows.PIPE_REJECT_REMOTE_CLIENTS, windows.PIPE_UNLIMITED_INSTANCES, 64*1024, 64*1024, 0, sa)
+		if err != nil {
+			return fmt.Errorf("failed to create pipe %s: %w", pipe, err)
+		}
+		openMode = windows.PIPE_ACCESS_DUPLEX
+
+		if err := windows.ConnectNamedPipe(handle, nil); err != nil && !errors.Is(err, windows.ERROR_PIPE_CONNECTED) {
+			_ = windows.CloseHandle(handle)
+			log.Warn().Err(err).Msg("failed to accept client of privileged helper")
+			continue
+		}
+		go func() {
+			conn := os.NewFile(uintptr(handle), pipe)
+			defer func() { _ = conn.Close() }()
+			if err := servePrivileged(context.Background(), conn, runner); err != nil {
+				log.Warn().Err(err).Msg("client of privileged helper failed")
+			}
+		}()
+	}
+}
+
+// ClosePrivilegedHelper removes the scheduled task of the privileged helper, which stops the helper.
+func ClosePrivilegedHelper() {
+	if !privilegedHelperStarted.Load() {
+		return
+	}
+	if _, err := ExecutePowershellCommand(context.Background(), []string{fmt.Sprintf("Unregister-ScheduledTask -TaskName %s -Confirm:$false", privilegedHelperTask)}, PSRun); err != nil {
+		log.Warn().Err(err).Msg("failed to remove scheduled task of the privileged helper")
+	}
+}
+
+func connectPrivilegedHelper(ctx context.Context) (io.ReadWriteCloser, error) {
+	pipe := privilegedPipeName(os.Getpid())
+	conn, err := openPrivilegedPipe(pipe)
+	if errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+		if err := startPrivilegedHelper(ctx); err != nil {
+			return nil, err
+		}
+		conn, err = awaitPrivilegedPipe(ctx, pipe, 15*time.Second)
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Debug().Str("pipe", pipe).Msg("connected to privileged helper")
+	return conn, nil
+}
+
+func awaitPrivilegedPipe(ctx context.Context, pipe string, timeout time.Duration) (io.ReadWriteCloser, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		conn, err := openPrivilegedPipe(pipe)
+		if err == nil || !(errors.Is(err, windows.ERROR_FILE_NOT_FOUND) || errors.Is(err, windows.ERROR_PIPE_BUSY)) {
+			return conn, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("privileged helper did not start: %w", err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func openPrivilegedPipe(pipe string) (io.ReadWriteCloser, error) {
+	name, err := windows.UTF16PtrFromString(pipe)
+	if err != nil {
+		return nil, err
+	}
+	// the helper may only identify, not impersonate the extension
+	handle, err := windows.CreateFile(name, windows.GENERIC_READ|windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING, windows.SECURITY_SQOS_PRESENT|windows.SECURITY_IDENTIFICATION, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyPrivilegedPipeServer(handle); err != nil {
+		_ = windows.CloseHandle(handle)
+		return nil, err
+	}
+	return os.NewFile(uintptr(handle), pipe), nil
+}
+
+// verifyPrivilegedPipeServer makes sure that the server of the pipe runs as SYSTEM.
+func verifyPrivilegedPipeServer(handle windows.Handle) error {
+	var pid uint32
+	if err := windows.GetNamedPipeServerProcessId(handle, &pid); err != nil {
+		return fmt.Errorf("failed to get process of privileged helper: %w", err)
+	}
+	process, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return fmt.Errorf("failed to open process of privileged helper: %w", err)
+	}
+	defer func() { _ = windows.CloseHandle(process) }()
+
+	var token windows.Token
+	if err := windows.OpenProcessToken(process, windows.TOKEN_QUERY, &token); err != nil {
+		return fmt.Errorf("failed to open token of privileged helper: %w", err)
+	}
+	defer func() { _ = token.Close() }()
+	user, err := token.GetTokenUser()
+	if err != nil {
+		return fmt.Errorf("failed to get user of privileged helper: %w", err)
+	}
+	if sid := user.User.Sid.String(); sid != systemSID {
+		return fmt.Errorf("privileged helper runs as %s instead of SYSTEM", sid)
+	}
+	return nil
+}
+
+// startPrivilegedHelper starts the extension binary as privileged helper in a scheduled task running as SYSTEM.
+func startPrivilegedHelper(ctx context.Context) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	user, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		return fmt.Errorf("failed to get user of the extension: %w", err)
+	}
+	task, err := json.Marshal(map[string]string{
+		"name":     privilegedHelperTask,
+		"execute":  executable,
+		"argument": fmt.Sprintf("%s --parent-pid %d --client-sid %s", PrivilegedHelperCommand, os.Getpid(), user.User.Sid.String()),
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("task", privilegedHelperTask).Msg("starting privileged helper")
+	privilegedHelperStarted.Store(true)
+	_, err = ExecutePowershellCommand(ctx, []string{
+		fmt.Sprintf("$task = ConvertFrom-Json ([Text.Encoding]::UTF8.GetString([Convert]::FromBase64String('%s')))", base64.StdEncoding.EncodeToString(task)),
+		"$A = New-ScheduledTaskAction -Execute $task.execute -Argument $task.argument",
+		"$P = New-ScheduledTaskPrincipal -UserId 'SYSTEM' -LogonType ServiceAccount -RunLevel Highest",
+		"$S = New-ScheduledTaskSettingsSet -ExecutionTimeLimit ([TimeSpan]::Zero) -MultipleInstances Parallel",
+		"Register-ScheduledTask -TaskName $task.name -Action $A -Principal $P -Settings $S -Force | Out-Null",
+		"Start-ScheduledTask -TaskName $task.name",
+	}, PSRun)
+	if err != nil {
+		return fmt.Errorf("failed to start privileged helper: %w", err)
+	}
+	return nil
+}
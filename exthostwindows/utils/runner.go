@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// CommandRunner runs the external commands of the extension. Actions get it injected, so their commands can be
+// recorded in tests and all commands changing the host pass through a single place.
+type CommandRunner interface {
+	// Run runs the command and waits for it to exit. It returns the combined output.
+	Run(ctx context.Context, name string, args ...string) (string, error)
+	// RunPowershell runs the commands in a powershell session, see ExecutePowershellCommand.
+	// Callers must make sure that passed in commands are properly sanitized.
+	RunPowershell(ctx context.Context, cmds []string, shell Shell) (string, error)
+	// RunPrivileged runs the invocation as SYSTEM. If the extension doesn't run as SYSTEM, the invocation is sent to
+	// the privileged helper. The error is only set if the invocation couldn't be run, failures are part of the result.
+	RunPrivileged(ctx context.Context, invocation PowershellInvocation) (PrivilegedResult, error)
+	// Start starts the command without waiting for it to exit.
+	Start(cmd Command) (Process, error)
+}
+
+// Command is a command started in the background.
+type Command struct {
+	Name string
+	Args []string
+	// Stdin is read by the process, if set.
+	Stdin io.Reader
+	// Stdout and Stderr receive the output of the process. If unset, the combined output is returned by Process.Wait.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+func (c Command) String() string {
+	return strings.Join(append([]string{c.Name}, c.Args...), " ")
+}
+
+// Process is a command started by a CommandRunner.
+type Process interface {
+	// Wait waits for the process to exit and returns its combined output, unless the output was redirected.
+	Wait() ([]byte, error)
+	Kill() error
+	Pid() int
+}
+
+type execRunner struct {
+	pool *PowershellPool
+	job  processJob
+}
+
+// NewCommandRunner returns a runner executing the commands on the host. Powershell scripts run with PSRun are executed
+// by long-lived powershell hosts, see PowershellPool. Started processes are killed when the extension exits.
+func NewCommandRunner() CommandRunner {
+	return &execRunner{pool: defaultPowershellPool(), job: defaultProcessJob()}
+}
+
+// NewNetworkCommandRunner returns a runner like NewCommandRunner, which runs powershell scripts in hosts reserved for
+// network attacks. Applying and reverting them doesn't wait for scripts of other actions.
+func NewNetworkCommandRunner() CommandRunner {
+	return &execRunner{pool: networkPowershellPool(), job: defaultProcessJob()}
+}
+
+func (r *execRunner) Run(ctx context.Context, name string, args ...string) (string, error) {
+	log.Debug().Str("name", name).Strs("args", args).Msg("running command")
+	cmd := exec.CommandContext(ctx, name, args...)
+	hideWindow(cmd)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := startInJob(r.job, cmd); err != nil {
+		return "", err
+	}
+	err := cmd.Wait()
+	return out.String(), err
+}
+
+func (r *execRunner) RunPowershell(ctx context.Context, cmds []string, shell Shell) (string, error) {
+	if shell == PSRun && r.pool != nil {
+		return r.pool.Run(ctx, cmds)
+	}
+	return ExecutePowershellCommand(ctx, cmds, shell)
+}
+
+func (r *execRunner) RunPrivileged(ctx context.Context, invocation PowershellInvocation) (PrivilegedResult, error) {
+	if isRunningAsSystem() {
+		return runPrivileged(ctx, r, invocation), nil
+	}
+	return defaultPrivilegedClient().run(ctx, invocation)
+}
+
+func (r *execRunner) Start(command Command) (Process, error) {
+	log.Info().Str("name", command.Name).Strs("args", command.Args).Msg("starting command")
+	cmd := exec.Command(command.Name, command.Args...)
+	hideWindow(cmd)
+
+	p := &execProcess{cmd: cmd}
+	cmd.Stdin = command.Stdin
+	cmd.Stdout = command.Stdout
+	cmd.Stderr = command.Stderr
+	if cmd.Stdout == nil {
+		cmd.Stdout = &p.output
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = &p.output
+	}
+	if err := startInJob(r.job, cmd); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+type execProcess struct {
+	cmd    *exec.Cmd
+	output bytes.Buffer
+}
+
+func (p *execProcess) Wait() ([]byte, error) {
+	err := p.cmd.Wait()
+	return p.output.Bytes(), err
+}
+
+func (p *execProcess) Kill() error {
+	return p.cmd.Process.Kill()
+}
+
+func (p *execProcess) Pid() int {
+	return p.cmd.Process.Pid
+}
@@ -12,7 +12,6 @@ import (
 	"os/user"
 	"strings"
 	"sync"
-	"syscall"
 
 	"github.com/rs/zerolog/log"
 )
@@ -36,7 +35,10 @@ func ExecutePowershellCommand(ctx context.Context, cmds []string, shell Shell) (
 		cmd := exec.CommandContext(ctx, "powershell", "-Command", commands) //NOSONAR commands are sanitized
 		cmd.Stdout = &outb
 		cmd.Stderr = &errb
-		err := cmd.Run()
+		err := startInJob(defaultProcessJob(), cmd)
+		if err == nil {
+			err = cmd.Wait()
+		}
 		out := strings.TrimSpace(outb.String())
 		if err != nil {
 			return "", fmt.Errorf("execution failed: %w, output: %s, error: %s", err, out, errb.String())
@@ -46,8 +48,8 @@ func ExecutePowershellCommand(ctx context.Context, cmds []string, shell Shell) (
 		cmd := exec.Command("powershell", "-Command", commands) //NOSONAR commands are sanitized
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
-		cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
-		return "", cmd.Start()
+		hideWindow(cmd)
+		return "", startInJob(defaultProcessJob(), cmd)
 	}
 }
 
@@ -72,23 +74,6 @@ var isRunningAsSystem = func() bool {
 	return isSystemCached
 }
 
-// BuildSystemCommandFor builds up the commands to wrap the given one into a scheduled task executed in SYSTEM scope.
-// If the extension is already running as SYSTEM, the command is returned directly without wrapping.
-func BuildSystemCommandFor(cmd string) []string {
-	if isRunningAsSystem() {
-		log.Debug().Msg("already running as SYSTEM, skipping scheduled task wrapper")
-		return []string{cmd}
-	}
-	escapedCmd := strings.ReplaceAll(cmd, "$", "`$")
-	scheduledTaskAction := fmt.Sprintf("$A=New-ScheduledTaskAction -Execute powershell -Argument \"-WindowStyle Hidden -Command %s\"", escapedCmd)
-	principal := "$P=New-ScheduledTaskPrincipal -UserId \"SYSTEM\" -RunLevel Highest"
-	registerScheduledTask := "Register-ScheduledTask SteadybitTempQoSPolicyTask -Action $A -Principal $P"
-	startTask := "Start-ScheduledTask SteadybitTempQoSPolicyTask"
-	awaitExecution := "for($i=0;$i -lt 20;$i++){if((Get-ScheduledTask -TaskName SteadybitTempQoSPolicyTask).State -ne 'Running'){break};Start-Sleep -Milliseconds 100};"
-	unregisterScheduledTask := "try{ Unregister-ScheduledTask SteadybitTempQoSPolicyTask -Confirm:$false } catch {}"
-	return []string{unregisterScheduledTask, scheduledTaskAction, principal, registerScheduledTask, startTask, awaitExecution, unregisterScheduledTask}
-}
-
 func SanitizePowershellArgs(args ...string) []string {
 	var sanitizedArgs []string
 	for _, arg := range args {
@@ -0,0 +1,10 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+//go:build !windows
+
+package utils
+
+import "os/exec"
+
+// hideWindow is a no-op on other platforms, they are only supported to run unit tests.
+func hideWindow(_ *exec.Cmd) {}
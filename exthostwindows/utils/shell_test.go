@@ -15,27 +15,6 @@ func Test_TrimShellOutput(t *testing.T) {
 	require.Equal(t, "hello world", command)
 }
 
-func Test_BuildSystemCommandFor_wrapsInScheduledTask_whenNotRunningAsSystem(t *testing.T) {
-	originalFn := isRunningAsSystem
-	defer func() { isRunningAsSystem = originalFn }()
-	isRunningAsSystem = func() bool { return false }
-
-	cmds := BuildSystemCommandFor("New-NetQosPolicy -Name test -Confirm:$false")
-
-	require.Len(t, cmds, 7)
-	require.Contains(t, cmds[1], "-Confirm:`$false")
-}
-
-func Test_BuildSystemCommandFor_runsDirectly_whenRunningAsSystem(t *testing.T) {
-	originalFn := isRunningAsSystem
-	defer func() { isRunningAsSystem = originalFn }()
-	isRunningAsSystem = func() bool { return true }
-
-	cmds := BuildSystemCommandFor("New-NetQosPolicy -Name test -Confirm:$false")
-
-	require.Equal(t, []string{"New-NetQosPolicy -Name test -Confirm:$false"}, cmds)
-}
-
 func Test_sanitizePowerShellArg(t *testing.T) {
 	tests := []struct {
 		name     string
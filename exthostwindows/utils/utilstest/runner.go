@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+// Package utilstest provides test doubles for the utils package. It is only imported by tests.
+package utilstest
+
+import (
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
+)
+
+// RecordingRunner is a utils.CommandRunner for tests. It records the commands instead of running them.
+type RecordingRunner struct {
+	lock      sync.Mutex
+	commands  []string
+	responses []recordedResponse
+}
+
+type recordedResponse struct {
+	pattern string
+	output  string
+	err     error
+}
+
+var _ utils.CommandRunner = (*RecordingRunner)(nil)
+
+func NewRecordingRunner() *RecordingRunner {
+	return &RecordingRunner{}
+}
+
+// Respond makes all commands containing the pattern return the output and error. The first matching response wins.
+func (r *RecordingRunner) Respond(pattern string, output string, err error) *RecordingRunner {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.responses = append(r.responses, recordedResponse{pattern: pattern, output: output, err: err})
+	return r
+}
+
+// Commands returns the recorded commands. Commands are recorded with their arguments separated by spaces, commands
+// run in a powershell session are recorded as the script passed to powershell and privileged invocations as described
+// by utils.PowershellInvocation.String.
+func (r *RecordingRunner) Commands() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return slices.Clone(r.commands)
+}
+
+func (r *RecordingRunner) Run(_ context.Context, name string, args ...string) (string, error) {
+	return r.record(utils.Command{Name: name, Args: args}.String())
+}
+
+func (r *RecordingRunner) RunPowershell(_ context.Context, cmds []string, _ utils.Shell) (string, error) {
+	return r.record(strings.Join(cmds, ";"))
+}
+
+func (r *RecordingRunner) RunPrivileged(_ context.Context, invocation utils.PowershellInvocation) (utils.PrivilegedResult, error) {
+	output, err := r.record(invocation.String())
+	if err != nil {
+		return utils.PrivilegedResult{ExitCode: 1, Output: output, Error: err.Error()}, nil
+	}
+	return utils.PrivilegedResult{Output: output}, nil
+}
+
+// Start records the command. The process exits immediately, its output is written to Stdout if set.
+func (r *RecordingRunner) Start(cmd utils.Command) (utils.Process, error) {
+	output, err := r.record(cmd.String())
+	if err != nil {
+		return nil, err
+	}
+	if cmd.Stdout != nil {
+		_, _ = io.WriteString(cmd.Stdout, output)
+		output = ""
+	}
+	return &recordedProcess{output: output}, nil
+}
+
+func (r *RecordingRunner) record(command string) (string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.commands = append(r.commands, command)
+	for _, response := range r.responses {
+		if strings.Contains(command, response.pattern) {
+			return response.output, response.err
+		}
+	}
+	return "", nil
+}
+
+// recordedProcess exits immediately with the recorded output.
+type recordedProcess struct {
+	output string
+}
+
+func (p *recordedProcess) Wait() ([]byte, error) {
+	return []byte(p.output), nil
+}
+
+func (p *recordedProcess) Kill() error {
+	return nil
+}
+
+// Pid returns 0, recorded processes don't run on the host.
+func (p *recordedProcess) Pid() int {
+	return 0
+}
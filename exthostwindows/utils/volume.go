@@ -0,0 +1,17 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package utils
+
+// VolumeSpace is the space of a volume in bytes.
+type VolumeSpace struct {
+	// Available is the free space usable by the extension, which respects disk quotas.
+	Available uint64
+	Total     uint64
+}
+
+// GetVolumeSpace returns the space of the volume containing the path. Unlike GetDriveSpace it queries the file system
+// directly instead of starting PowerShell, so it is cheap enough to be polled.
+func GetVolumeSpace(path string) (VolumeSpace, error) {
+	return volumeSpace(path)
+}
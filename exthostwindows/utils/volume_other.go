@@ -0,0 +1,23 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+//go:build !windows
+
+package utils
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// volumeSpace is only supported on other platforms to run unit tests.
+func volumeSpace(path string) (VolumeSpace, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return VolumeSpace{}, fmt.Errorf("failed to get space of volume of %s: %w", path, err)
+	}
+	return VolumeSpace{
+		Available: uint64(stat.Bavail) * uint64(stat.Bsize),
+		Total:     uint64(stat.Blocks) * uint64(stat.Bsize),
+	}, nil
+}
@@ -0,0 +1,22 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetVolumeSpace(t *testing.T) {
+	space, err := GetVolumeSpace(t.TempDir())
+	require.NoError(t, err)
+	assert.Positive(t, space.Total)
+	assert.LessOrEqual(t, space.Available, space.Total)
+
+	_, err = GetVolumeSpace(filepath.Join(t.TempDir(), "missing"))
+	assert.ErrorContains(t, err, "failed to get space of volume")
+}
@@ -0,0 +1,23 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package utils
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+func volumeSpace(path string) (VolumeSpace, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return VolumeSpace{}, err
+	}
+	var space VolumeSpace
+	var totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(pathPtr, &space.Available, &space.Total, &totalFree); err != nil {
+		return VolumeSpace{}, fmt.Errorf("failed to get space of volume of %s: %w", path, err)
+	}
+	return space, nil
+}
@@ -5,6 +5,7 @@
 package main
 
 import (
+	"os"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -15,6 +16,7 @@ import (
 	"github.com/steadybit/discovery-kit/go/discovery_kit_sdk"
 	"github.com/steadybit/extension-host-windows/config"
 	"github.com/steadybit/extension-host-windows/exthostwindows"
+	"github.com/steadybit/extension-host-windows/exthostwindows/utils"
 	"github.com/steadybit/extension-kit/extbuild"
 	"github.com/steadybit/extension-kit/exthealth"
 	"github.com/steadybit/extension-kit/exthttp"
@@ -28,6 +30,14 @@ var startedAt = time.Now().Format(time.RFC3339)
 func main() {
 	extlogging.InitZeroLog()
 
+	// The extension binary also runs the helper executing privileged commands as SYSTEM, see utils.RunPrivilegedHelper.
+	if len(os.Args) > 1 && os.Args[1] == utils.PrivilegedHelperCommand {
+		if err := utils.RunPrivilegedHelper(os.Args[2:]); err != nil {
+			log.Fatal().Err(err).Msg("privileged helper failed")
+		}
+		return
+	}
+
 	// Register a QoS policy cleanup routine as additional safeguard.
 	stopQosCleanup := exthostwindows.RegisterQosPolicyCleanup()
 	extensionRegistry := exthostwindows.NewExtensionRegistry("Extension Host Windows", 0, []string{"ACTION", "DISCOVERY"})
@@ -39,6 +49,8 @@ func main() {
 			log.Error().Err(err).Msg("unable to remove local discovery from the Windows registry")
 		}
 		stopQosCleanup()
+		utils.ClosePowershellHosts()
+		utils.ClosePrivilegedHelper()
 		exthttp.StopListen()
 	})
 
@@ -53,14 +65,28 @@ func main() {
 	exthealth.SetReady(false)
 	exthealth.StartProbes(int(config.Config.HealthPort))
 
+	// Revert network attacks left over from a previous run, e.g. if the extension crashed during an attack.
+	exthostwindows.RevertLeftoverNetworkAttacks()
+	// Delete test files left over by IO stress attacks.
+	exthostwindows.RemoveLeftoverIoStressFiles()
+
 	action_kit_sdk.RegisterAction(exthostwindows.NewShutdownAction())
 	action_kit_sdk.RegisterAction(exthostwindows.NewStopProcessAction())
-	action_kit_sdk.RegisterAction(exthostwindows.NewNetworkBlockDnsContainerAction())
-	action_kit_sdk.RegisterAction(exthostwindows.NewNetworkBlackholeContainerAction())
-	action_kit_sdk.RegisterAction(exthostwindows.NewNetworkLimitBandwidthContainerAction())
-	action_kit_sdk.RegisterAction(exthostwindows.NewNetworkDelayContainerAction())
-	action_kit_sdk.RegisterAction(exthostwindows.NewNetworkCorruptPackagesContainerAction())
-	action_kit_sdk.RegisterAction(exthostwindows.NewNetworkPackageLossContainerAction())
+	networkActions := []action_kit_sdk.Action[exthostwindows.NetworkActionState]{
+		exthostwindows.NewNetworkBlockDnsContainerAction(),
+		exthostwindows.NewNetworkBlackholeContainerAction(),
+		exthostwindows.NewNetworkLimitBandwidthContainerAction(),
+		exthostwindows.NewNetworkDelayContainerAction(),
+		exthostwindows.NewNetworkCorruptPackagesContainerAction(),
+		exthostwindows.NewNetworkPackageLossContainerAction(),
+		exthostwindows.NewNetworkPackageDuplicationContainerAction(),
+		exthostwindows.NewNetworkPackageReorderingContainerAction(),
+		exthostwindows.NewNetworkTcpResetContainerAction(),
+	}
+	for _, action := range networkActions {
+		action_kit_sdk.RegisterAction(action)
+	}
+	action_kit_sdk.RegisterAction(exthostwindows.NewNetworkDnsErrorAction())
 	action_kit_sdk.RegisterAction(exthostwindows.NewTimetravelAction())
 	action_kit_sdk.RegisterAction(exthostwindows.NewStressCpuAction())
 	action_kit_sdk.RegisterAction(exthostwindows.NewStressIoAction())
@@ -70,6 +96,8 @@ func main() {
 	discovery_kit_sdk.Register(exthostwindows.NewHostDiscovery())
 
 	exthttp.RegisterHttpHandler("/", exthttp.IfNoneMatchHandler(func() string { return startedAt }, exthttp.GetterAsHandler(getExtensionList)))
+	// Returns the filters and commands network actions would run, to debug network attacks without applying them.
+	exthostwindows.RegisterNetworkDryRunHandler(networkActions...)
 
 	extsignals.ActivateSignalHandlers()
 
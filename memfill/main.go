@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/elastic/go-sysinfo"
+)
+
+func main() {
+	if len(os.Args) > 1 && (os.Args[1] == "--help" || os.Args[1] == "-h") {
+		fmt.Println("'memfill' allocates and holds memory for the given duration.")
+		fmt.Println("usage: memfill <size><%|MiB> <usage|absolute> <duration>\nexample: memfill 80% usage 30s")
+		fmt.Println("In usage mode the memory usage of the host is held at the size, in absolute mode the size is allocated.")
+		fmt.Println("The allocated bytes are written to stdout as one line of JSON per second.")
+		os.Exit(0)
+	}
+
+	opts, err := parseArgs(os.Args[1:])
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if err := run(opts, readHostMemory, &filler{chunkSize: chunkSize}, os.Stdout, time.Second); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func readHostMemory() (memoryInfo, error) {
+	host, err := sysinfo.Host()
+	if err != nil {
+		return memoryInfo{}, err
+	}
+	memory, err := host.Memory()
+	if err != nil {
+		return memoryInfo{}, err
+	}
+	return memoryInfo{Total: memory.Total, Available: memory.Available}, nil
+}
@@ -0,0 +1,172 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type mode string
+
+const (
+	modeUsage    mode = "usage"
+	modeAbsolute mode = "absolute"
+
+	mebibyte = 1024 * 1024
+	pageSize = 4096
+	// chunkSize is the granularity of allocations. Usage deviating less than a chunk from the target is not adjusted.
+	chunkSize = 16 * mebibyte
+	// defaultReserve is never allocated, so the host stays responsive.
+	defaultReserve = 64 * mebibyte
+)
+
+type options struct {
+	size     uint64
+	percent  bool
+	mode     mode
+	duration time.Duration
+	// reserve is the memory left available.
+	reserve uint64
+}
+
+// parseArgs parses the arguments "<size><unit> <mode> <duration>", e.g. "80% usage 30s" or "512MiB absolute 30s".
+func parseArgs(args []string) (options, error) {
+	if len(args) != 3 {
+		return options{}, fmt.Errorf("expected 3 arguments, got %d", len(args))
+	}
+
+	opts := options{reserve: defaultReserve}
+	size, percent := strings.CutSuffix(args[0], "%")
+	if !percent {
+		var ok bool
+		if size, ok = strings.CutSuffix(args[0], "MiB"); !ok {
+			return options{}, fmt.Errorf("size %s must end with %% or MiB", args[0])
+		}
+	}
+	parsed, err := strconv.ParseUint(size, 10, 64)
+	if err != nil || parsed == 0 {
+		return options{}, fmt.Errorf("invalid size %s", args[0])
+	}
+	if percent && parsed > 100 {
+		return options{}, fmt.Errorf("size %s must not exceed 100%%", args[0])
+	}
+	opts.size, opts.percent = parsed, percent
+
+	opts.mode = mode(args[1])
+	if opts.mode != modeUsage && opts.mode != modeAbsolute {
+		return options{}, fmt.Errorf("mode must be %s or %s, got %s", modeUsage, modeAbsolute, args[1])
+	}
+
+	if opts.duration, err = time.ParseDuration(args[2]); err != nil {
+		return options{}, fmt.Errorf("invalid duration %s: %w", args[2], err)
+	}
+	return opts, nil
+}
+
+// bytes returns the requested size in bytes.
+func (o options) bytes(total uint64) uint64 {
+	if o.percent {
+		return total * o.size / 100
+	}
+	return o.size * mebibyte
+}
+
+type memoryInfo struct {
+	Total     uint64
+	Available uint64
+}
+
+type memoryReader func() (memoryInfo, error)
+
+// adjustment returns the number of bytes to allocate, or to free if negative. In usage mode the total usage of the
+// host is adjusted to the requested size, in absolute mode the allocated bytes. Allocations are limited to the
+// available memory.
+func (o options) adjustment(info memoryInfo, allocated uint64) int64 {
+	var delta int64
+	if o.mode == modeUsage {
+		used := info.Total - info.Available
+		delta = int64(o.bytes(info.Total)) - int64(used)
+	} else {
+		delta = int64(o.bytes(info.Total)) - int64(allocated)
+	}
+
+	if delta < 0 {
+		return max(delta, -int64(allocated))
+	}
+	if info.Available < o.reserve {
+		return 0
+	}
+	return min(delta, int64(info.Available-o.reserve))
+}
+
+// filler holds the allocated memory in chunks.
+type filler struct {
+	chunkSize uint64
+	chunks    [][]byte
+}
+
+func (f *filler) allocated() uint64 {
+	return uint64(len(f.chunks)) * f.chunkSize
+}
+
+// adjust allocates or frees whole chunks. Adjustments smaller than a chunk are ignored.
+func (f *filler) adjust(delta int64) {
+	for ; delta >= int64(f.chunkSize); delta -= int64(f.chunkSize) {
+		chunk := make([]byte, f.chunkSize)
+		// touching every page makes sure the memory is committed
+		for i := 0; i < len(chunk); i += pageSize {
+			chunk[i] = 1
+		}
+		f.chunks = append(f.chunks, chunk)
+	}
+
+	freed := false
+	for ; delta <= -int64(f.chunkSize) && len(f.chunks) > 0; delta += int64(f.chunkSize) {
+		f.chunks[len(f.chunks)-1] = nil
+		f.chunks = f.chunks[:len(f.chunks)-1]
+		freed = true
+	}
+	if freed {
+		debug.FreeOSMemory()
+	}
+}
+
+// report is written to stdout as one line of JSON per interval.
+type report struct {
+	Allocated uint64 `json:"allocated"`
+	Used      uint64 `json:"used"`
+	Total     uint64 `json:"total"`
+}
+
+// run fills the memory until the duration elapsed, adjusting the allocation once per interval.
+func run(opts options, readMemory memoryReader, f *filler, out io.Writer, interval time.Duration) error {
+	encoder := json.NewEncoder(out)
+	deadline := time.Now().Add(opts.duration)
+	for {
+		info, err := readMemory()
+		if err != nil {
+			return fmt.Errorf("failed to read memory usage: %w", err)
+		}
+		f.adjust(opts.adjustment(info, f.allocated()))
+
+		if info, err = readMemory(); err != nil {
+			return fmt.Errorf("failed to read memory usage: %w", err)
+		}
+		if err := encoder.Encode(report{Allocated: f.allocated(), Used: info.Total - info.Available, Total: info.Total}); err != nil {
+			return err
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil
+		}
+		time.Sleep(min(interval, remaining))
+	}
+}
@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    options
+		wantErr string
+	}{
+		{name: "percent", args: []string{"80%", "usage", "30s"}, want: options{size: 80, percent: true, mode: modeUsage, duration: 30 * time.Second, reserve: defaultReserve}},
+		{name: "mebibytes", args: []string{"512MiB", "absolute", "1m"}, want: options{size: 512, mode: modeAbsolute, duration: time.Minute, reserve: defaultReserve}},
+		{name: "missing unit", args: []string{"512", "absolute", "1m"}, wantErr: "size 512 must end with % or MiB"},
+		{name: "above 100 percent", args: []string{"101%", "usage", "1m"}, wantErr: "size 101% must not exceed 100%"},
+		{name: "zero", args: []string{"0MiB", "usage", "1m"}, wantErr: "invalid size 0MiB"},
+		{name: "invalid mode", args: []string{"10%", "fill", "1m"}, wantErr: "mode must be usage or absolute, got fill"},
+		{name: "missing duration", args: []string{"10%", "usage"}, wantErr: "expected 3 arguments, got 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseArgs(tt.args)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestOptions_Adjustment(t *testing.T) {
+	const gib = 1024 * mebibyte
+	tests := []struct {
+		name      string
+		opts      options
+		info      memoryInfo
+		allocated uint64
+		want      int64
+	}{
+		{
+			name: "usage grows to target",
+			opts: options{size: 80, percent: true, mode: modeUsage},
+			info: memoryInfo{Total: 10 * gib, Available: 6 * gib},
+			want: 4 * gib,
+		},
+		{
+			name:      "usage shrinks if others allocated memory",
+			opts:      options{size: 80, percent: true, mode: modeUsage},
+			info:      memoryInfo{Total: 10 * gib, Available: 1 * gib},
+			allocated: 4 * gib,
+			want:      -1 * gib,
+		},
+		{
+			name:      "usage frees at most the allocated memory",
+			opts:      options{size: 20, percent: true, mode: modeUsage},
+			info:      memoryInfo{Total: 10 * gib, Available: 4 * gib},
+			allocated: 1 * gib,
+			want:      -1 * gib,
+		},
+		{
+			name: "usage in mebibytes",
+			opts: options{size: 5 * 1024, mode: modeUsage},
+			info: memoryInfo{Total: 10 * gib, Available: 8 * gib},
+			want: 3 * gib,
+		},
+		{
+			name:      "absolute ignores other usage",
+			opts:      options{size: 1024, mode: modeAbsolute},
+			info:      memoryInfo{Total: 10 * gib, Available: 2 * gib},
+			allocated: 512 * mebibyte,
+			want:      512 * mebibyte,
+		},
+		{
+			name: "absolute in percent",
+			opts: options{size: 10, percent: true, mode: modeAbsolute},
+			info: memoryInfo{Total: 10 * gib, Available: 8 * gib},
+			want: 1 * gib,
+		},
+		{
+			name: "limited to available memory",
+			opts: options{size: 100, percent: true, mode: modeUsage, reserve: defaultReserve},
+			info: memoryInfo{Total: 10 * gib, Available: 1 * gib},
+			want: 1*gib - defaultReserve,
+		},
+		{
+			name: "nothing available",
+			opts: options{size: 4096, mode: modeAbsolute, reserve: defaultReserve},
+			info: memoryInfo{Total: 10 * gib, Available: defaultReserve / 2},
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.opts.adjustment(tt.info, tt.allocated))
+		})
+	}
+}
+
+func TestFiller_Adjust(t *testing.T) {
+	f := &filler{chunkSize: 2 * pageSize}
+
+	f.adjust(5 * pageSize)
+	assert.Equal(t, uint64(4*pageSize), f.allocated())
+
+	// smaller than a chunk
+	f.adjust(pageSize)
+	f.adjust(-pageSize)
+	assert.Equal(t, uint64(4*pageSize), f.allocated())
+
+	f.adjust(-2 * pageSize)
+	assert.Equal(t, uint64(2*pageSize), f.allocated())
+
+	f.adjust(-10 * pageSize)
+	assert.Equal(t, uint64(0), f.allocated())
+}
+
+func TestRun_ReachesTargetUsage(t *testing.T) {
+	const total = 1024 * pageSize
+	f := &filler{chunkSize: 8 * pageSize}
+	// other processes use a quarter of the memory
+	readMemory := func() (memoryInfo, error) {
+		return memoryInfo{Total: total, Available: total*3/4 - f.allocated()}, nil
+	}
+	opts := options{size: 50, mode: modeUsage, percent: true, duration: 30 * time.Millisecond}
+
+	var out bytes.Buffer
+	require.NoError(t, run(opts, readMemory, f, &out, 10*time.Millisecond))
+
+	var reports []report
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var r report
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &r))
+		reports = append(reports, r)
+	}
+	require.NotEmpty(t, reports)
+	last := reports[len(reports)-1]
+	assert.Equal(t, report{Allocated: total / 4, Used: total / 2, Total: total}, last)
+}
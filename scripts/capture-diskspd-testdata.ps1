@@ -0,0 +1,29 @@
+# Captures the diskspd output the parser in exthostwindows/diskspd is tested with. Run it on a Windows host with
+# diskspd on the PATH, the command lines must match the ones recorded in exthostwindows/diskspd/testdata_test.go.
+param(
+  [string]$Diskspd = "diskspd.exe",
+  [string]$TestDir = $env:TEMP
+)
+
+$ErrorActionPreference = "Stop"
+$testdataPath = "$PSScriptRoot\..\exthostwindows\diskspd\testdata"
+# diskspd output is parsed as is, so it is written without a byte order mark
+$utf8 = New-Object System.Text.UTF8Encoding $false
+$testFile = Join-Path $TestDir "steadybit-diskspd-testdata.dat"
+
+$runs = @{
+  "sequential-read" = @("-d10", "-F4", "-b64K", "-o2", "-c1024M", "-L", "-W0", "-Sh")
+  "random-mixed"    = @("-d10", "-F2", "-b8K", "-o8", "-w30", "-r", "-c1024M", "-L", "-W0", "-Sh")
+}
+
+try {
+  foreach ($name in $runs.Keys) {
+    Write-Output "Capturing $name"
+    $text = & $Diskspd @($runs[$name] + $testFile) | Out-String
+    [System.IO.File]::WriteAllText("$testdataPath\$name.txt", $text, $utf8)
+    $xml = & $Diskspd @($runs[$name] + "-Rxml" + $testFile) | Out-String
+    [System.IO.File]::WriteAllText("$testdataPath\$name.xml", $xml, $utf8)
+  }
+} finally {
+  Remove-Item -Path $testFile -Force -ErrorAction SilentlyContinue
+}
@@ -0,0 +1,25 @@
+# Captures the cmdlet output the extension decodes with the same ConvertTo-Json pipelines it runs. Run it in Windows
+# PowerShell as administrator, the pipelines must match the commands recorded by the fixture tests.
+$ErrorActionPreference = "Stop"
+# the output is decoded as is, so it is written without a byte order mark
+$utf8 = New-Object System.Text.UTF8Encoding $false
+$exthostwindows = "$PSScriptRoot\..\exthostwindows"
+
+$captures = [ordered]@{
+  "utils\testdata\get-volume.json"         = 'ConvertTo-Json -Compress -Depth 4 -InputObject @(Get-Volume | Select-Object -Property DriveLetter,Size,SizeRemaining)'
+  "utils\testdata\get-physicaldisk.json"   = 'ConvertTo-Json -Compress -Depth 4 -InputObject @(Get-PhysicalDisk | Select-Object -Property DeviceId,FriendlyName,Size)'
+  "network\testdata\get-netqospolicy.json" = 'ConvertTo-Json -Compress -Depth 4 -InputObject @(Get-NetQosPolicy | Select-Object -Property Name,Precedence,IPDstPrefixMatchCondition,IPDstPortStartMatchCondition,IPDstPortEndMatchCondition,ThrottleRateAction)'
+}
+
+# the policies the network fixture test expects next to the ones already configured on the host
+New-NetQosPolicy -Name "STEADYBIT_QOS_1mbit_eb1893d5_0" -Precedence 255 -IPDstPrefixMatchCondition "10.0.0.0/24" -IPDstPortStartMatchCondition 5432 -IPDstPortEndMatchCondition 5432 -ThrottleRateActionBitsPerSecond 1000000 -Confirm:$false | Out-Null
+New-NetQosPolicy -Name "STEADYBIT_QOS_1mbit_eb1893d5_1" -Precedence 255 -IPDstPrefixMatchCondition "10.0.1.0/24" -ThrottleRateActionBitsPerSecond 1000000 -Confirm:$false | Out-Null
+try {
+  foreach ($file in $captures.Keys) {
+    Write-Output "Capturing $file"
+    $json = Invoke-Expression $captures[$file]
+    [System.IO.File]::WriteAllText("$exthostwindows\$file", $json, $utf8)
+  }
+} finally {
+  Remove-NetQosPolicy -Name "STEADYBIT_QOS_1mbit_eb1893d5_*" -Confirm:$false -ErrorAction SilentlyContinue
+}
@@ -10,7 +10,12 @@ Write-Output "Found ZIP file $latestZip"
 
 Write-Output "Downloading WinDivert release"
 $winDivertPath = "$distPath\WinDivert"
-$releaseVersion = if ($env:STEADYBIT_WINDIVERT_VERSION) { $env:STEADYBIT_WINDIVERT_VERSION } else { "latest" }
+# the network attacks rely on the wdna modes of the pinned release, never package the latest one
+$pinnedVersion = ((Get-Content "$scriptPath\..\versions.txt" | Where-Object { $_ -like "STEADYBIT_WINDIVERT_VERSION=*" }) -split "=", 2)[1]
+$releaseVersion = if ($env:STEADYBIT_WINDIVERT_VERSION) { $env:STEADYBIT_WINDIVERT_VERSION } else { $pinnedVersion }
+if ([string]::IsNullOrEmpty($releaseVersion)) {
+  Write-Error "No WinDivert release pinned in versions.txt" -ErrorAction Stop
+}
 & "$scriptPath\download-windivert.ps1" -DownloadDir "$winDivertPath" -ReleaseVersion "$releaseVersion"
 
 # Create a temp location for the extraction
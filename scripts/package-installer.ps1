@@ -17,7 +17,7 @@ if ($Env:SKIP_LICENSES_REPORT -eq "true"){
 $ProgressPreference = 'SilentlyContinue'
 $scriptPath = $PSScriptRoot
 $distPath = "$scriptPath\..\dist"
-$devzeroPath = "$scriptPath\..\devzero"
+$memfillPath = "$scriptPath\..\memfill"
 $artifactPath = "$scriptPath\..\windowspkg\WindowsHostExtensionInstaller\Artifacts"
 $solutionPath = "$scriptPath\..\windowspkg\WindowsHostExtensionInstaller"
 $cpuStressPath = "$scriptPath\..\steadybit-stress-cpu"
@@ -45,38 +45,16 @@ Add-Type -AssemblyName System.IO.Compression.FileSystem
 
 Write-Output "Extraction completed."
 
-Write-Output "Downloading latest release of memfill..."
-
-$memfillApiUrl = "https://api.github.com/repos/steadybit/memfill/releases/latest"
-$memfillRelease = Invoke-RestMethod -Uri $memfillApiUrl -Headers $headers
-$memfillAsset = $memfillRelease.assets | Where-Object { $_.name -like "*.exe" } | Select-Object -First 1
-
-if ($null -eq $memfillAsset) {
-    Write-Error "No .exe asset found in the latest memfill release." -ErrorAction Stop
-}
-
-$memfillExePath = "$artifactPath\memfill.exe"
-Invoke-WebRequest -Uri $memfillAsset.browser_download_url -OutFile $memfillExePath -Headers $headers
-
-Write-Output "memfill.exe added to artifacts."
-
 Copy-Item licenses\THIRD-PARTY-LICENSES.csv windowspkg\WindowsHostExtensionInstaller\Artifacts
 
-Write-Output "Running dotnet publish in: $cpuStressPath"
+Write-Output "Building steadybit-stress-cpu in: $cpuStressPath"
 Push-Location $cpuStressPath
-dotnet publish -c Release -r "win-x64"  -o $artifactPath /p:SelfContained=true /p:PublishSingleFile=true /p:PublishTrimmed=true
-Pop-Location
-
-Write-Output "Building devzero in: $devzeroPath"
-Push-Location $devzeroPath
-go build -o $artifactPath\devzero.exe main.go
+go build -o $artifactPath\steadybit-stress-cpu.exe .
 Pop-Location
 
-Push-Location $artifactPath
-Write-Output "Downloading and extracting coreutils"
-Invoke-WebRequest -Uri https://github.com/uutils/coreutils/releases/download/0.1.0/coreutils-0.1.0-x86_64-pc-windows-msvc.zip  -OutFile CoreUtils.zip -Headers $headers
-[System.IO.Compression.ZipFile]::ExtractToDirectory("$artifactPath\CoreUtils.zip", "$artifactPath\CoreUtils")
-Copy-Item "$artifactPath\CoreUtils\coreutils-0.1.0-x86_64-pc-windows-msvc\coreutils.exe" $artifactPath
+Write-Output "Building memfill in: $memfillPath"
+Push-Location $memfillPath
+go build -o $artifactPath\memfill.exe .
 Pop-Location
 
 Push-Location $artifactPath
@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/steadybit/extension-kit/extbuild"
+)
+
+func main() {
+	numCPU := runtime.NumCPU()
+	flags := flag.NewFlagSet("steadybit-stress-cpu", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Println("'steadybit-stress-cpu' holds the total CPU load of the host at the given percentage.")
+		fmt.Println("usage: steadybit-stress-cpu [flags]\nexample: steadybit-stress-cpu --duration 30 --cores 2 --percentage 80")
+		fmt.Println("The load of the host is written to stdout as one line of JSON per second.")
+		flags.PrintDefaults()
+	}
+	duration := flags.Int("duration", 30, "duration of the CPU stress in seconds")
+	cores := flags.Int("cores", numCPU, "number of cores running a worker")
+	load := flags.Int("percentage", 100, "total CPU load of the host in percent")
+	priority := flags.String("priority", "Normal", "priority of the process: Normal, AboveNormal, High or RealTime")
+	affinity := flags.Bool("affinity", false, "pin each worker to its own core")
+	version := flags.Bool("version", false, "print the version")
+	_ = flags.Parse(os.Args[1:])
+
+	if *version {
+		fmt.Println(extbuild.GetSemverVersionStringOrUnknown())
+		os.Exit(0)
+	}
+
+	opts := options{duration: time.Duration(*duration) * time.Second, cores: *cores, load: *load, priority: *priority, affinity: *affinity}
+	if err := opts.validate(numCPU); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := setPriority(opts.priority); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "failed to set priority %s: %s\n", opts.priority, err)
+		os.Exit(1)
+	}
+
+	sampler, err := newSystemSampler(numCPU)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	w := &workers{count: opts.cores, affinity: opts.affinity, pin: pinThread, spin: busyWait}
+	if err := run(opts, sampler, w, numCPU, os.Stdout, os.Stderr, time.Second); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
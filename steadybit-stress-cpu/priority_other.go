@@ -0,0 +1,23 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+//go:build !windows
+
+package main
+
+import "errors"
+
+// priorityClasses only contains the names on other platforms, they are only supported to run unit tests.
+var priorityClasses = map[string]uint32{
+	"Normal":      0,
+	"AboveNormal": 0,
+	"High":        0,
+	"RealTime":    0,
+}
+
+func setPriority(string) error {
+	return nil
+}
+
+func pinThread(int) error {
+	return errors.New("pinning threads is only supported on windows")
+}
@@ -0,0 +1,34 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+var priorityClasses = map[string]uint32{
+	"Normal":      windows.NORMAL_PRIORITY_CLASS,
+	"AboveNormal": windows.ABOVE_NORMAL_PRIORITY_CLASS,
+	"High":        windows.HIGH_PRIORITY_CLASS,
+	"RealTime":    windows.REALTIME_PRIORITY_CLASS,
+}
+
+var procSetThreadAffinityMask = windows.NewLazySystemDLL("kernel32.dll").NewProc("SetThreadAffinityMask")
+
+func setPriority(priority string) error {
+	return windows.SetPriorityClass(windows.CurrentProcess(), priorityClasses[priority])
+}
+
+// pinThread pins the current thread to the core. Only the cores of the first processor group are supported.
+func pinThread(core int) error {
+	if core >= 64 {
+		return fmt.Errorf("core %d is not part of the first processor group", core)
+	}
+	if r, _, err := procSetThreadAffinityMask.Call(uintptr(windows.CurrentThread()), uintptr(1)<<core); r == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/elastic/go-sysinfo"
+	"github.com/elastic/go-sysinfo/types"
+)
+
+// cpuSample is the CPU load since the previous sample, from 0 to 1 of the host capacity.
+type cpuSample struct {
+	host float64
+	// stress is the part of the host load caused by the workers.
+	stress float64
+}
+
+type cpuSampler interface {
+	sample() (cpuSample, error)
+}
+
+// systemSampler samples the CPU times of the host and of the own process.
+type systemSampler struct {
+	host   types.Host
+	self   types.CPUTimer
+	numCPU int
+
+	lastAt   time.Time
+	lastIdle time.Duration
+	lastSelf time.Duration
+}
+
+func newSystemSampler(numCPU int) (*systemSampler, error) {
+	host, err := sysinfo.Host()
+	if err != nil {
+		return nil, err
+	}
+	process, err := sysinfo.Self()
+	if err != nil {
+		return nil, err
+	}
+	self, ok := process.(types.CPUTimer)
+	if !ok {
+		return nil, errors.New("cpu times of processes are not supported")
+	}
+
+	s := &systemSampler{host: host, self: self, numCPU: numCPU}
+	if _, err := s.sample(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// sample derives the load from the idle time of the host, as the other CPU times aren't reported the same way on all
+// platforms. On Windows the kernel time includes the idle time.
+func (s *systemSampler) sample() (cpuSample, error) {
+	now := time.Now()
+	host, err := s.host.CPUTime()
+	if err != nil {
+		return cpuSample{}, err
+	}
+	self, err := s.self.CPUTime()
+	if err != nil {
+		return cpuSample{}, err
+	}
+
+	capacity := float64(now.Sub(s.lastAt)) * float64(s.numCPU)
+	sample := cpuSample{
+		host:   clamp(1 - float64(host.Idle-s.lastIdle)/capacity),
+		stress: clamp(float64(self.User+self.System-s.lastSelf) / capacity),
+	}
+	s.lastAt, s.lastIdle, s.lastSelf = now, host.Idle, self.User+self.System
+	return sample, nil
+}
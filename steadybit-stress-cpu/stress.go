@@ -0,0 +1,174 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// period is the length of a duty cycle. Each worker is busy for the duty cycle's share of the period and sleeps for the rest.
+const period = 100 * time.Millisecond
+
+type options struct {
+	duration time.Duration
+	cores    int
+	// load is the target of the total host CPU load in percent.
+	load     int
+	priority string
+	// affinity pins each worker to its own core.
+	affinity bool
+}
+
+func (o options) validate(numCPU int) error {
+	if o.duration < time.Second {
+		return fmt.Errorf("duration must be at least 1s, got %s", o.duration)
+	}
+	if o.cores < 1 || o.cores > numCPU {
+		return fmt.Errorf("cores must be between 1 and %d, got %d", numCPU, o.cores)
+	}
+	if o.load < 1 || o.load > 100 {
+		return fmt.Errorf("percentage must be between 1 and 100, got %d", o.load)
+	}
+	if _, ok := priorityClasses[o.priority]; !ok {
+		return fmt.Errorf("priority must be Normal, AboveNormal, High or RealTime, got %s", o.priority)
+	}
+	return nil
+}
+
+// controller holds the total CPU load of the host at the target by adjusting the duty cycle of the workers. Load
+// caused by other processes is compensated, as only the total load of the host is measured.
+type controller struct {
+	// target is the total host CPU load, from 0 to 1.
+	target float64
+	// share is the part of the host capacity the workers can use.
+	share float64
+	// gain is the part of the deviation corrected per update. Smaller values react slower, but don't amplify noise.
+	gain float64
+	duty float64
+}
+
+func newController(target float64, cores, numCPU int) *controller {
+	share := float64(cores) / float64(numCPU)
+	// without knowing the load of other processes, start as if there was none
+	return &controller{target: target, share: share, gain: 0.5, duty: clamp(target / share)}
+}
+
+// update corrects the duty cycle by the deviation of the measured host load from the target and returns the new duty
+// cycle. The deviation is divided by the share of the workers, as a worker changes the host load only by its share.
+func (c *controller) update(hostLoad float64) float64 {
+	c.duty = clamp(c.duty + c.gain*(c.target-hostLoad)/c.share)
+	return c.duty
+}
+
+func clamp(v float64) float64 {
+	return math.Min(math.Max(v, 0), 1)
+}
+
+// workers burn CPU on count goroutines, each locked to its own thread. All workers run the same duty cycle, which is
+// controlled by the total load of the host, not by the load of single cores.
+type workers struct {
+	count    int
+	affinity bool
+	// duty is the duty cycle from 0 to 1, stored as bits of a float64.
+	duty atomic.Uint64
+	// pin pins the current thread to the core.
+	pin func(core int) error
+	// spin keeps the current thread busy for the duration, see busyWait.
+	spin func(d time.Duration)
+}
+
+func (w *workers) setDuty(duty float64) {
+	w.duty.Store(math.Float64bits(duty))
+}
+
+func (w *workers) getDuty() float64 {
+	return math.Float64frombits(w.duty.Load())
+}
+
+// run runs the workers until the context is done.
+func (w *workers) run(ctx context.Context, errs io.Writer) {
+	var wg sync.WaitGroup
+	for core := range w.count {
+		wg.Go(func() {
+			runtime.LockOSThread()
+			defer runtime.UnlockOSThread()
+			if w.affinity {
+				if err := w.pin(core); err != nil {
+					_, _ = fmt.Fprintf(errs, "failed to pin worker to core %d: %s\n", core, err)
+				}
+			}
+			w.burn(ctx)
+		})
+	}
+	wg.Wait()
+}
+
+func (w *workers) burn(ctx context.Context) {
+	for ctx.Err() == nil {
+		start := time.Now()
+		w.spin(time.Duration(float64(period) * w.getDuty()))
+		if idle := period - time.Since(start); idle > 0 {
+			time.Sleep(idle)
+		}
+	}
+}
+
+// busyWait keeps the current thread busy for the duration.
+func busyWait(d time.Duration) {
+	start := time.Now()
+	for time.Since(start) < d {
+	}
+}
+
+// report is written to stdout as one line of JSON per interval. Loads are in percent of the host capacity.
+type report struct {
+	Time       time.Time `json:"time"`
+	HostLoad   float64   `json:"hostLoad"`
+	StressLoad float64   `json:"stressLoad"`
+	Duty       float64   `json:"duty"`
+}
+
+// run stresses the CPU until the duration elapsed. Once per interval the host load is sampled, the duty cycle of the
+// workers is adjusted and a report is written to out.
+func run(opts options, sampler cpuSampler, w *workers, numCPU int, out io.Writer, errs io.Writer, interval time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), opts.duration)
+	defer cancel()
+
+	c := newController(float64(opts.load)/100, opts.cores, numCPU)
+	w.setDuty(c.duty)
+
+	var wg sync.WaitGroup
+	wg.Go(func() { w.run(ctx, errs) })
+	defer wg.Wait()
+
+	encoder := json.NewEncoder(out)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			sample, err := sampler.sample()
+			if err != nil {
+				cancel()
+				return fmt.Errorf("failed to sample cpu load: %w", err)
+			}
+			duty := c.update(sample.host)
+			w.setDuty(duty)
+			if err := encoder.Encode(report{Time: now, HostLoad: sample.host * 100, StressLoad: sample.stress * 100, Duty: duty * 100}); err != nil {
+				cancel()
+				return err
+			}
+		}
+	}
+}
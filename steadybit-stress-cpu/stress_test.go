@@ -0,0 +1,182 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2026 Steadybit GmbH
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeHost is a cpuSampler simulating a host. The load of the workers is derived from the duty cycle of the controller.
+type fakeHost struct {
+	c *controller
+	// other is the load of other processes, from 0 to 1.
+	other float64
+	// efficiency is the part of the duty cycle actually used by the workers.
+	efficiency float64
+}
+
+func (h *fakeHost) sample() (cpuSample, error) {
+	stress := h.c.duty * h.c.share * h.efficiency
+	return cpuSample{host: clamp(h.other + stress), stress: stress}, nil
+}
+
+// settle updates the controller with the samples of the host until the duty cycle settled.
+func settle(t *testing.T, h *fakeHost) cpuSample {
+	for range 30 {
+		sample, err := h.sample()
+		require.NoError(t, err)
+		h.c.update(sample.host)
+	}
+	sample, err := h.sample()
+	require.NoError(t, err)
+	return sample
+}
+
+func TestController_ReachesTarget(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     float64
+		cores      int
+		other      float64
+		efficiency float64
+		wantHost   float64
+		wantDuty   float64
+	}{
+		{name: "idle host", target: 0.8, cores: 4, efficiency: 1, wantHost: 0.8, wantDuty: 0.8},
+		{name: "other load is compensated", target: 0.8, cores: 4, other: 0.3, efficiency: 1, wantHost: 0.8, wantDuty: 0.5},
+		{name: "less cores", target: 0.4, cores: 2, efficiency: 1, wantHost: 0.4, wantDuty: 0.8},
+		{name: "inefficient workers", target: 0.5, cores: 4, efficiency: 0.8, wantHost: 0.5, wantDuty: 0.625},
+		{name: "target not reachable with less cores", target: 0.9, cores: 2, efficiency: 1, wantHost: 0.5, wantDuty: 1},
+		{name: "other load above target", target: 0.5, cores: 4, other: 0.7, efficiency: 1, wantHost: 0.7, wantDuty: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &fakeHost{c: newController(tt.target, tt.cores, 4), other: tt.other, efficiency: tt.efficiency}
+
+			sample := settle(t, h)
+			assert.InDelta(t, tt.wantHost, sample.host, 0.005)
+			assert.InDelta(t, tt.wantDuty, h.c.duty, 0.005)
+		})
+	}
+}
+
+func TestController_FollowsChangingLoad(t *testing.T) {
+	h := &fakeHost{c: newController(0.6, 4, 4), efficiency: 1}
+	assert.InDelta(t, 0.6, settle(t, h).host, 0.005)
+
+	h.other = 0.4
+	assert.InDelta(t, 0.6, settle(t, h).host, 0.005)
+	assert.InDelta(t, 0.2, h.c.duty, 0.005)
+
+	h.other = 0
+	assert.InDelta(t, 0.6, settle(t, h).host, 0.005)
+}
+
+func TestController_StartsWithoutOtherLoad(t *testing.T) {
+	assert.InDelta(t, 0.5, newController(0.25, 2, 4).duty, 0.0001)
+	assert.InDelta(t, 1.0, newController(0.75, 2, 4).duty, 0.0001)
+}
+
+func TestOptions_Validate(t *testing.T) {
+	valid := options{duration: time.Second, cores: 2, load: 50, priority: "Normal"}
+	require.NoError(t, valid.validate(4))
+
+	invalid := []struct {
+		modify  func(o *options)
+		wantErr string
+	}{
+		{func(o *options) { o.duration = 0 }, "duration must be at least 1s, got 0s"},
+		{func(o *options) { o.cores = 5 }, "cores must be between 1 and 4, got 5"},
+		{func(o *options) { o.load = 101 }, "percentage must be between 1 and 100, got 101"},
+		{func(o *options) { o.priority = "Idle" }, "priority must be Normal, AboveNormal, High or RealTime, got Idle"},
+	}
+	for _, tt := range invalid {
+		opts := valid
+		tt.modify(&opts)
+		assert.EqualError(t, opts.validate(4), tt.wantErr)
+	}
+}
+
+// sequenceSampler returns the given host loads, one per sample.
+type sequenceSampler struct {
+	loads []float64
+}
+
+func (s *sequenceSampler) sample() (cpuSample, error) {
+	if len(s.loads) == 0 {
+		return cpuSample{}, errors.New("no more samples")
+	}
+	load := s.loads[0]
+	s.loads = s.loads[1:]
+	return cpuSample{host: load, stress: load / 2}, nil
+}
+
+func TestRun_ReportsLoad(t *testing.T) {
+	opts := options{duration: 500 * time.Millisecond, cores: 1, load: 50}
+	sampler := &sequenceSampler{loads: []float64{0.3, 0.4, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5}}
+	// the workers sleep instead of burning a core while the test runs
+	w := &workers{count: 1, spin: time.Sleep}
+
+	var out bytes.Buffer
+	require.NoError(t, run(opts, sampler, w, 4, &out, io.Discard, 50*time.Millisecond))
+
+	var reports []report
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var r report
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &r))
+		reports = append(reports, r)
+	}
+	require.GreaterOrEqual(t, len(reports), 5)
+	assert.Equal(t, 30.0, reports[0].HostLoad)
+	assert.Equal(t, 15.0, reports[0].StressLoad)
+	assert.False(t, reports[0].Time.IsZero())
+	// starts at a duty cycle of 100%, as a single core can't reach 50% of 4 cores, and stays there
+	assert.Equal(t, 100.0, reports[len(reports)-1].Duty)
+}
+
+func TestRun_FailsIfSamplingFails(t *testing.T) {
+	opts := options{duration: time.Minute, cores: 1, load: 50}
+	w := &workers{count: 1, spin: time.Sleep}
+
+	err := run(opts, &sequenceSampler{}, w, 1, io.Discard, io.Discard, 10*time.Millisecond)
+	assert.EqualError(t, err, "failed to sample cpu load: no more samples")
+}
+
+func TestWorkers_PinFailureIsReported(t *testing.T) {
+	w := &workers{count: 1, affinity: true, pin: func(core int) error { return errors.New("not allowed") }, spin: time.Sleep}
+	var errs bytes.Buffer
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+
+	w.run(ctx, &errs)
+	assert.Equal(t, "failed to pin worker to core 0: not allowed\n", errs.String())
+}
+
+func TestWorkers_SpinForDutyCycle(t *testing.T) {
+	var lock sync.Mutex
+	var spins []time.Duration
+	w := &workers{count: 2, spin: func(d time.Duration) {
+		lock.Lock()
+		defer lock.Unlock()
+		spins = append(spins, d)
+	}}
+	w.setDuty(0.3)
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	w.run(ctx, io.Discard)
+	assert.Equal(t, []time.Duration{30 * time.Millisecond, 30 * time.Millisecond}, spins)
+}